@@ -16,6 +16,10 @@
 // 1649164719_add_community_archives_info_table.up.sql (208B)
 // 1649174829_add_visitble_token.up.sql (84B)
 // 1649882262_add_derived_from_accounts.up.sql (110B)
+// 1650373957_add_stickers_packs_order.up.sql (59B)
+// 1650458316_add_stickers_usage.up.sql (53B)
+// 1650462000_add_stickers_market_seen.up.sql (59B)
+// 1650470000_add_stickers_packs_pending_backup.up.sql (68B)
 // doc.go (74B)
 
 package migrations
@@ -405,6 +409,86 @@ func _1649882262_add_derived_from_accountsUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1650373957_add_stickers_packs_orderUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x4e\x2d\x29\xc9\xcc\x4b\x2f\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2e\xc9\x4c\xce\x4e\x2d\x2a\x8e\x2f\x48\x4c\xce\x2e\x8e\xcf\x2f\x4a\x49\x2d\x52\x70\xf2\xf1\x77\xb2\xe6\x02\x00\x7b\x47\x82\x10\x3b\x00\x00\x00")
+
+func _1650373957_add_stickers_packs_orderUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1650373957_add_stickers_packs_orderUpSql,
+		"1650373957_add_stickers_packs_order.up.sql",
+	)
+}
+
+func _1650373957_add_stickers_packs_orderUpSql() (*asset, error) {
+	bytes, err := _1650373957_add_stickers_packs_orderUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1650373957_add_stickers_packs_order.up.sql", size: 59, mode: os.FileMode(0664), modTime: time.Unix(1650374019, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd8, 0x13, 0x2c, 0xb3, 0xe2, 0x5d, 0xfc, 0xde, 0x27, 0x58, 0x46, 0x15, 0x89, 0xda, 0x2d, 0x7b, 0xbb, 0xb5, 0x50, 0xc1, 0x67, 0x2a, 0x59, 0xbb, 0xad, 0x89, 0xcb, 0x46, 0x40, 0x8e, 0x26, 0xcb}}
+	return a, nil
+}
+
+var __1650458316_add_stickers_usageUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x4e\x2d\x29\xc9\xcc\x4b\x2f\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2e\xc9\x4c\xce\x4e\x2d\x2a\x8e\x2f\x2d\x4e\x4c\x4f\x55\x70\xf2\xf1\x77\xb2\xe6\x02\x00\xa9\x05\x0b\x31\x35\x00\x00\x00")
+
+func _1650458316_add_stickers_usageUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1650458316_add_stickers_usageUpSql,
+		"1650458316_add_stickers_usage.up.sql",
+	)
+}
+
+func _1650458316_add_stickers_usageUpSql() (*asset, error) {
+	bytes, err := _1650458316_add_stickers_usageUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1650458316_add_stickers_usage.up.sql", size: 53, mode: os.FileMode(0664), modTime: time.Unix(1650458378, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xa6, 0x0, 0xe, 0x83, 0x24, 0xbb, 0x1f, 0x72, 0x6c, 0x2a, 0x9, 0xaf, 0x61, 0x20, 0x1, 0xcd, 0x73, 0x9d, 0xc1, 0x13, 0x4e, 0x44, 0x68, 0x54, 0x22, 0x85, 0xe8, 0xab, 0x82, 0xfa, 0x88, 0xa}}
+	return a, nil
+}
+
+var __1650462000_add_stickers_market_seenUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x4e\x2d\x29\xc9\xcc\x4b\x2f\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2e\xc9\x4c\xce\x4e\x2d\x2a\x8e\xcf\x4d\x2c\xca\x4e\x2d\x89\x2f\x4e\x4d\xcd\x53\x70\xf2\xf1\x77\xb2\xe6\x02\x00\x95\x5e\x73\x5e\x3b\x00\x00\x00")
+
+func _1650462000_add_stickers_market_seenUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1650462000_add_stickers_market_seenUpSql,
+		"1650462000_add_stickers_market_seen.up.sql",
+	)
+}
+
+func _1650462000_add_stickers_market_seenUpSql() (*asset, error) {
+	bytes, err := _1650462000_add_stickers_market_seenUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1650462000_add_stickers_market_seen.up.sql", size: 59, mode: os.FileMode(0664), modTime: time.Unix(1650462062, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xf8, 0x39, 0x77, 0x9f, 0x46, 0x6b, 0x5b, 0xaa, 0x4f, 0x23, 0x47, 0x57, 0xac, 0x5f, 0x79, 0x25, 0x61, 0xca, 0x4c, 0xad, 0x24, 0x20, 0x62, 0x5a, 0x8f, 0xae, 0x4d, 0xa8, 0xad, 0x2e, 0x31, 0xf4}}
+	return a, nil
+}
+
+var __1650470000_add_stickers_packs_pending_backupUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x4e\x2d\x29\xc9\xcc\x4b\x2f\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2e\xc9\x4c\xce\x4e\x2d\x2a\x8e\x2f\x48\x4c\xce\x06\x92\xa9\x79\x29\x40\x25\xf1\x49\x40\x5e\x69\x81\x82\x93\x8f\xbf\x93\x35\x17\x00\x91\x04\x9b\x3f\x44\x00\x00\x00")
+
+func _1650470000_add_stickers_packs_pending_backupUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1650470000_add_stickers_packs_pending_backupUpSql,
+		"1650470000_add_stickers_packs_pending_backup.up.sql",
+	)
+}
+
+func _1650470000_add_stickers_packs_pending_backupUpSql() (*asset, error) {
+	bytes, err := _1650470000_add_stickers_packs_pending_backupUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1650470000_add_stickers_packs_pending_backup.up.sql", size: 68, mode: os.FileMode(0664), modTime: time.Unix(1650470062, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x63, 0x3d, 0xf6, 0xf9, 0xb2, 0x1c, 0x3e, 0x9e, 0x82, 0x74, 0x54, 0x39, 0x84, 0xaf, 0xd, 0x1f, 0x9b, 0xfb, 0x62, 0xc2, 0x13, 0xbd, 0x58, 0xf2, 0x75, 0xf1, 0xe, 0xf6, 0xa, 0x17, 0x10, 0xaf}}
+	return a, nil
+}
+
 var _docGo = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x2c\xc9\xb1\x0d\xc4\x20\x0c\x05\xd0\x9e\x29\xfe\x02\xd8\xfd\x6d\xe3\x4b\xac\x2f\x44\x82\x09\x78\x7f\xa5\x49\xfd\xa6\x1d\xdd\xe8\xd8\xcf\x55\x8a\x2a\xe3\x47\x1f\xbe\x2c\x1d\x8c\xfa\x6f\xe3\xb4\x34\xd4\xd9\x89\xbb\x71\x59\xb6\x18\x1b\x35\x20\xa2\x9f\x0a\x03\xa2\xe5\x0d\x00\x00\xff\xff\x60\xcd\x06\xbe\x4a\x00\x00\x00")
 
 func docGoBytes() ([]byte, error) {
@@ -548,6 +632,14 @@ var _bindata = map[string]func() (*asset, error){
 
 	"1649882262_add_derived_from_accounts.up.sql": _1649882262_add_derived_from_accountsUpSql,
 
+	"1650373957_add_stickers_packs_order.up.sql": _1650373957_add_stickers_packs_orderUpSql,
+
+	"1650458316_add_stickers_usage.up.sql": _1650458316_add_stickers_usageUpSql,
+
+	"1650462000_add_stickers_market_seen.up.sql": _1650462000_add_stickers_market_seenUpSql,
+
+	"1650470000_add_stickers_packs_pending_backup.up.sql": _1650470000_add_stickers_packs_pending_backupUpSql,
+
 	"doc.go": docGo,
 }
 
@@ -608,6 +700,10 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1649164719_add_community_archives_info_table.up.sql": &bintree{_1649164719_add_community_archives_info_tableUpSql, map[string]*bintree{}},
 	"1649174829_add_visitble_token.up.sql":                &bintree{_1649174829_add_visitble_tokenUpSql, map[string]*bintree{}},
 	"1649882262_add_derived_from_accounts.up.sql":         &bintree{_1649882262_add_derived_from_accountsUpSql, map[string]*bintree{}},
+	"1650373957_add_stickers_packs_order.up.sql":          &bintree{_1650373957_add_stickers_packs_orderUpSql, map[string]*bintree{}},
+	"1650458316_add_stickers_usage.up.sql":                &bintree{_1650458316_add_stickers_usageUpSql, map[string]*bintree{}},
+	"1650462000_add_stickers_market_seen.up.sql":          &bintree{_1650462000_add_stickers_market_seenUpSql, map[string]*bintree{}},
+	"1650470000_add_stickers_packs_pending_backup.up.sql": &bintree{_1650470000_add_stickers_packs_pending_backupUpSql, map[string]*bintree{}},
 	"doc.go": &bintree{docGo, map[string]*bintree{}},
 }}
 
@@ -0,0 +1 @@
+ALTER TABLE settings ADD COLUMN stickers_market_seen BLOB;
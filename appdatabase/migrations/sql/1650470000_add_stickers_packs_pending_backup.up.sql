@@ -0,0 +1 @@
+ALTER TABLE settings ADD COLUMN stickers_packs_pending_backup BLOB;
@@ -87,6 +87,15 @@ func (c *ContractMaker) NewStickerType(chainID uint64) (*stickers.StickerType, e
 	)
 }
 
+func (c *ContractMaker) NewStickerPacks1155(chainID uint64, contractAddr common.Address) (*stickers.StickerPacks1155, error) {
+	backend, err := c.RPCClient.EthClient(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	return stickers.NewStickerPacks1155(contractAddr, backend)
+}
+
 func (c *ContractMaker) NewStickerMarket(chainID uint64) (*stickers.StickerMarket, error) {
 	contractAddr, err := stickers.StickerMarketContractAddress(chainID)
 	if err != nil {
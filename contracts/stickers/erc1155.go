@@ -0,0 +1,94 @@
+package stickers
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StickerPacks1155ABI is the subset of the ERC-1155 sticker packs contract ABI
+// needed to read packs, where each pack is a token ID
+const StickerPacks1155ABI = "[{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"uri\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"packData\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"bool\",\"name\":\"mintable\",\"type\":\"bool\"},{\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"},{\"internalType\":\"uint256\",\"name\":\"price\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[],\"name\":\"packCount\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"address\",\"name\":\"account\",\"type\":\"address\"},{\"internalType\":\"uint256\",\"name\":\"id\",\"type\":\"uint256\"}],\"name\":\"balanceOf\",\"outputs\":[{\"internalType\":\"uint256\",\"name\":\"\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"}]"
+
+// StickerPacks1155 is a read-only binding of an ERC-1155 sticker packs contract
+type StickerPacks1155 struct {
+	contract *bind.BoundContract
+}
+
+// StickerPack1155Data is the pack information stored on chain, the pack
+// content is referenced by the token URI instead
+type StickerPack1155Data struct {
+	Owner     common.Address
+	Mintable  bool
+	Timestamp *big.Int
+	Price     *big.Int
+}
+
+func NewStickerPacks1155(address common.Address, caller bind.ContractCaller) (*StickerPacks1155, error) {
+	parsed, err := abi.JSON(strings.NewReader(StickerPacks1155ABI))
+	if err != nil {
+		return nil, err
+	}
+
+	return &StickerPacks1155{contract: bind.NewBoundContract(address, parsed, caller, nil, nil)}, nil
+}
+
+// URI is a free data retrieval call binding the contract method uri.
+//
+// Solidity: function uri(uint256 id) view returns(string)
+func (_StickerPacks1155 *StickerPacks1155) URI(opts *bind.CallOpts, id *big.Int) (string, error) {
+	var out []interface{}
+	err := _StickerPacks1155.contract.Call(opts, &out, "uri", id)
+	if err != nil {
+		return "", err
+	}
+
+	return *abi.ConvertType(out[0], new(string)).(*string), nil
+}
+
+// PackData is a free data retrieval call binding the contract method packData.
+//
+// Solidity: function packData(uint256 id) view returns(address owner, bool mintable, uint256 timestamp, uint256 price)
+func (_StickerPacks1155 *StickerPacks1155) PackData(opts *bind.CallOpts, id *big.Int) (StickerPack1155Data, error) {
+	var out []interface{}
+	err := _StickerPacks1155.contract.Call(opts, &out, "packData", id)
+	if err != nil {
+		return StickerPack1155Data{}, err
+	}
+
+	return StickerPack1155Data{
+		Owner:     *abi.ConvertType(out[0], new(common.Address)).(*common.Address),
+		Mintable:  *abi.ConvertType(out[1], new(bool)).(*bool),
+		Timestamp: *abi.ConvertType(out[2], new(*big.Int)).(**big.Int),
+		Price:     *abi.ConvertType(out[3], new(*big.Int)).(**big.Int),
+	}, nil
+}
+
+// PackCount is a free data retrieval call binding the contract method packCount.
+//
+// Solidity: function packCount() view returns(uint256)
+func (_StickerPacks1155 *StickerPacks1155) PackCount(opts *bind.CallOpts) (*big.Int, error) {
+	var out []interface{}
+	err := _StickerPacks1155.contract.Call(opts, &out, "packCount")
+	if err != nil {
+		return nil, err
+	}
+
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+// BalanceOf is a free data retrieval call binding the contract method balanceOf.
+//
+// Solidity: function balanceOf(address account, uint256 id) view returns(uint256)
+func (_StickerPacks1155 *StickerPacks1155) BalanceOf(opts *bind.CallOpts, account common.Address, id *big.Int) (*big.Int, error) {
+	var out []interface{}
+	err := _StickerPacks1155.contract.Call(opts, &out, "balanceOf", account, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
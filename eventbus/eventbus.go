@@ -0,0 +1,118 @@
+package eventbus
+
+import (
+	"sync"
+)
+
+// Size of the buffer of every subscription channel, events published to a
+// subscriber whose buffer is full are dropped
+const subscriptionBufferSize = 16
+
+type EventType string
+
+const (
+	StickerPackAdded   EventType = "stickers.packAdded"
+	StickerPackRemoved EventType = "stickers.packRemoved"
+	MediaServed        EventType = "media.served"
+	CacheEvicted       EventType = "media.cacheEvicted"
+	MediaPortChanged   EventType = "media.portChanged"
+)
+
+type Event struct {
+	Type    EventType
+	Payload interface{}
+}
+
+// StickerPackPayload is the payload of StickerPackAdded and StickerPackRemoved
+// events
+type StickerPackPayload struct {
+	ChainID uint64
+	PackID  string
+}
+
+// MediaServedPayload is the payload of MediaServed events
+type MediaServedPayload struct {
+	Kind string
+	ID   string
+}
+
+// CacheEvictedPayload is the payload of CacheEvicted events
+type CacheEvictedPayload struct {
+	Cache string
+	Key   string
+}
+
+// MediaPortChangedPayload is the payload of MediaPortChanged events
+type MediaPortChangedPayload struct {
+	Port    int
+	BaseURL string
+}
+
+// Bus is an in-memory publish/subscribe hub for lifecycle events. The zero
+// Bus is ready to use. A nil Bus is valid too, it discards every published
+// event and its subscriptions are closed right away
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[EventType][]chan Event
+}
+
+func New() *Bus {
+	return &Bus{subscribers: make(map[EventType][]chan Event)}
+}
+
+// Subscribe returns a channel receiving every event of the given type
+// published after the call
+func (b *Bus) Subscribe(eventType EventType) <-chan Event {
+	ch := make(chan Event, subscriptionBufferSize)
+	if b == nil {
+		close(ch)
+		return ch
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[EventType][]chan Event)
+	}
+	b.subscribers[eventType] = append(b.subscribers[eventType], ch)
+
+	return ch
+}
+
+// Unsubscribe stops the delivery of events to ch and closes it
+func (b *Bus) Unsubscribe(ch <-chan Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for eventType, subscribers := range b.subscribers {
+		for i, subscriber := range subscribers {
+			if subscriber == ch {
+				b.subscribers[eventType] = append(subscribers[:i], subscribers[i+1:]...)
+				close(subscriber)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers the event to the current subscribers without blocking
+func (b *Bus) Publish(eventType EventType, payload interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	event := Event{Type: eventType, Payload: payload}
+	for _, subscriber := range b.subscribers[eventType] {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	bus := New()
+
+	added := bus.Subscribe(StickerPackAdded)
+	removed := bus.Subscribe(StickerPackRemoved)
+
+	bus.Publish(StickerPackAdded, StickerPackPayload{ChainID: 1, PackID: "2"})
+
+	require.Equal(t, Event{Type: StickerPackAdded, Payload: StickerPackPayload{ChainID: 1, PackID: "2"}}, <-added)
+	require.Empty(t, removed)
+
+	bus.Unsubscribe(added)
+	_, open := <-added
+	require.False(t, open)
+
+	bus.Publish(StickerPackAdded, StickerPackPayload{ChainID: 1, PackID: "3"})
+}
+
+func TestPublishDoesNotBlock(t *testing.T) {
+	bus := New()
+	ch := bus.Subscribe(MediaServed)
+
+	for i := 0; i < subscriptionBufferSize*2; i++ {
+		bus.Publish(MediaServed, MediaServedPayload{Kind: "image"})
+	}
+
+	require.Len(t, ch, subscriptionBufferSize)
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	ch := bus.Subscribe(MediaServed)
+	bus.Publish(MediaServed, MediaServedPayload{Kind: "image"})
+
+	_, ok := <-ch
+	require.False(t, ok, "subscription to a nil bus should be closed")
+	bus.Unsubscribe(ch)
+}
+
+func TestZeroBus(t *testing.T) {
+	var bus Bus
+	bus.Publish(MediaServed, MediaServedPayload{Kind: "image"})
+	ch := bus.Subscribe(MediaServed)
+	bus.Publish(MediaServed, MediaServedPayload{Kind: "audio"})
+
+	require.Equal(t, Event{Type: MediaServed, Payload: MediaServedPayload{Kind: "audio"}}, <-ch)
+	bus.Unsubscribe(ch)
+}
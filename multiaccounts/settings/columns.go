@@ -316,6 +316,11 @@ var (
 			protobufType:      protobuf.SyncSetting_STICKERS_PACKS_INSTALLED,
 		},
 	}
+	StickersPacksOrder = SettingField{
+		reactFieldName: "stickers/packs-order",
+		dBColumnName:   "stickers_packs_order",
+		valueHandler:   JSONBlobHandler,
+	}
 	StickersPacksPending = SettingField{
 		reactFieldName: "stickers/packs-pending",
 		dBColumnName:   "stickers_packs_pending",
@@ -329,6 +334,21 @@ var (
 			protobufType:      protobuf.SyncSetting_STICKERS_PACKS_PENDING,
 		},
 	}
+	StickersPacksPendingBackup = SettingField{
+		reactFieldName: "stickers/packs-pending-backup",
+		dBColumnName:   "stickers_packs_pending_backup",
+		valueHandler:   JSONBlobHandler,
+	}
+	StickersMarketSeen = SettingField{
+		reactFieldName: "stickers/market-seen",
+		dBColumnName:   "stickers_market_seen",
+		valueHandler:   JSONBlobHandler,
+	}
+	StickersUsage = SettingField{
+		reactFieldName: "stickers/usage",
+		dBColumnName:   "stickers_usage",
+		valueHandler:   JSONBlobHandler,
+	}
 	StickersRecentStickers = SettingField{
 		reactFieldName: "stickers/recent-stickers",
 		dBColumnName:   "stickers_recent_stickers",
@@ -443,9 +463,13 @@ var (
 		RemotePushNotificationsEnabled,
 		SendPushNotifications,
 		SendStatusUpdates,
+		StickersMarketSeen,
 		StickersPacksInstalled,
+		StickersPacksOrder,
 		StickersPacksPending,
+		StickersPacksPendingBackup,
 		StickersRecentStickers,
+		StickersUsage,
 		SyncingOnMobileNetwork,
 		TelemetryServerURL,
 		TestNetworksEnabled,
@@ -265,7 +265,7 @@ func (db *Database) SetSettingLastSynced(setting SettingField, clock uint64) err
 
 func (db *Database) GetSettings() (Settings, error) {
 	var s Settings
-	err := db.db.QueryRow("SELECT address, anon_metrics_should_send, chaos_mode, currency, current_network, custom_bootnodes, custom_bootnodes_enabled, dapps_address, display_name, eip1581_address, fleet, hide_home_tooltip, installation_id, key_uid, keycard_instance_uid, keycard_paired_on, keycard_pairing, last_updated, latest_derived_path, link_preview_request_enabled, link_previews_enabled_sites, log_level, mnemonic, name, networks, notifications_enabled, push_notifications_server_enabled, push_notifications_from_contacts_only, remote_push_notifications_enabled, send_push_notifications, push_notifications_block_mentions, photo_path, pinned_mailservers, preferred_name, preview_privacy, public_key, remember_syncing_choice, signing_phrase, stickers_packs_installed, stickers_packs_pending, stickers_recent_stickers, syncing_on_mobile_network, default_sync_period, use_mailservers, messages_from_contacts_only, usernames, appearance, profile_pictures_show_to, profile_pictures_visibility, wallet_root_address, wallet_set_up_passed, wallet_visible_tokens, waku_bloom_filter_mode, webview_allow_permission_requests, current_user_status, send_status_updates, gif_recents, gif_favorites, opensea_enabled, last_backup, backup_enabled, telemetry_server_url, auto_message_enabled, gif_api_key, test_networks_enabled FROM settings WHERE synthetic_id = 'id'").Scan(
+	err := db.db.QueryRow("SELECT address, anon_metrics_should_send, chaos_mode, currency, current_network, custom_bootnodes, custom_bootnodes_enabled, dapps_address, display_name, eip1581_address, fleet, hide_home_tooltip, installation_id, key_uid, keycard_instance_uid, keycard_paired_on, keycard_pairing, last_updated, latest_derived_path, link_preview_request_enabled, link_previews_enabled_sites, log_level, mnemonic, name, networks, notifications_enabled, push_notifications_server_enabled, push_notifications_from_contacts_only, remote_push_notifications_enabled, send_push_notifications, push_notifications_block_mentions, photo_path, pinned_mailservers, preferred_name, preview_privacy, public_key, remember_syncing_choice, signing_phrase, stickers_market_seen, stickers_packs_installed, stickers_packs_order, stickers_packs_pending, stickers_packs_pending_backup, stickers_recent_stickers, stickers_usage, syncing_on_mobile_network, default_sync_period, use_mailservers, messages_from_contacts_only, usernames, appearance, profile_pictures_show_to, profile_pictures_visibility, wallet_root_address, wallet_set_up_passed, wallet_visible_tokens, waku_bloom_filter_mode, webview_allow_permission_requests, current_user_status, send_status_updates, gif_recents, gif_favorites, opensea_enabled, last_backup, backup_enabled, telemetry_server_url, auto_message_enabled, gif_api_key, test_networks_enabled FROM settings WHERE synthetic_id = 'id'").Scan(
 		&s.Address,
 		&s.AnonMetricsShouldSend,
 		&s.ChaosMode,
@@ -304,9 +304,13 @@ func (db *Database) GetSettings() (Settings, error) {
 		&s.PublicKey,
 		&s.RememberSyncingChoice,
 		&s.SigningPhrase,
+		&s.StickersMarketSeen,
 		&s.StickerPacksInstalled,
+		&s.StickerPacksOrder,
 		&s.StickerPacksPending,
+		&s.StickerPacksPendingBackup,
 		&s.StickersRecentStickers,
+		&s.StickersUsage,
 		&s.SyncingOnMobileNetwork,
 		&s.DefaultSyncPeriod,
 		&s.UseMailservers,
@@ -515,16 +519,36 @@ func (db *Database) GetInstalledStickerPacks() (rst *json.RawMessage, err error)
 	return
 }
 
+func (db *Database) GetStickerPacksOrder() (rst *json.RawMessage, err error) {
+	err = db.makeSelectRow(StickersPacksOrder).Scan(&rst)
+	return
+}
+
 func (db *Database) GetPendingStickerPacks() (rst *json.RawMessage, err error) {
 	err = db.makeSelectRow(StickersPacksPending).Scan(&rst)
 	return
 }
 
+func (db *Database) GetPendingStickerPacksBackup() (rst *json.RawMessage, err error) {
+	err = db.makeSelectRow(StickersPacksPendingBackup).Scan(&rst)
+	return
+}
+
 func (db *Database) GetRecentStickers() (rst *json.RawMessage, err error) {
 	err = db.makeSelectRow(StickersRecentStickers).Scan(&rst)
 	return
 }
 
+func (db *Database) GetStickersMarketSeen() (rst *json.RawMessage, err error) {
+	err = db.makeSelectRow(StickersMarketSeen).Scan(&rst)
+	return
+}
+
+func (db *Database) GetStickersUsage() (rst *json.RawMessage, err error) {
+	err = db.makeSelectRow(StickersUsage).Scan(&rst)
+	return
+}
+
 func (db *Database) SetPinnedMailservers(mailservers map[string]string) error {
 	return db.SaveSettingField(PinnedMailservers, mailservers)
 }
@@ -153,9 +153,13 @@ type Settings struct {
 	// RemotePushNotificationsEnabled indicates whether we should be using remote notifications (ios only for now)
 	RemotePushNotificationsEnabled bool             `json:"remote-push-notifications-enabled?,omitempty"`
 	SigningPhrase                  string           `json:"signing-phrase"`
+	StickersMarketSeen             *json.RawMessage `json:"stickers/market-seen,omitempty"`
 	StickerPacksInstalled          *json.RawMessage `json:"stickers/packs-installed,omitempty"`
+	StickerPacksOrder              *json.RawMessage `json:"stickers/packs-order,omitempty"`
 	StickerPacksPending            *json.RawMessage `json:"stickers/packs-pending,omitempty"`
+	StickerPacksPendingBackup      *json.RawMessage `json:"stickers/packs-pending-backup,omitempty"`
 	StickersRecentStickers         *json.RawMessage `json:"stickers/recent-stickers,omitempty"`
+	StickersUsage                  *json.RawMessage `json:"stickers/usage,omitempty"`
 	SyncingOnMobileNetwork         bool             `json:"syncing-on-mobile-network?,omitempty"`
 	// DefaultSyncPeriod is how far back in seconds we should pull messages from a mailserver
 	DefaultSyncPeriod uint `json:"default-sync-period"`
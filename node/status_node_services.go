@@ -21,6 +21,7 @@ import (
 	"github.com/status-im/status-go/multiaccounts/accounts"
 	"github.com/status-im/status-go/params"
 	"github.com/status-im/status-go/rpc"
+	"github.com/status-im/status-go/server"
 	accountssvc "github.com/status-im/status-go/services/accounts"
 	appmetricsservice "github.com/status-im/status-go/services/appmetrics"
 	"github.com/status-im/status-go/services/browsers"
@@ -98,6 +99,7 @@ func (b *StatusNode) initServices(config *params.NodeConfig) error {
 		}
 
 		b.wakuExtSrvc = wakuext
+		b.serveStickersLocally(wakuext.Service)
 
 		services = append(services, wakuext)
 	}
@@ -115,6 +117,7 @@ func (b *StatusNode) initServices(config *params.NodeConfig) error {
 		}
 
 		b.wakuV2ExtSrvc = wakuext
+		b.serveStickersLocally(wakuext.Service)
 
 		services = append(services, wakuext)
 	}
@@ -393,10 +396,23 @@ func (b *StatusNode) ensService() *ens.Service {
 func (b *StatusNode) stickersService(accountDB *accounts.Database) *stickers.Service {
 	if b.stickersSrvc == nil {
 		b.stickersSrvc = stickers.NewService(accountDB, b.rpcClient, b.gethAccountManager, b.rpcFiltersSrvc, b.config)
+		b.stickersSrvc.SetExchangeRates(wallet.CryptoCompareRates{})
 	}
 	return b.stickersSrvc
 }
 
+// serveStickersLocally serves the stickers and IPFS content through the media
+// server of the messenger, so that sticker URLs point at localhost
+func (b *StatusNode) serveStickersLocally(extService *ext.Service) {
+	extService.SetMediaServerOptions(
+		server.WithStickerFetcher(b.stickersSrvc.FetchSticker),
+		server.WithIPFSFetcher(b.stickersSrvc.FetchIPFS),
+		server.WithCacheTrimmer(b.stickersSrvc.TrimCaches),
+		server.WithCacheStats(b.stickersSrvc.CacheStats),
+	)
+	b.stickersSrvc.SetMediaServerURL(extService.MediaServerURL)
+}
+
 func (b *StatusNode) gifService(accountsDB *accounts.Database) *gif.Service {
 	if b.gifSrvc == nil {
 		b.gifSrvc = gif.NewService(accountsDB)
@@ -469,6 +469,9 @@ type NodeConfig struct {
 	// (desktop provider API)
 	Web3ProviderConfig Web3ProviderConfig
 
+	// StickersConfig extra configuration for stickers.Service
+	StickersConfig StickersConfig
+
 	// SwarmConfig extra configuration for Swarm and ENS
 	SwarmConfig SwarmConfig `json:"SwarmConfig," validate:"structonly"`
 
@@ -534,6 +537,23 @@ type Web3ProviderConfig struct {
 	Enabled bool
 }
 
+// StickersConfig extra configuration for stickers.Service.
+type StickersConfig struct {
+	// GatewayBaseURL is the IPFS subdomain gateway used to build the sticker
+	// URLs, the default gateway being used when it's empty
+	GatewayBaseURL string `json:"GatewayBaseURL"`
+	// Contracts selects the sticker packs contract of a chain by chain ID,
+	// chains without an entry use the legacy contracts
+	Contracts map[uint64]StickerContractConfig `json:"Contracts"`
+}
+
+// StickerContractConfig describes the sticker packs contract deployed on a
+// chain, Type being "legacy" or "erc1155"
+type StickerContractConfig struct {
+	Type    string `json:"Type"`
+	Address string `json:"Address"`
+}
+
 // BridgeConfig provides configuration for Whisper-Waku bridge.
 type BridgeConfig struct {
 	Enabled bool
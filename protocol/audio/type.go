@@ -1,6 +1,9 @@
 package audio
 
 import (
+	"bytes"
+	"errors"
+
 	"github.com/status-im/status-go/protocol/protobuf"
 )
 
@@ -17,6 +20,22 @@ func amr(buf []byte) bool {
 		buf[4] == 0x52 && buf[5] == 0x0A
 }
 
+func ogg(buf []byte) bool {
+	return bytes.HasPrefix(buf, []byte("OggS"))
+}
+
+func mp3(buf []byte) bool {
+	if bytes.HasPrefix(buf, []byte("ID3")) {
+		return true
+	}
+	// MPEG audio frame sync, AAC ADTS headers have a zero layer
+	return len(buf) > 1 && buf[0] == 0xFF && buf[1]&0xE0 == 0xE0 && buf[1]&0x06 != 0
+}
+
+func mp4(buf []byte) bool {
+	return len(buf) > 11 && bytes.Equal(buf[4:8], []byte("ftyp"))
+}
+
 func Type(buf []byte) protobuf.AudioMessage_AudioType {
 	switch {
 	case aac(buf):
@@ -27,3 +46,22 @@ func Type(buf []byte) protobuf.AudioMessage_AudioType {
 		return protobuf.AudioMessage_UNKNOWN_AUDIO_TYPE
 	}
 }
+
+// Mime returns the MIME type of the audio in buf, detected from its first
+// bytes
+func Mime(buf []byte) (string, error) {
+	switch {
+	case aac(buf):
+		return "audio/aac", nil
+	case amr(buf):
+		return "audio/amr", nil
+	case ogg(buf):
+		return "audio/ogg", nil
+	case mp3(buf):
+		return "audio/mpeg", nil
+	case mp4(buf):
+		return "audio/mp4", nil
+	default:
+		return "", errors.New("mime type not found")
+	}
+}
@@ -0,0 +1,33 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMime(t *testing.T) {
+	testCases := []struct {
+		name string
+		buf  []byte
+		mime string
+	}{
+		{"aac", []byte{0xFF, 0xF1, 0x50, 0x80}, "audio/aac"},
+		{"amr", []byte("#!AMR\n\x3c\x00\x00\x00\x00\x00"), "audio/amr"},
+		{"ogg", []byte("OggS\x00\x02"), "audio/ogg"},
+		{"mp3 with tag", []byte("ID3\x04\x00"), "audio/mpeg"},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x64}, "audio/mpeg"},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A \x00\x00"), "audio/mp4"},
+	}
+
+	for _, tc := range testCases {
+		mime, err := Mime(tc.buf)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.mime, mime, tc.name)
+	}
+
+	_, err := Mime([]byte("audio"))
+	require.Error(t, err)
+	_, err = Mime(nil)
+	require.Error(t, err)
+}
@@ -391,7 +391,7 @@ func NewMessenger(
 	}
 
 	mailservers := mailserversDB.NewDB(database)
-	httpServer, err := server.NewServer(database, logger)
+	httpServer, err := server.NewServer(database, logger, c.mediaServerOptions...)
 
 	if err != nil {
 		return nil, err
@@ -578,6 +578,15 @@ func (m *Messenger) ToBackground() {
 	}
 }
 
+// MediaServerURL returns the base URL of the local media server, or an empty
+// string if it is not listening.
+func (m *Messenger) MediaServerURL() string {
+	if m.httpServer == nil {
+		return ""
+	}
+	return m.httpServer.BaseURL()
+}
+
 func (m *Messenger) Start() (*MessengerResponse, error) {
 	m.logger.Info("starting messenger", zap.String("identity", types.EncodeHex(crypto.FromECDSAPub(&m.identity.PublicKey))))
 	// Start push notification server
@@ -20,6 +20,7 @@ import (
 	"github.com/status-im/status-go/protocol/pushnotificationclient"
 	"github.com/status-im/status-go/protocol/pushnotificationserver"
 	"github.com/status-im/status-go/protocol/transport"
+	"github.com/status-im/status-go/server"
 	"github.com/status-im/status-go/services/mailservers"
 )
 
@@ -68,6 +69,7 @@ type config struct {
 	clusterConfig       params.ClusterConfig
 	browserDatabase     *browsers.Database
 	torrentConfig       *params.TorrentConfig
+	mediaServerOptions  []server.Option
 
 	verifyTransactionClient  EthClient
 	verifyENSURL             string
@@ -275,3 +277,12 @@ func WithTorrentConfig(tc *params.TorrentConfig) Option {
 		return nil
 	}
 }
+
+// WithMediaServerOptions configures the local media server, e.g. with the
+// fetchers serving the /stickers and /ipfs routes.
+func WithMediaServerOptions(opts ...server.Option) Option {
+	return func(c *config) error {
+		c.mediaServerOptions = append(c.mediaServerOptions, opts...)
+		return nil
+	}
+}
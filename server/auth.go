@@ -0,0 +1,64 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+)
+
+// Number of random bytes of the generated basic auth passwords
+const basicAuthPasswordBytes = 16
+
+// basicAuth holds the credentials accepted by the server
+type basicAuth struct {
+	username string
+	password string
+}
+
+// WithBasicAuth requires clients to authenticate with HTTP Basic credentials
+// for username and a password generated for the lifetime of the server, see
+// BasicAuthCredentials. Without it, the routes are open
+func WithBasicAuth(username string) Option {
+	return func(s *Server) error {
+		if username == "" {
+			return errors.New("empty basic auth username")
+		}
+
+		password := make([]byte, basicAuthPasswordBytes)
+		if _, err := rand.Read(password); err != nil {
+			return err
+		}
+
+		s.auth = &basicAuth{username: username, password: hex.EncodeToString(password)}
+		return nil
+	}
+}
+
+// BasicAuthCredentials returns the credentials clients have to present, to
+// be embedded in the media URLs. It returns false if the server doesn't
+// require authentication
+func (s *Server) BasicAuthCredentials() (string, string, bool) {
+	if s.auth == nil {
+		return "", "", false
+	}
+	return s.auth.username, s.auth.password, true
+}
+
+// authenticate rejects the requests without valid credentials with 401
+func (a *basicAuth) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		// Both are compared so that the time taken doesn't tell which is wrong
+		validUsername := subtle.ConstantTimeCompare([]byte(username), []byte(a.username)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) == 1
+		if !ok || !validUsername || !validPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="status-go", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
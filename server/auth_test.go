@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBasicAuth(t *testing.T) {
+	_, err := NewServer(nil, zap.NewNop(), WithBasicAuth(""))
+	require.Error(t, err)
+
+	s, err := NewServer(nil, zap.NewNop(), WithBasicAuth("status"))
+	require.NoError(t, err)
+
+	username, password, ok := s.BasicAuthCredentials()
+	require.True(t, ok)
+	require.Equal(t, "status", username)
+	require.Len(t, password, 2*basicAuthPasswordBytes)
+
+	other, err := NewServer(nil, zap.NewNop(), WithBasicAuth("status"))
+	require.NoError(t, err)
+	_, otherPassword, _ := other.BasicAuthCredentials()
+	require.NotEqual(t, password, otherPassword)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	get := func(username, password string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/messages/identicons?publicKey="+testPublicKey, nil)
+		require.NoError(t, err)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+
+	for _, credentials := range [][2]string{{"", ""}, {"status", "wrong"}, {"other", password}} {
+		resp := get(credentials[0], credentials[1])
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode, credentials)
+		require.Contains(t, resp.Header.Get("WWW-Authenticate"), "Basic")
+	}
+
+	require.Equal(t, http.StatusOK, get(username, password).StatusCode)
+
+	// Credentials embedded in the URL are sent as basic auth
+	resp, err := http.Get("http://" + username + ":" + password + "@" + ts.Listener.Addr().String() + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestNoAuth(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	_, _, ok := s.BasicAuthCredentials()
+	require.False(t, ok)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+)
+
+// Cache-Control of the identicons, which never change
+const defaultIdenticonCacheControl = "max-age:290304000, public"
+
+// Cache-Control of the identicons for privacy sensitive profiles, which don't
+// keep them on disk
+const privateIdenticonCacheControl = "no-store"
+
+// CachePolicy maps built-in routes to the Cache-Control header of their
+// successful responses
+type CachePolicy map[string]string
+
+func defaultCachePolicy() CachePolicy {
+	return CachePolicy{
+		"/messages/images":     "no-store",
+		"/messages/audio":      "no-store",
+		"/messages/avatar":     "no-store",
+		"/messages/identicons": defaultIdenticonCacheControl,
+		"/stickers":            "public, max-age=31536000, immutable",
+		"/ipfs":                "public, max-age=31536000, immutable",
+	}
+}
+
+// WithCachePolicy overrides the Cache-Control header of the given routes,
+// the other routes keep their default policy. For the avatar route, it
+// applies to stored avatars, the identicon fallback is always revalidated or,
+// with WithPrivateIdenticons, not stored at all
+func WithCachePolicy(policy CachePolicy) Option {
+	return func(s *Server) error {
+		for route, cacheControl := range policy {
+			if _, ok := s.cachePolicy[route]; !ok {
+				return fmt.Errorf("no cache policy for route %s", route)
+			}
+			s.cachePolicy[route] = cacheControl
+		}
+		return nil
+	}
+}
+
+// WithPrivateIdenticons keeps the identicons out of the client caches, so
+// that artifacts derived from public keys don't outlive the session. It's a
+// shorthand for the no-store cache policy on the identicons route, which also
+// applies to the identicon fallback of the avatars
+func WithPrivateIdenticons() Option {
+	return WithCachePolicy(CachePolicy{"/messages/identicons": privateIdenticonCacheControl})
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCachePolicy(t *testing.T) {
+	get := func(s *Server, path string) *http.Response {
+		ts := httptest.NewServer(s.routes())
+		defer ts.Close()
+
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp
+	}
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	resp := get(s, "/messages/identicons?publicKey="+testPublicKey)
+	require.Equal(t, defaultIdenticonCacheControl, resp.Header.Get("Cache-Control"))
+	require.NotEmpty(t, resp.Header.Get("Expires"))
+
+	s, err = NewServer(nil, zap.NewNop(), WithCachePolicy(CachePolicy{"/messages/identicons": "private, max-age=3600"}))
+	require.NoError(t, err)
+	require.Equal(t, "no-store", s.cachePolicy["/messages/images"])
+
+	resp = get(s, "/messages/identicons?publicKey="+testPublicKey)
+	require.Equal(t, "private, max-age=3600", resp.Header.Get("Cache-Control"))
+	require.Empty(t, resp.Header.Get("Expires"))
+
+	_, err = NewServer(nil, zap.NewNop(), WithCachePolicy(CachePolicy{"/unknown": "no-store"}))
+	require.Error(t, err)
+}
+
+func TestPrivateIdenticons(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop(), WithPrivateIdenticons(), WithMediaStore(&memoryMediaStore{}))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	require.Empty(t, resp.Header.Get("Expires"))
+
+	// The identicon fallback of the avatars isn't stored either
+	resp, err = http.Get(ts.URL + "/messages/avatar?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	// Other routes keep their policy
+	require.Equal(t, defaultCachePolicy()["/stickers"], s.cachePolicy["/stickers"])
+}
@@ -0,0 +1,48 @@
+package server
+
+// CacheStats counts the lookups of a cache
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// HitRatio returns the share of lookups that were hits, zero without lookups
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// CacheStatsSource returns the lookup counters of caches kept outside of the
+// server, by cache name
+type CacheStatsSource func() map[string]CacheStats
+
+// WithCacheStats reports the counters of the caches of other services along
+// with the server caches, from CacheStats and /metrics
+func WithCacheStats(source CacheStatsSource) Option {
+	return func(s *Server) error {
+		s.statsSources = append(s.statsSources, source)
+		return nil
+	}
+}
+
+// CacheStats returns the lookup counters of the server caches, and of those
+// reported WithCacheStats, by cache name
+func (s *Server) CacheStats() map[string]CacheStats {
+	stats := map[string]CacheStats{
+		s.variants.name: s.variants.Stats(),
+		s.stickers.name: s.stickers.Stats(),
+		s.ipfs.name:     s.ipfs.Stats(),
+	}
+	if s.images != nil {
+		stats[s.images.name] = s.images.Stats()
+	}
+	for _, source := range s.statsSources {
+		for name, cacheStats := range source() {
+			stats[name] = cacheStats
+		}
+	}
+	return stats
+}
@@ -1,11 +1,15 @@
 package server
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"math/big"
 	"time"
 )
@@ -25,7 +29,7 @@ func GenerateX509Cert(from, to time.Time) (*x509.Certificate, error) {
 		NotAfter:              to,
 		DNSNames:              []string{"localhost"},
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
 		IsCA:                  true,
 	}
@@ -40,11 +44,74 @@ func GenerateX509PEMs(cert *x509.Certificate, key *ecdsa.PrivateKey) (certPem, k
 	}
 	certPem = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
 
+	keyPem, err = encodeKeyPEM(key)
+	return
+}
+
+// GenerateClientCertPEMs generates a client certificate signed by ca, for
+// servers requiring client authentication
+func GenerateClientCertPEMs(ca *tls.Certificate, from, to time.Time) (certPem, keyPem []byte, err error) {
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return
+	}
+
+	caKey, ok := ca.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		err = errors.New("unsupported CA private key")
+		return
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return
+	}
+
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{Organization: []string{"Client cert"}},
+		NotBefore:    from,
+		NotAfter:     to,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return
+	}
+	certPem = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+
+	keyPem, err = encodeKeyPEM(key)
+	return
+}
+
+func encodeKeyPEM(key crypto.PrivateKey) ([]byte, error) {
 	privBytes, err := x509.MarshalPKCS8PrivateKey(key)
 	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}), nil
+}
+
+// encodeTLSCertPEMs serializes the certificate chain and the private key of
+// cert to PEM
+func encodeTLSCertPEMs(cert *tls.Certificate) (certPem, keyPem []byte, err error) {
+	if cert == nil || len(cert.Certificate) == 0 {
+		err = errors.New("no certificate")
 		return
 	}
-	keyPem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
 
+	for _, der := range cert.Certificate {
+		certPem = append(certPem, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+
+	keyPem, err = encodeKeyPEM(cert.PrivateKey)
 	return
 }
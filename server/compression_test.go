@@ -0,0 +1,92 @@
+package server
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	require.Equal(t, "gzip", acceptedEncoding("gzip, deflate, br"))
+	require.Equal(t, "gzip", acceptedEncoding("deflate;q=0.5, GZIP"))
+	require.Equal(t, "deflate", acceptedEncoding("deflate"))
+	require.Equal(t, "deflate", acceptedEncoding("gzip;q=0, deflate"))
+	require.Equal(t, "", acceptedEncoding("br"))
+	require.Equal(t, "", acceptedEncoding(""))
+}
+
+func TestCompression(t *testing.T) {
+	text := strings.Repeat(`{"key":"value"}`, 100)
+
+	get := func(s *Server, path, acceptEncoding string) (*http.Response, []byte) {
+		ts := httptest.NewServer(s.routes())
+		defer ts.Close()
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		if acceptEncoding != "" {
+			// Setting the header disables the transport transparent decompression
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body io.Reader = resp.Body
+		switch resp.Header.Get("Content-Encoding") {
+		case "gzip":
+			body, err = gzip.NewReader(resp.Body)
+			require.NoError(t, err)
+		case "deflate":
+			body = flate.NewReader(resp.Body)
+		}
+
+		data, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp, data
+	}
+
+	newServer := func(opts ...Option) *Server {
+		s, err := NewServer(nil, zap.NewNop(), opts...)
+		require.NoError(t, err)
+		s.Handle("/json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(text))
+		}))
+		return s
+	}
+
+	s := newServer(WithCompression())
+	for _, encoding := range []string{"gzip", "deflate"} {
+		resp, body := get(s, "/json", encoding)
+		require.Equal(t, encoding, resp.Header.Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+		require.Equal(t, text, string(body))
+	}
+
+	resp, body := get(s, "/json", "")
+	require.Empty(t, resp.Header.Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	require.Equal(t, text, string(body))
+
+	// PNG identicons are already compressed
+	resp, _ = get(s, "/messages/identicons?publicKey="+testPublicKey, "gzip")
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	// Compression is disabled by default
+	resp, body = get(newServer(), "/json", "gzip")
+	require.Empty(t, resp.Header.Get("Content-Encoding"))
+	require.Empty(t, resp.Header.Get("Vary"))
+	require.Equal(t, text, string(body))
+}
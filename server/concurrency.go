@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Seconds after which the clients turned away by the concurrency limit are
+// told to retry
+const concurrencyRetryAfter = "1"
+
+// WithMaxConcurrentRequests bounds the number of requests served at once, as
+// each of them can buffer a whole media payload in memory. Up to queued
+// requests over the limit wait for a slot, the others fail with 503
+func WithMaxConcurrentRequests(max int, queued int) Option {
+	return func(s *Server) error {
+		if max <= 0 || queued < 0 {
+			return errors.New("invalid max concurrent requests")
+		}
+
+		s.limiter = newConcurrencyLimiter(max, queued)
+		return nil
+	}
+}
+
+// concurrencyLimiter is a semaphore of the requests served, admitting a
+// bounded number of waiting requests
+type concurrencyLimiter struct {
+	// slots holds a token per request being served
+	slots chan struct{}
+	// admitted holds a token per request being served or waiting for a slot
+	admitted chan struct{}
+}
+
+func newConcurrencyLimiter(max int, queued int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		slots:    make(chan struct{}, max),
+		admitted: make(chan struct{}, max+queued),
+	}
+}
+
+// limit serves the requests once they get a slot, rejecting them with 503
+// when the queue is full. Requests whose client goes away while waiting
+// are dropped
+func (l *concurrencyLimiter) limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.admitted <- struct{}{}:
+		default:
+			w.Header().Set("Retry-After", concurrencyRetryAfter)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer func() { <-l.admitted }()
+
+		select {
+		case l.slots <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		defer func() { <-l.slots }()
+
+		next.ServeHTTP(w, r)
+	})
+}
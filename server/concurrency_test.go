@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	_, err := NewServer(nil, zap.NewNop(), WithMaxConcurrentRequests(0, 1))
+	require.Error(t, err)
+	_, err = NewServer(nil, zap.NewNop(), WithMaxConcurrentRequests(1, -1))
+	require.Error(t, err)
+
+	const max = 2
+	const queued = 1
+	const requests = 5
+
+	s, err := NewServer(nil, zap.NewNop(), WithMaxConcurrentRequests(max, queued))
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	release := make(chan struct{})
+	s.Handle("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	type result struct {
+		status     int
+		retryAfter string
+	}
+	results := make(chan result, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			resp, err := http.Get(ts.URL + "/slow")
+			if err != nil {
+				results <- result{}
+				return
+			}
+			resp.Body.Close()
+			results <- result{resp.StatusCode, resp.Header.Get("Retry-After")}
+		}()
+	}
+
+	// The requests over the limit and the queue are rejected right away
+	for i := 0; i < requests-max-queued; i++ {
+		select {
+		case res := <-results:
+			require.Equal(t, http.StatusServiceUnavailable, res.status)
+			require.Equal(t, concurrencyRetryAfter, res.retryAfter)
+		case <-time.After(5 * time.Second):
+			t.Fatal("requests over the limit weren't rejected")
+		}
+	}
+	require.Len(t, s.limiter.admitted, max+queued)
+	require.Eventually(t, func() bool { return len(s.limiter.slots) == max }, 5*time.Second, 10*time.Millisecond)
+
+	// The queued request is served once a slot is released
+	close(release)
+	for i := 0; i < max+queued; i++ {
+		select {
+		case res := <-results:
+			require.Equal(t, http.StatusOK, res.status)
+		case <-time.After(5 * time.Second):
+			t.Fatal("admitted requests weren't served")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, max, maxInFlight)
+	require.Len(t, s.limiter.admitted, 0)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Maximum length of the filenames suggested for downloads
+const maxDownloadFilenameLength = 128
+
+// mediaExtensions are the file extensions of the served media types
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"audio/aac":  ".aac",
+	"audio/amr":  ".amr",
+	"audio/ogg":  ".ogg",
+	"audio/mpeg": ".mp3",
+	"audio/mp4":  ".m4a",
+}
+
+// wantsDownload tells whether the request asks for the media as an attachment
+// to be saved rather than displayed
+func wantsDownload(r *http.Request) bool {
+	download, err := strconv.ParseBool(r.URL.Query().Get("download"))
+	return err == nil && download
+}
+
+// setContentDisposition makes the media an attachment when the request asks to
+// download it, suggesting the requested filename or one derived from the
+// message ID, with the extension of the media type
+func setContentDisposition(w http.ResponseWriter, r *http.Request, messageID, contentType string) {
+	if !wantsDownload(r) {
+		return
+	}
+
+	filename := sanitizeFilename(r.URL.Query().Get("filename"))
+	if filename == "" {
+		filename = sanitizeFilename(messageID)
+	}
+	if filename == "" {
+		filename = "media"
+	}
+
+	if ext := mediaExtensions[contentType]; ext != "" && path.Ext(filename) == "" {
+		filename += ext
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
+
+// sanitizeFilename keeps the letters, digits, dots, dashes and underscores of
+// name, replacing other characters with underscores, so that the filename
+// can't inject header fields or escape the download directory
+func sanitizeFilename(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+
+	sanitized = strings.TrimLeft(sanitized, ".")
+	if len(sanitized) > maxDownloadFilenameLength {
+		sanitized = sanitized[:maxDownloadFilenameLength]
+	}
+	return sanitized
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"photo.png", "photo.png"},
+		{"0x1234-abcd_ef", "0x1234-abcd_ef"},
+		{"../../etc/passwd", "_.._etc_passwd"},
+		{"a\"b\r\nX-Header: 1", "a_b__X-Header__1"},
+		{"été.jpg", "_t_.jpg"},
+		{"...", ""},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.expected, sanitizeFilename(c.name), c.name)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	db, stop := setupTestDB(t)
+	defer stop()
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload, audio_payload) VALUES (?, ?, ?)`, "0x01", image, []byte{0xFF, 0xF1, 0x50, 0x80})
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	disposition := func(method, path string, query url.Values) string {
+		req, err := http.NewRequest(method, ts.URL+path+"?"+query.Encode(), nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.Header.Get("Content-Disposition")
+	}
+
+	inline := url.Values{"messageId": {"0x01"}}
+	require.Empty(t, disposition(http.MethodGet, "/messages/images", inline))
+	require.Empty(t, disposition(http.MethodGet, "/messages/audio", inline))
+
+	download := url.Values{"messageId": {"0x01"}, "download": {"1"}}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		require.Equal(t, `attachment; filename=0x01.png`, disposition(method, "/messages/images", download))
+		require.Equal(t, `attachment; filename=0x01.aac`, disposition(method, "/messages/audio", download))
+	}
+
+	named := url.Values{"messageId": {"0x01"}, "download": {"true"}, "filename": {"holiday\r\nSet-Cookie: a=b"}}
+	require.Equal(t, `attachment; filename=holiday__Set-Cookie__a_b.png`, disposition(http.MethodGet, "/messages/images", named))
+
+	named.Set("filename", "holiday.jpeg")
+	require.Equal(t, `attachment; filename=holiday.jpeg`, disposition(http.MethodGet, "/messages/images", named))
+}
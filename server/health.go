@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Time allowed to check the health of the media store
+const healthCheckTimeout = 5 * time.Second
+
+// pinger is implemented by the media stores able to check their connection
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthHandler reports whether the media store is reachable, with 503 when
+// it isn't
+type healthHandler struct {
+	store  MediaStore
+	logger *zap.Logger
+}
+
+func (s *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
+
+	status := healthStatus{Status: "ok"}
+	code := http.StatusOK
+	if p, ok := s.store.(pinger); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		if err := p.Ping(ctx); err != nil {
+			logger.Error("media store unhealthy", zap.Error(err))
+			status = healthStatus{Status: "unavailable", Error: err.Error()}
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(status)
+	if err != nil {
+		logger.Error("failed to write health status", zap.Error(err))
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func TestSetDB(t *testing.T) {
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	oldDB, stopOld := setupTestDB(t)
+	defer stopOld()
+	_, err = oldDB.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?)`, "old", image)
+	require.NoError(t, err)
+
+	newDB, stopNew := setupTestDB(t)
+	defer stopNew()
+	_, err = newDB.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?)`, "new", image)
+	require.NoError(t, err)
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	get := func(path string) int {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+
+	// The routes are kept while the database is swapped
+	require.Equal(t, http.StatusServiceUnavailable, get("/health"))
+	require.Equal(t, http.StatusInternalServerError, get("/messages/images?messageId=old"))
+
+	require.NoError(t, s.SetDB(oldDB))
+	require.Equal(t, http.StatusOK, get("/health"))
+	require.Equal(t, http.StatusOK, get("/messages/images?messageId=old"))
+	require.Equal(t, http.StatusNotFound, get("/messages/images?messageId=new"))
+
+	require.NoError(t, s.SetDB(newDB))
+	require.Equal(t, http.StatusNotFound, get("/messages/images?messageId=old"))
+	require.Equal(t, http.StatusOK, get("/messages/images?messageId=new"))
+
+	require.Error(t, s.SetDB(nil))
+
+	custom, err := NewServer(nil, zap.NewNop(), WithMediaStore(NewSQLMediaStore(oldDB)))
+	require.NoError(t, err)
+	require.Error(t, custom.SetDB(newDB))
+}
+
+func TestHealth(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	check := func() (int, healthStatus) {
+		resp, err := http.Get(ts.URL + "/health")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var status healthStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		return resp.StatusCode, status
+	}
+
+	code, status := check()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok", status.Status)
+
+	// The connection goes bad
+	require.NoError(t, db.Close())
+	code, status = check()
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "unavailable", status.Status)
+	require.NotEmpty(t, status.Error)
+}
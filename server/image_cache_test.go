@@ -0,0 +1,66 @@
+package server
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func TestImageCache(t *testing.T) {
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+	connector := &busyConnector{payload: image}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	s, err := NewServer(db, zap.NewNop(), WithImageCache(1024*1024))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	get := func() []byte {
+		resp, err := http.Get(ts.URL + "/messages/images?messageId=1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return body
+	}
+
+	require.Equal(t, image, get())
+	require.Equal(t, image, get())
+	require.Equal(t, 1, connector.queries)
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1}, s.CacheStats()["images"])
+
+	// Swapping the database drops the cached images
+	otherImage, err := identicon.Generate("0x04bb")
+	require.NoError(t, err)
+	otherConnector := &busyConnector{payload: otherImage}
+	otherDB := sql.OpenDB(otherConnector)
+	defer otherDB.Close()
+
+	require.NoError(t, s.SetDB(otherDB))
+	require.Equal(t, otherImage, get())
+	require.Equal(t, otherImage, get())
+	require.Equal(t, 1, otherConnector.queries)
+}
+
+func TestImageCacheDisabled(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.Nil(t, s.images)
+	require.NotContains(t, s.CacheStats(), "images")
+
+	_, err = NewServer(nil, zap.NewNop(), WithImageCache(0))
+	require.Error(t, err)
+}
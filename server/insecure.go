@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var ErrInsecure = errors.New("server runs without TLS")
+
+// insecureServers counts the running insecure servers, so that clients aren't
+// given a certificate that isn't served while any of them runs
+var insecureServers int32
+
+func startInsecure() {
+	atomic.AddInt32(&insecureServers, 1)
+}
+
+func stopInsecure() {
+	atomic.AddInt32(&insecureServers, -1)
+}
+
+func isInsecureMode() bool {
+	return atomic.LoadInt32(&insecureServers) > 0
+}
+
+// WithInsecure makes the server serve plain HTTP, without generating a
+// certificate. It's meant for local debugging only, and makes PublicTLSCert
+// fail while the server runs
+func WithInsecure() Option {
+	return func(s *Server) error {
+		s.Insecure = true
+		return nil
+	}
+}
+
+// ensureCertificate sets the certificate served, the global one or one of the
+// server's own, unless it was set already. Insecure servers are created
+// without certificate
+func (s *Server) ensureCertificate() error {
+	s.certLock.Lock()
+	defer s.certLock.Unlock()
+
+	if s.cert != nil {
+		return nil
+	}
+
+	if s.ownCert {
+		cert, _, err := newTLSCert()
+		if err != nil {
+			return err
+		}
+		s.cert = cert
+		return nil
+	}
+
+	err := generateTLSCert()
+	if err != nil {
+		return err
+	}
+
+	s.cert, _ = globalTLSCert()
+	return nil
+}
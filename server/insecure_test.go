@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestInsecure(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.False(t, s.Insecure)
+
+	s, err = NewServer(nil, zap.NewNop(), WithInsecure())
+	require.NoError(t, err)
+	require.True(t, s.Insecure)
+
+	require.NoError(t, s.Start())
+	addr := waitListening(t, s)
+
+	_, err = PublicTLSCert()
+	require.Equal(t, ErrInsecure, err)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	resp, err := client.Get("http://" + addr.String() + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	client.CloseIdleConnections()
+	require.NoError(t, s.Stop())
+
+	_, err = PublicTLSCert()
+	require.NoError(t, err)
+}
+
+func TestInsecureServers(t *testing.T) {
+	first, err := NewServer(nil, zap.NewNop(), WithInsecure())
+	require.NoError(t, err)
+	second, err := NewServer(nil, zap.NewNop(), WithInsecure())
+	require.NoError(t, err)
+	secure, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, first.Start())
+	waitListening(t, first)
+	require.NoError(t, second.Start())
+	waitListening(t, second)
+
+	// A secure server doesn't end insecure mode
+	require.NoError(t, secure.Start())
+	waitListening(t, secure)
+	require.NoError(t, secure.Stop())
+
+	_, err = PublicTLSCert()
+	require.Equal(t, ErrInsecure, err)
+
+	// Insecure mode lasts until the last insecure server stops
+	require.NoError(t, first.Stop())
+	_, err = PublicTLSCert()
+	require.Equal(t, ErrInsecure, err)
+
+	// Stopping twice doesn't count the server twice
+	require.NoError(t, first.Stop())
+	_, err = PublicTLSCert()
+	require.Equal(t, ErrInsecure, err)
+
+	require.NoError(t, second.Stop())
+	_, err = PublicTLSCert()
+	require.NoError(t, err)
+}
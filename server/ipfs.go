@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/eventbus"
+	"github.com/status-im/status-go/protocol/images"
+)
+
+// Default total size of the IPFS content kept in memory
+const defaultIPFSCacheBytes = 32 * 1024 * 1024
+
+// Time allowed to fetch IPFS content from the gateway
+const ipfsFetchTimeout = 30 * time.Second
+
+// IPFSFetcher downloads IPFS content by CID
+type IPFSFetcher func(ctx context.Context, cid string) ([]byte, error)
+
+type ipfsHandler struct {
+	fetch        IPFSFetcher
+	cache        *variantCache
+	logger       *zap.Logger
+	events       *eventbus.Bus
+	cacheControl string
+	maxBytes     int64
+	timeout      time.Duration
+}
+
+func (s *ipfsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
+
+	rawCID := r.URL.Query().Get("cid")
+	if rawCID == "" {
+		logger.Error("no cid")
+		http.Error(w, "no cid", http.StatusBadRequest)
+		return
+	}
+
+	contentID, err := cid.Decode(rawCID)
+	if err != nil {
+		logger.Error("invalid cid", zap.String("cid", rawCID), zap.Error(err))
+		http.Error(w, "invalid cid", http.StatusBadRequest)
+		return
+	}
+	// The same content can be requested with different encodings of its CID
+	key := contentID.String()
+
+	content, ok := s.cache.Get(key, "")
+	if !ok {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+
+		content, err = s.fetch(ctx, key)
+		if err != nil {
+			logger.Error("failed to fetch ipfs content", zap.String("cid", key), zap.Error(err))
+			status := http.StatusBadGateway
+			if errors.Is(err, context.DeadlineExceeded) || ctx.Err() == context.DeadlineExceeded {
+				status = http.StatusGatewayTimeout
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		if payloadTooLarge(w, logger, "ipfs", int64(len(content)), s.maxBytes) {
+			return
+		}
+
+		s.cache.Add(key, "", content)
+	}
+
+	mime, err := images.ImageMime(content)
+	if err != nil {
+		mime = http.DetectContentType(content)
+	}
+
+	// IPFS content is content addressed, so it never changes
+	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Cache-Control", s.cacheControl)
+
+	err = writePayload(w, r, content)
+	if errors.Is(err, context.Canceled) {
+		logger.Debug("client disconnected while writing ipfs content")
+		return
+	}
+	if err != nil {
+		logger.Error("failed to write ipfs content", zap.Error(err))
+		return
+	}
+
+	s.events.Publish(eventbus.MediaServed, eventbus.MediaServedPayload{Kind: "ipfs", ID: key})
+}
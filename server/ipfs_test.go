@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func testCID(t *testing.T, data []byte) cid.Cid {
+	mh, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	require.NoError(t, err)
+	return cid.NewCidV1(cid.Raw, mh)
+}
+
+func TestIPFSHandler(t *testing.T) {
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	imageCID := testCID(t, image)
+	hugeCID := testCID(t, []byte("huge"))
+	slowCID := testCID(t, []byte("slow"))
+
+	var mu sync.Mutex
+	fetches := 0
+	fetch := func(ctx context.Context, contentID string) ([]byte, error) {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+
+		switch contentID {
+		case imageCID.String():
+			return image, nil
+		case hugeCID.String():
+			return make([]byte, 1025), nil
+		case slowCID.String():
+			<-ctx.Done()
+			return nil, ctx.Err()
+		default:
+			return nil, errors.New("gateway unavailable")
+		}
+	}
+
+	s, err := NewServer(nil, zap.NewNop(), WithIPFSFetcher(fetch), WithMaxPayloadBytes(1024))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	// The content is cached by CID, whatever its encoding
+	base58, err := imageCID.StringOfBase('z')
+	require.NoError(t, err)
+	for _, query := range []string{imageCID.String(), base58} {
+		resp, err := http.Get(ts.URL + "/ipfs?cid=" + query)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		require.Contains(t, resp.Header.Get("Cache-Control"), "immutable")
+		require.Equal(t, image, body)
+	}
+	require.Equal(t, 1, fetches, "content wasn't cached")
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1}, s.CacheStats()["ipfs"])
+
+	handler := s.builtinRoutes()["/ipfs"].(*ipfsHandler)
+	handler.timeout = 10 * time.Millisecond
+	tts := httptest.NewServer(handler)
+	defer tts.Close()
+
+	for query, status := range map[string]int{
+		"":                                 http.StatusBadRequest,
+		"?cid=notacid":                     http.StatusBadRequest,
+		"?cid=" + testCID(t, nil).String(): http.StatusBadGateway,
+		"?cid=" + hugeCID.String():         http.StatusRequestEntityTooLarge,
+		"?cid=" + slowCID.String():         http.StatusGatewayTimeout,
+	} {
+		resp, err := http.Get(tts.URL + "/ipfs" + query)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, status, resp.StatusCode, query)
+	}
+}
+
+func TestIPFSHandlerDisabled(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/ipfs?cid=bafkqaaa")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
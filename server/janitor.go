@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Share of their budget the caches are trimmed down to by TrimCaches when no
+// janitor is set
+const defaultCacheLowWater = 0.5
+
+// WithCacheJanitor trims the in-memory caches down to lowWater of their
+// budget every interval while the server runs, so that they don't hold on
+// to memory the device may need
+func WithCacheJanitor(interval time.Duration, lowWater float64) Option {
+	return func(s *Server) error {
+		if interval <= 0 || lowWater < 0 || lowWater >= 1 {
+			return errors.New("invalid cache janitor settings")
+		}
+
+		s.janitor = &cacheJanitor{interval: interval, lowWater: lowWater}
+		return nil
+	}
+}
+
+// CacheTrimmer trims caches kept outside of the server down to lowWater of
+// their budget
+type CacheTrimmer func(lowWater float64)
+
+// WithCacheTrimmer trims the caches of other services along with the server
+// caches, from TrimCaches and the cache janitor
+func WithCacheTrimmer(trim CacheTrimmer) Option {
+	return func(s *Server) error {
+		s.trimmers = append(s.trimmers, trim)
+		return nil
+	}
+}
+
+// cacheJanitor periodically trims the caches of a server. stop and done are
+// nil when it isn't running
+type cacheJanitor struct {
+	interval time.Duration
+	lowWater float64
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// start runs trim every interval until stop is called, it's a no-op when
+// already running
+func (j *cacheJanitor) start(trim func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stop != nil {
+		return
+	}
+	j.stop = make(chan struct{})
+	j.done = make(chan struct{})
+
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				trim()
+			case <-stop:
+				return
+			}
+		}
+	}(j.stop, j.done)
+}
+
+// stopAndWait stops the janitor and waits for it to exit, it's a no-op when
+// not running
+func (j *cacheJanitor) stopAndWait() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stop == nil {
+		return
+	}
+	close(j.stop)
+	<-j.done
+	j.stop, j.done = nil, nil
+}
+
+// TrimCaches evicts the least recently used entries of the in-memory caches,
+// including those of the trimmers set WithCacheTrimmer, down to the low-water
+// mark of the cache janitor, or half of their budget without janitor. It's
+// meant to be called on memory warnings from the OS
+func (s *Server) TrimCaches() {
+	lowWater := defaultCacheLowWater
+	if s.janitor != nil {
+		lowWater = s.janitor.lowWater
+	}
+
+	s.variants.Trim(lowWater)
+	s.stickers.Trim(lowWater)
+	s.ipfs.Trim(lowWater)
+	if s.images != nil {
+		s.images.Trim(lowWater)
+	}
+	for _, trim := range s.trimmers {
+		trim(lowWater)
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTrimCaches(t *testing.T) {
+	_, err := NewServer(nil, zap.NewNop(), WithCacheJanitor(0, 0.5))
+	require.Error(t, err)
+	_, err = NewServer(nil, zap.NewNop(), WithCacheJanitor(time.Minute, 1))
+	require.Error(t, err)
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	s.stickers.SetMaxBytes(100)
+	for _, hash := range []string{"a", "b", "c", "d"} {
+		s.stickers.Add(hash, "", make([]byte, 25))
+	}
+	require.Equal(t, int64(100), s.stickers.Size())
+
+	s.TrimCaches()
+	require.Equal(t, int64(50), s.stickers.Size())
+	// The least recently used entries are evicted
+	_, ok := s.stickers.Get("a", "")
+	require.False(t, ok)
+	_, ok = s.stickers.Get("d", "")
+	require.True(t, ok)
+}
+
+func TestCacheJanitor(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop(), WithCacheJanitor(10*time.Millisecond, 0.25))
+	require.NoError(t, err)
+	s.ipfs.SetMaxBytes(100)
+
+	require.NoError(t, s.Start())
+	waitListening(t, s)
+
+	s.janitor.mu.Lock()
+	done := s.janitor.done
+	s.janitor.mu.Unlock()
+	require.NotNil(t, done)
+
+	// Starting it again, as on restarts, doesn't start another one
+	s.janitor.start(s.TrimCaches)
+	s.janitor.mu.Lock()
+	require.Equal(t, done, s.janitor.done)
+	s.janitor.mu.Unlock()
+
+	for _, hash := range []string{"a", "b", "c", "d"} {
+		s.ipfs.Add(hash, "", make([]byte, 25))
+	}
+	require.Eventually(t, func() bool { return s.ipfs.Size() == 25 }, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Stop())
+	select {
+	case <-done:
+	default:
+		t.Fatal("janitor still running after Stop")
+	}
+	require.Nil(t, s.janitor.done)
+}
+
+func TestCacheTrimmer(t *testing.T) {
+	var trimmed []float64
+	trim := func(lowWater float64) { trimmed = append(trimmed, lowWater) }
+
+	s, err := NewServer(nil, zap.NewNop(), WithCacheTrimmer(trim))
+	require.NoError(t, err)
+	s.TrimCaches()
+	require.Equal(t, []float64{defaultCacheLowWater}, trimmed)
+
+	s, err = NewServer(nil, zap.NewNop(), WithCacheTrimmer(trim), WithCacheJanitor(time.Minute, 0.25))
+	require.NoError(t, err)
+	s.TrimCaches()
+	require.Equal(t, []float64{defaultCacheLowWater, 0.25}, trimmed)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"sync"
+)
+
+var ErrMediaNotFound = errors.New("media not found")
+var ErrNoDatabase = errors.New("no media database")
+
+// Number of leading payload bytes read to detect its MIME type
+const mimeSniffLength = 512
+
+// Size of the chunks in which payloads are streamed
+const payloadChunkSize = 256 * 1024
+
+// PayloadHead describes a stored payload without loading it
+type PayloadHead struct {
+	// Stored is false when the message has no such payload
+	Stored bool
+	// Size is the size of the payload in bytes
+	Size int64
+	// Head holds up to mimeSniffLength leading bytes of the payload
+	Head []byte
+}
+
+// MediaStore provides the media served by the server. Lookups of unknown
+// messages or contacts fail with ErrMediaNotFound. The lookups give up once
+// ctx, the context of the request being served, is done
+type MediaStore interface {
+	// Image returns the image payload of a message
+	Image(ctx context.Context, messageID string) ([]byte, error)
+	// Audio returns the audio payload of a message
+	Audio(ctx context.Context, messageID string) ([]byte, error)
+	// MediaHead describes the "image" or "audio" payload of a message
+	MediaHead(ctx context.Context, messageID, kind string) (PayloadHead, error)
+	// Sender returns the public key of the sender of a message
+	Sender(ctx context.Context, messageID string) (string, error)
+	// Avatar returns the avatar of a contact for the given image type
+	Avatar(ctx context.Context, publicKey, imageType string) ([]byte, error)
+}
+
+// MediaStreamer is implemented by the stores able to stream audio instead of
+// loading it at once
+type MediaStreamer interface {
+	// AudioStream describes the audio payload of a message and returns a
+	// reader of the whole payload
+	AudioStream(ctx context.Context, messageID string) (PayloadHead, io.Reader, error)
+}
+
+// sqlMediaStore reads the media from the messenger database, which can be
+// replaced with setDB while serving
+type sqlMediaStore struct {
+	mu sync.RWMutex
+	db *sql.DB
+
+	// busy retries the queries failing because the database is busy
+	busy busyRetry
+}
+
+// NewSQLMediaStore returns a MediaStore reading the media from the messenger
+// database, retrying the queries while the database is busy. Without a
+// database, every lookup fails with ErrNoDatabase
+func NewSQLMediaStore(db *sql.DB) MediaStore {
+	if db == nil {
+		return noMediaStore{}
+	}
+	return &sqlMediaStore{db: db, busy: defaultBusyRetry}
+}
+
+// database returns the current database, ErrNoDatabase if there's none
+func (s *sqlMediaStore) database() (*sql.DB, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.db == nil {
+		return nil, ErrNoDatabase
+	}
+	return s.db, nil
+}
+
+func (s *sqlMediaStore) setDB(db *sql.DB) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.db = db
+}
+
+// Ping checks that the current database is reachable
+func (s *sqlMediaStore) Ping(ctx context.Context) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	return db.PingContext(ctx)
+}
+
+func (s *sqlMediaStore) Image(ctx context.Context, messageID string) ([]byte, error) {
+	return s.queryPayload(ctx, `SELECT image_payload FROM user_messages WHERE id = ?`, messageID)
+}
+
+func (s *sqlMediaStore) Audio(ctx context.Context, messageID string) ([]byte, error) {
+	return s.queryPayload(ctx, `SELECT audio_payload FROM user_messages WHERE id = ?`, messageID)
+}
+
+func (s *sqlMediaStore) MediaHead(ctx context.Context, messageID, kind string) (PayloadHead, error) {
+	var column string
+	switch kind {
+	case "image":
+		column = "image_payload"
+	case "audio":
+		column = "audio_payload"
+	default:
+		return PayloadHead{}, ErrUnknownMediaKind
+	}
+
+	db, err := s.database()
+	if err != nil {
+		return PayloadHead{}, err
+	}
+
+	var size sql.NullInt64
+	var head []byte
+	err = s.busy.run(ctx, func() error {
+		return db.QueryRowContext(ctx, `SELECT length(`+column+`), substr(`+column+`, 1, ?) FROM user_messages WHERE id = ?`, mimeSniffLength, messageID).Scan(&size, &head)
+	})
+	if err != nil {
+		return PayloadHead{}, notFound(err)
+	}
+
+	return PayloadHead{Stored: size.Valid, Size: size.Int64, Head: head}, nil
+}
+
+// AudioStream reads the audio in chunks, a payload fitting in one chunk is
+// read with a single query
+func (s *sqlMediaStore) AudioStream(ctx context.Context, messageID string) (PayloadHead, io.Reader, error) {
+	db, err := s.database()
+	if err != nil {
+		return PayloadHead{}, nil, err
+	}
+
+	var size sql.NullInt64
+	var chunk []byte
+	err = s.busy.run(ctx, func() error {
+		return db.QueryRowContext(ctx, `SELECT length(audio_payload), substr(audio_payload, 1, ?) FROM user_messages WHERE id = ?`, payloadChunkSize, messageID).Scan(&size, &chunk)
+	})
+	if err != nil {
+		return PayloadHead{}, nil, notFound(err)
+	}
+
+	head := PayloadHead{Stored: size.Valid, Size: size.Int64, Head: chunk}
+	if len(head.Head) > mimeSniffLength {
+		head.Head = head.Head[:mimeSniffLength]
+	}
+
+	return head, &chunkReader{ctx: ctx, db: db, busy: s.busy, column: "audio_payload", messageID: messageID, size: size.Int64, offset: int64(len(chunk)), chunk: chunk}, nil
+}
+
+func (s *sqlMediaStore) Sender(ctx context.Context, messageID string) (string, error) {
+	db, err := s.database()
+	if err != nil {
+		return "", err
+	}
+
+	var publicKey string
+	err = s.busy.run(ctx, func() error {
+		return db.QueryRowContext(ctx, `SELECT source FROM user_messages WHERE id = ?`, messageID).Scan(&publicKey)
+	})
+	return publicKey, notFound(err)
+}
+
+func (s *sqlMediaStore) Avatar(ctx context.Context, publicKey, imageType string) ([]byte, error) {
+	return s.queryPayload(ctx, `SELECT payload FROM chat_identity_contacts WHERE contact_id = ? AND image_type = ?`, publicKey, imageType)
+}
+
+// queryPayload reads a single blob, retrying while the database is busy
+func (s *sqlMediaStore) queryPayload(ctx context.Context, query string, args ...interface{}) ([]byte, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	err = s.busy.run(ctx, func() error {
+		return db.QueryRowContext(ctx, query, args...).Scan(&payload)
+	})
+	return payload, notFound(err)
+}
+
+// notFound translates a missing row into ErrMediaNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrMediaNotFound
+	}
+	return err
+}
+
+// chunkReader reads a payload chunk by chunk, from the database the
+// payload started to be read from
+type chunkReader struct {
+	ctx       context.Context
+	db        *sql.DB
+	busy      busyRetry
+	column    string
+	messageID string
+	size      int64
+	// offset is the number of bytes read from the database
+	offset int64
+	// chunk holds the bytes read from the database but not by the reader yet
+	chunk []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunk) == 0 {
+		if r.offset >= r.size {
+			return 0, io.EOF
+		}
+
+		err := r.busy.run(r.ctx, func() error {
+			return r.db.QueryRowContext(r.ctx, `SELECT substr(`+r.column+`, ?, ?) FROM user_messages WHERE id = ?`, r.offset+1, payloadChunkSize, r.messageID).Scan(&r.chunk)
+		})
+		if err != nil {
+			return 0, err
+		}
+		if len(r.chunk) == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		r.offset += int64(len(r.chunk))
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+// noMediaStore is the store of a server created without a database
+type noMediaStore struct{}
+
+func (noMediaStore) Image(context.Context, string) ([]byte, error) {
+	return nil, ErrNoDatabase
+}
+
+func (noMediaStore) Audio(context.Context, string) ([]byte, error) {
+	return nil, ErrNoDatabase
+}
+
+func (noMediaStore) MediaHead(context.Context, string, string) (PayloadHead, error) {
+	return PayloadHead{}, ErrNoDatabase
+}
+
+func (noMediaStore) Sender(context.Context, string) (string, error) {
+	return "", ErrNoDatabase
+}
+
+func (noMediaStore) Avatar(context.Context, string, string) ([]byte, error) {
+	return nil, ErrNoDatabase
+}
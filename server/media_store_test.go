@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+// memoryMediaStore serves media from memory
+type memoryMediaStore struct {
+	images  map[string][]byte
+	audio   map[string][]byte
+	senders map[string]string
+	avatars map[string][]byte
+}
+
+func (m *memoryMediaStore) Image(_ context.Context, messageID string) ([]byte, error) {
+	return lookup(m.images, messageID)
+}
+
+func (m *memoryMediaStore) Audio(_ context.Context, messageID string) ([]byte, error) {
+	return lookup(m.audio, messageID)
+}
+
+func (m *memoryMediaStore) MediaHead(_ context.Context, messageID, kind string) (PayloadHead, error) {
+	payloads := m.images
+	if kind == "audio" {
+		payloads = m.audio
+	}
+
+	payload, err := lookup(payloads, messageID)
+	if err != nil {
+		return PayloadHead{}, err
+	}
+
+	head := payload
+	if len(head) > mimeSniffLength {
+		head = head[:mimeSniffLength]
+	}
+	return PayloadHead{Stored: payload != nil, Size: int64(len(payload)), Head: head}, nil
+}
+
+func (m *memoryMediaStore) Sender(_ context.Context, messageID string) (string, error) {
+	sender, ok := m.senders[messageID]
+	if !ok {
+		return "", ErrMediaNotFound
+	}
+	return sender, nil
+}
+
+func (m *memoryMediaStore) Avatar(_ context.Context, publicKey, imageType string) ([]byte, error) {
+	return lookup(m.avatars, publicKey)
+}
+
+func lookup(payloads map[string][]byte, key string) ([]byte, error) {
+	payload, ok := payloads[key]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	return payload, nil
+}
+
+func TestMediaStore(t *testing.T) {
+	image, err := identicon.Generate("image")
+	require.NoError(t, err)
+	avatar, err := identicon.Generate("avatar")
+	require.NoError(t, err)
+
+	store := &memoryMediaStore{
+		images:  map[string][]byte{"1": image},
+		audio:   map[string][]byte{"1": []byte("audio"), "2": {}},
+		senders: map[string]string{"1": testPublicKey},
+		avatars: map[string][]byte{testPublicKey: avatar},
+	}
+
+	s, err := NewServer(nil, zap.NewNop(), WithMediaStore(store))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		path   string
+		status int
+		body   []byte
+	}{
+		{"/messages/images?messageId=1", http.StatusOK, image},
+		{"/messages/images?messageId=2", http.StatusNotFound, nil},
+		{"/messages/audio?messageId=1", http.StatusOK, []byte("audio")},
+		{"/messages/audio?messageId=2", http.StatusUnprocessableEntity, nil},
+		{"/messages/audio?messageId=3", http.StatusNotFound, nil},
+		{"/messages/avatar?messageId=1", http.StatusOK, avatar},
+		{"/messages/avatar?messageId=2", http.StatusNotFound, nil},
+	}
+
+	for _, tc := range testCases {
+		resp, err := http.Get(ts.URL + tc.path)
+		require.NoError(t, err)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, tc.status, resp.StatusCode, tc.path)
+		if tc.status == http.StatusOK {
+			require.Equal(t, tc.body, body, tc.path)
+		}
+	}
+
+	size, mime, err := s.MediaInfo("1", "image")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(image)), size)
+	require.Equal(t, "image/png", mime)
+
+	_, err = NewServer(nil, zap.NewNop(), WithMediaStore(nil))
+	require.Error(t, err)
+}
+
+func TestServerWithoutDatabase(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	paths := []string{
+		"/messages/images?messageId=1",
+		"/messages/audio?messageId=1",
+		"/messages/avatar?publicKey=" + testPublicKey,
+		"/messages/avatar?messageId=1",
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		for _, path := range paths {
+			req, err := http.NewRequest(method, ts.URL+path, nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusInternalServerError, resp.StatusCode, "%s %s", method, path)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, _, err = s.MediaInfo("1", "image")
+	require.Equal(t, ErrNoDatabase, err)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// responseRecorder captures the status code and the size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// metrics holds the media server metrics, each server has its own registry so
+// that several servers can run side by side
+type metrics struct {
+	registry  *prom.Registry
+	requests  *prom.CounterVec
+	bytes     *prom.CounterVec
+	durations *prom.HistogramVec
+}
+
+func newMetrics(s *Server) (*metrics, error) {
+	m := &metrics{
+		registry: prom.NewRegistry(),
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Name: "mediaserver_requests_total",
+			Help: "Number of requests by handler and response status class.",
+		}, []string{"handler", "status"}),
+		bytes: prom.NewCounterVec(prom.CounterOpts{
+			Name: "mediaserver_response_bytes_total",
+			Help: "Number of response body bytes served by handler.",
+		}, []string{"handler"}),
+		durations: prom.NewHistogramVec(prom.HistogramOpts{
+			Name: "mediaserver_request_duration_seconds",
+			Help: "The time it took to serve requests by handler.",
+		}, []string{"handler"}),
+	}
+
+	collectors := []prom.Collector{m.requests, m.bytes, m.durations}
+	for name := range s.CacheStats() {
+		name := name
+		collectors = append(collectors,
+			prom.NewCounterFunc(prom.CounterOpts{
+				Name:        "mediaserver_cache_hits_total",
+				Help:        "Number of cache lookups that were hits.",
+				ConstLabels: prom.Labels{"cache": name},
+			}, func() float64 { return float64(s.CacheStats()[name].Hits) }),
+			prom.NewCounterFunc(prom.CounterOpts{
+				Name:        "mediaserver_cache_misses_total",
+				Help:        "Number of cache lookups that were misses.",
+				ConstLabels: prom.Labels{"cache": name},
+			}, func() float64 { return float64(s.CacheStats()[name].Misses) }),
+		)
+	}
+
+	for _, collector := range collectors {
+		if err := m.registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// instrument records the requests served by h under the handler label name
+func (m *metrics) instrument(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
+
+		h.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.WithLabelValues(name, strconv.Itoa(status/100)+"xx").Inc()
+		m.bytes.WithLabelValues(name).Add(float64(recorder.bytes))
+		m.durations.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMetrics(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	_, err := db.Exec(`INSERT INTO user_messages (id, audio_payload) VALUES (?, ?)`, "1", []byte("audio"))
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop(), WithMetrics())
+	require.NoError(t, err)
+	s.variants.Get("1", "webp")
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	for _, path := range []string{"/messages/audio?messageId=1", "/messages/audio?messageId=1", "/messages/audio?messageId=2"} {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	metrics := string(body)
+	require.Contains(t, metrics, `mediaserver_requests_total{handler="/messages/audio",status="2xx"} 2`)
+	require.Contains(t, metrics, `mediaserver_requests_total{handler="/messages/audio",status="4xx"} 1`)
+	require.Contains(t, metrics, `mediaserver_response_bytes_total{handler="/messages/audio"}`)
+	require.Contains(t, metrics, `mediaserver_request_duration_seconds_count{handler="/messages/audio"} 3`)
+	require.Contains(t, metrics, `mediaserver_cache_misses_total{cache="variants"} 1`)
+
+	require.Panics(t, func() { s.Handle("/metrics", http.NotFoundHandler()) })
+}
+
+func TestExternalCacheStats(t *testing.T) {
+	source := func() map[string]CacheStats {
+		return map[string]CacheStats{"metadata": {Hits: 3, Misses: 1}}
+	}
+
+	s, err := NewServer(nil, zap.NewNop(), WithCacheStats(source), WithMetrics())
+	require.NoError(t, err)
+	require.Equal(t, CacheStats{Hits: 3, Misses: 1}, s.CacheStats()["metadata"])
+	require.Contains(t, s.CacheStats(), "variants")
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `mediaserver_cache_hits_total{cache="metadata"} 3`)
+}
+
+func TestMetricsDisabledByDefault(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// Networks the server can listen on, binding the IPv4 loopback address, the
+// IPv6 one, or both on the same port
+const (
+	NetworkTCP  = "tcp"
+	NetworkTCP4 = "tcp4"
+	NetworkTCP6 = "tcp6"
+)
+
+// Number of free ports tried for both IP versions before giving up
+const dualStackAttempts = 3
+
+var ErrInvalidNetwork = errors.New("invalid network, expected tcp, tcp4 or tcp6")
+
+func validNetwork(network string) bool {
+	return network == NetworkTCP || network == NetworkTCP4 || network == NetworkTCP6
+}
+
+// listenTCP listens on the loopback addresses of network at port, a zero port
+// picking a free one. NetworkTCP listens on both the IPv4 and the IPv6
+// loopback addresses, falling back to IPv4 only when IPv6 isn't available
+func listenTCP(network string, port int) (net.Listener, string, error) {
+	lc := net.ListenConfig{Control: reuseAddr}
+
+	switch network {
+	case NetworkTCP4:
+		listener, err := lc.Listen(context.Background(), NetworkTCP4, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		return listener, NetworkTCP4, err
+	case NetworkTCP6:
+		listener, err := lc.Listen(context.Background(), NetworkTCP6, net.JoinHostPort("::1", strconv.Itoa(port)))
+		return listener, NetworkTCP6, err
+	case NetworkTCP:
+	default:
+		return nil, "", ErrInvalidNetwork
+	}
+
+	var err error
+	for attempt := 0; attempt < dualStackAttempts; attempt++ {
+		var ipv4, ipv6 net.Listener
+		ipv4, _, err = listenTCP(NetworkTCP4, port)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// The IPv6 listener takes the port picked for IPv4
+		ipv6, _, err = listenTCP(NetworkTCP6, ipv4.Addr().(*net.TCPAddr).Port)
+		if err == nil {
+			return newDualStackListener(ipv4, ipv6), NetworkTCP, nil
+		}
+		if port != 0 || !errors.Is(err, syscall.EADDRINUSE) {
+			return ipv4, NetworkTCP4, nil
+		}
+
+		// The free IPv4 port is taken on IPv6, try another one
+		ipv4.Close()
+	}
+
+	return nil, "", err
+}
+
+// listenPorts listens on the first free port among the preferred one, the
+// ports of the configured range, and finally any free port
+func (s *Server) listenPorts(preferred int) (net.Listener, string, error) {
+	var ports []int
+	if preferred != 0 {
+		ports = append(ports, preferred)
+	}
+	for port := s.portMin; port != 0 && port <= s.portMax; port++ {
+		if port != preferred {
+			ports = append(ports, port)
+		}
+	}
+
+	for _, port := range ports {
+		listener, network, err := listenTCP(s.Network, port)
+		if err == nil {
+			return listener, network, nil
+		}
+		s.logger.Debug("failed to listen on port, trying the next one", zap.Int("port", port), zap.Error(err))
+	}
+
+	return listenTCP(s.Network, 0)
+}
+
+// dualStackListener accepts the connections of several listeners, its
+// address being the one of the first listener
+type dualStackListener struct {
+	listeners []net.Listener
+	accepted  chan acceptResult
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newDualStackListener(listeners ...net.Listener) *dualStackListener {
+	l := &dualStackListener{
+		listeners: listeners,
+		accepted:  make(chan acceptResult),
+		closed:    make(chan struct{}),
+	}
+	for _, listener := range listeners {
+		go l.accept(listener)
+	}
+	return l
+}
+
+func (l *dualStackListener) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		select {
+		case l.accepted <- acceptResult{conn, err}:
+		case <-l.closed:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Temporary() {
+				return
+			}
+		}
+	}
+}
+
+func (l *dualStackListener) Accept() (net.Conn, error) {
+	select {
+	case result := <-l.accepted:
+		return result.conn, result.err
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *dualStackListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		for _, listener := range l.listeners {
+			if closeErr := listener.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
+
+func (l *dualStackListener) Addr() net.Addr {
+	return l.listeners[0].Addr()
+}
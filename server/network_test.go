@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/eventbus"
+	"github.com/status-im/status-go/signal"
+)
+
+func TestNetworkTCP4(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, NetworkTCP4, s.Network)
+
+	require.NoError(t, s.Start())
+
+	addr := waitListening(t, s).(*net.TCPAddr)
+	require.Equal(t, "127.0.0.1", addr.IP.String())
+	require.Equal(t, NetworkTCP4, s.ListenNetwork())
+	require.NoError(t, s.Stop())
+}
+
+func TestNetworkDualStack(t *testing.T) {
+	ipv6, err := net.Listen(NetworkTCP6, "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 isn't available:", err)
+	}
+	require.NoError(t, ipv6.Close())
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	s.Network = NetworkTCP
+
+	require.NoError(t, s.Start())
+
+	addr := waitListening(t, s).(*net.TCPAddr)
+	require.Equal(t, NetworkTCP, s.ListenNetwork())
+
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		conn, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(addr.Port)))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	}
+	require.NoError(t, s.Stop())
+}
+
+func TestInvalidNetwork(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	s.Network = "udp"
+
+	require.Equal(t, ErrInvalidNetwork, s.Start())
+	require.False(t, s.Running())
+}
+
+// freePortPair returns a port listened on and the next port, which is free
+func freePortPair(t *testing.T) (net.Listener, int) {
+	for i := 0; i < 10; i++ {
+		busy, err := net.Listen(NetworkTCP4, "127.0.0.1:0")
+		require.NoError(t, err)
+
+		next := busy.Addr().(*net.TCPAddr).Port + 1
+		free, err := net.Listen(NetworkTCP4, net.JoinHostPort("127.0.0.1", strconv.Itoa(next)))
+		if err == nil {
+			require.NoError(t, free.Close())
+			return busy, next
+		}
+		require.NoError(t, busy.Close())
+	}
+	t.Fatal("no pair of free ports")
+	return nil, 0
+}
+
+func TestPortRange(t *testing.T) {
+	busy, next := freePortPair(t)
+	defer busy.Close()
+	min := busy.Addr().(*net.TCPAddr).Port
+
+	s, err := NewServer(nil, zap.NewNop(), WithPortRange(min, next))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Start())
+	addr := waitListening(t, s).(*net.TCPAddr)
+	require.Equal(t, next, addr.Port)
+	require.Equal(t, next, s.Port)
+	require.NoError(t, s.Stop())
+
+	// Any free port is used once the whole range is busy
+	s, err = NewServer(nil, zap.NewNop(), WithPortRange(min, min))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Start())
+	addr = waitListening(t, s).(*net.TCPAddr)
+	require.NotEqual(t, min, addr.Port)
+	require.NoError(t, s.Stop())
+
+	for _, r := range [][2]int{{0, 10}, {10, 5}, {60000, 70000}} {
+		_, err = NewServer(nil, zap.NewNop(), WithPortRange(r[0], r[1]))
+		require.Error(t, err)
+	}
+}
+
+func TestPortChanged(t *testing.T) {
+	var mu sync.Mutex
+	var signals []signal.MediaServerPortChangedSignal
+	signal.SetMobileSignalHandler(func(data []byte) {
+		var envelope struct {
+			Type  string                              `json:"type"`
+			Event signal.MediaServerPortChangedSignal `json:"event"`
+		}
+		require.NoError(t, json.Unmarshal(data, &envelope))
+		if envelope.Type == signal.EventMediaServerPortChanged {
+			mu.Lock()
+			signals = append(signals, envelope.Event)
+			mu.Unlock()
+		}
+	})
+	defer signal.SetMobileSignalHandler(nil)
+
+	bus := eventbus.New()
+	events := bus.Subscribe(eventbus.MediaPortChanged)
+
+	s, err := NewServer(nil, zap.NewNop(), WithEventBus(bus))
+	require.NoError(t, err)
+
+	// Binding the first port isn't a change
+	require.NoError(t, s.Start())
+	port := waitListening(t, s).(*net.TCPAddr).Port
+	require.NoError(t, s.Stop())
+
+	// Restarting on the same port isn't either
+	require.NoError(t, s.Start())
+	waitListening(t, s)
+	require.Equal(t, port, s.Port)
+	require.NoError(t, s.Stop())
+	require.Empty(t, events)
+
+	busy, err := net.Listen(NetworkTCP4, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	require.NoError(t, err)
+	defer busy.Close()
+
+	require.NoError(t, s.Start())
+	newPort := waitListening(t, s).(*net.TCPAddr).Port
+	require.NotEqual(t, port, newPort)
+
+	expected := eventbus.MediaPortChangedPayload{Port: newPort, BaseURL: s.BaseURL()}
+	select {
+	case event := <-events:
+		require.Equal(t, expected, event.Payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("port change wasn't published")
+	}
+	require.NoError(t, s.Stop())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []signal.MediaServerPortChangedSignal{{Port: newPort, BaseURL: expected.BaseURL}}, signals)
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+var ErrNotRunning = errors.New("server isn't running")
+
+// pausableListener holds back the accepted connections while paused, so that
+// the port stays bound without serving new clients
+type pausableListener struct {
+	net.Listener
+	network string
+
+	mu      sync.Mutex
+	resumed chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newPausableListener(listener net.Listener, network string) *pausableListener {
+	return &pausableListener{Listener: listener, network: network, closed: make(chan struct{})}
+}
+
+func (l *pausableListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	resumed := l.resumed
+	l.mu.Unlock()
+
+	if resumed == nil {
+		return conn, nil
+	}
+
+	// The connection is served once resumed
+	select {
+	case <-resumed:
+		return conn, nil
+	case <-l.closed:
+		conn.Close()
+		return nil, errors.New("listener closed while paused")
+	}
+}
+
+func (l *pausableListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+func (l *pausableListener) pause() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.resumed == nil {
+		l.resumed = make(chan struct{})
+	}
+}
+
+func (l *pausableListener) resume() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.resumed != nil {
+		close(l.resumed)
+		l.resumed = nil
+	}
+}
+
+func (l *pausableListener) paused() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.resumed != nil
+}
+
+// Pause stops serving new connections while keeping the listener, hence the
+// port, so that Resume serves again on the same address. Requests in flight
+// are completed, idle connections are closed, and the connections made while
+// paused wait to be served until Resume
+func (s *Server) Pause() error {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	if s.listener == nil {
+		return ErrNotRunning
+	}
+
+	s.listener.pause()
+	s.server.SetKeepAlivesEnabled(false)
+	return nil
+}
+
+// Resume serves the connections again after Pause
+func (s *Server) Resume() error {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	if s.listener == nil {
+		return ErrNotRunning
+	}
+
+	s.server.SetKeepAlivesEnabled(true)
+	s.listener.resume()
+	return nil
+}
+
+// Paused tells whether the server is paused
+func (s *Server) Paused() bool {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+	return s.listener != nil && s.listener.paused()
+}
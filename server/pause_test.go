@@ -0,0 +1,78 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPauseResume(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	require.Equal(t, ErrNotRunning, s.Pause())
+	require.Equal(t, ErrNotRunning, s.Resume())
+
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		MinVersion: tls.VersionTLS12,
+	}}}
+
+	require.NoError(t, s.Start())
+	addr := waitListening(t, s)
+	url := "https://" + addr.String() + "/messages/identicons?publicKey=" + testPublicKey
+
+	get := func(client *http.Client) error {
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return nil
+	}
+	require.NoError(t, get(client))
+
+	require.NoError(t, s.Pause())
+	require.True(t, s.Paused())
+	require.True(t, s.Running())
+
+	// The port stays bound
+	_, err = net.Listen("tcp", addr.String())
+	require.Error(t, err)
+
+	// New connections aren't served while paused
+	impatient := &http.Client{Transport: client.Transport.(*http.Transport).Clone(), Timeout: 200 * time.Millisecond}
+	require.Error(t, get(impatient))
+
+	// but they are once resumed
+	done := make(chan error, 1)
+	go func() { done <- get(client) }()
+	select {
+	case <-done:
+		t.Fatal("request served while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, s.Resume())
+	require.False(t, s.Paused())
+	require.NoError(t, <-done)
+	require.NoError(t, get(client))
+	require.Equal(t, addr, s.ListenAddr())
+
+	// The impatient dial completes in the background once resumed, leaving an
+	// idle connection the server would otherwise wait for on Stop
+	impatient.CloseIdleConnections()
+	require.NoError(t, s.Stop())
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Cache-Control of the placeholder, which is only served until the image is
+// received
+const placeholderCacheControl = "max-age=60"
+
+// placeholderImage is a 16x16 light gray PNG served in place of missing
+// images when requested with fallback=placeholder
+var placeholderImage = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x02, 0x00, 0x00, 0x00, 0x90, 0x91, 0x68, 0x36, 0x00, 0x00, 0x00,
+	0x14, 0x49, 0x44, 0x41, 0x54, 0x78, 0xda, 0x63, 0xb8, 0x41, 0x22, 0x60,
+	0x18, 0xd5, 0x30, 0xaa, 0x61, 0xf8, 0x6a, 0x00, 0x00, 0x7d, 0x30, 0x88,
+	0x1f, 0x3b, 0xf2, 0xe4, 0x97, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e,
+	0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+// wantsPlaceholder tells whether the request asks for the placeholder instead
+// of an error when the image is missing
+func wantsPlaceholder(r *http.Request) bool {
+	return r.URL.Query().Get("fallback") == "placeholder"
+}
+
+// servePlaceholder responds with the placeholder image and 203, so that clients
+// can tell it from the actual image
+func servePlaceholder(w http.ResponseWriter, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(placeholderImage)))
+	w.Header().Set("Cache-Control", placeholderCacheControl)
+	w.WriteHeader(http.StatusNonAuthoritativeInfo)
+
+	_, err := w.Write(placeholderImage)
+	if err != nil {
+		logger.Error("failed to write placeholder image", zap.Error(err))
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func TestPlaceholderImage(t *testing.T) {
+	_, err := png.Decode(bytes.NewReader(placeholderImage))
+	require.NoError(t, err)
+
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?), (?, ?)`, "1", image, "2", []byte{})
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		query  string
+		status int
+		body   []byte
+	}{
+		{"messageId=1", http.StatusOK, image},
+		{"messageId=1&fallback=placeholder", http.StatusOK, image},
+		{"messageId=2", http.StatusNotFound, nil},
+		{"messageId=2&fallback=placeholder", http.StatusNonAuthoritativeInfo, placeholderImage},
+		{"messageId=3", http.StatusNotFound, nil},
+		{"messageId=3&fallback=placeholder", http.StatusNonAuthoritativeInfo, placeholderImage},
+	}
+
+	for _, tc := range testCases {
+		resp, err := http.Get(ts.URL + "/messages/images?" + tc.query)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, tc.status, resp.StatusCode, tc.query)
+		if tc.body != nil {
+			require.Equal(t, tc.body, body, tc.query)
+			require.Equal(t, "image/png", resp.Header.Get("Content-Type"), tc.query)
+		}
+		if tc.status == http.StatusNonAuthoritativeInfo {
+			require.Equal(t, placeholderCacheControl, resp.Header.Get("Cache-Control"))
+		}
+
+		resp, err = http.Head(ts.URL + "/messages/images?" + tc.query)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, tc.status, resp.StatusCode, tc.query)
+	}
+}
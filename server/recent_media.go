@@ -0,0 +1,63 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// MediaAccess records a media item served by the server
+type MediaAccess struct {
+	MessageID  string    `json:"messageId"`
+	Kind       string    `json:"kind"`
+	AccessedAt time.Time `json:"accessedAt"`
+}
+
+// recentMedia is a fixed size ring buffer of the last media accesses. A nil
+// recentMedia records nothing
+type recentMedia struct {
+	mu      sync.Mutex
+	entries []MediaAccess
+	next    int
+	full    bool
+}
+
+func newRecentMedia(size int) *recentMedia {
+	return &recentMedia{entries: make([]MediaAccess, size)}
+}
+
+func (r *recentMedia) Record(messageID, kind string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = MediaAccess{MessageID: messageID, Kind: kind, AccessedAt: time.Now()}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// List returns the recorded accesses, most recent first
+func (r *recentMedia) List() []MediaAccess {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+
+	result := make([]MediaAccess, 0, count)
+	for i := 1; i <= count; i++ {
+		result = append(result, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+
+	return result
+}
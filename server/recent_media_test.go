@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRecentMedia(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	for i := 1; i <= 4; i++ {
+		_, err := db.Exec(`INSERT INTO user_messages (id, audio_payload) VALUES (?, ?)`, fmt.Sprint(i), []byte("audio"))
+		require.NoError(t, err)
+	}
+
+	s, err := NewServer(db, zap.NewNop(), WithRecentMedia(3))
+	require.NoError(t, err)
+	require.Empty(t, s.RecentMedia())
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		resp, err := http.Get(ts.URL + "/messages/audio?messageId=" + id)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	recent := s.RecentMedia()
+	require.Len(t, recent, 3)
+	for i, id := range []string{"4", "3", "2"} {
+		require.Equal(t, id, recent[i].MessageID)
+		require.Equal(t, "audio", recent[i].Kind)
+	}
+	require.False(t, recent[0].AccessedAt.Before(recent[1].AccessedAt))
+
+	_, err = NewServer(db, zap.NewNop(), WithRecentMedia(0))
+	require.Error(t, err)
+
+	s, err = NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+	require.Nil(t, s.RecentMedia())
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Header carrying the ID of a request, set by the client or generated
+const requestIDHeader = "X-Request-ID"
+
+// Maximum length of a request ID set by the client, longer IDs are replaced
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// withRequestID assigns an ID to every request, the one of the X-Request-ID
+// header if valid, and echoes it in the response
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// validRequestID tells whether id is short printable ASCII, so that it can be
+// logged and echoed as is
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// requestID returns the ID assigned to the request of ctx, if any
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger returns logger annotated with the ID of the request r
+func requestLogger(r *http.Request, logger *zap.Logger) *zap.Logger {
+	id := requestID(r.Context())
+	if id == "" {
+		return logger
+	}
+	return logger.With(zap.String("requestID", id))
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes by the handlers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRequestID(t *testing.T) {
+	var logs syncBuffer
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&logs), zap.DebugLevel))
+
+	s, err := NewServer(nil, logger)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	get := func(id string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/messages/identicons", nil)
+		require.NoError(t, err)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		return resp
+	}
+
+	resp := get("client-id")
+	require.Equal(t, "client-id", resp.Header.Get(requestIDHeader))
+	require.Contains(t, logs.String(), `"requestID":"client-id"`)
+
+	for _, id := range []string{"", strings.Repeat("a", maxRequestIDLength+1), "with space"} {
+		resp = get(id)
+		generated := resp.Header.Get(requestIDHeader)
+		require.Len(t, generated, 16, id)
+		require.Contains(t, logs.String(), `"requestID":"`+generated+`"`)
+	}
+}
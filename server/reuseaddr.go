@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"syscall"
+)
+
+// reuseAddr sets SO_REUSEADDR on the listening socket, so that the port of a
+// stopped server can be bound again while its connections are in TIME_WAIT
+func reuseAddr(network, address string, conn syscall.RawConn) error {
+	var sockErr error
+	err := conn.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,11 @@
+package server
+
+import (
+	"syscall"
+)
+
+// reuseAddr is a no-op on Windows, where SO_REUSEADDR lets other processes
+// bind a port in use, and ports in TIME_WAIT can be bound again anyway
+func reuseAddr(network, address string, conn syscall.RawConn) error {
+	return nil
+}
@@ -1,238 +1,1601 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/status-im/status-go/eth-node/crypto"
+	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/eventbus"
+	userimages "github.com/status-im/status-go/images"
+	"github.com/status-im/status-go/protocol/audio"
 	"github.com/status-im/status-go/protocol/identity/identicon"
 	"github.com/status-im/status-go/protocol/images"
+	"github.com/status-im/status-go/signal"
 )
 
 var globalCertificate *tls.Certificate = nil
 var globalPem string
 
+// globalCertificateLock guards the global certificate, generated on first use
+var globalCertificateLock sync.RWMutex
+
 func generateTLSCert() error {
+	globalCertificateLock.RLock()
+	generated := globalCertificate != nil
+	globalCertificateLock.RUnlock()
+	if generated {
+		return nil
+	}
+
+	globalCertificateLock.Lock()
+	defer globalCertificateLock.Unlock()
+
+	// Another caller might have generated it in the meantime
 	if globalCertificate != nil {
 		return nil
 	}
 
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	cert, certPem, err := newTLSCert()
 	if err != nil {
 		return err
 	}
 
+	globalCertificate, globalPem = cert, certPem
+	return nil
+}
+
+// ResetGlobalCertificate discards the global certificate, a new one being
+// generated the next time it's needed. Servers already created keep serving
+// the certificate they got
+func ResetGlobalCertificate() {
+	globalCertificateLock.Lock()
+	defer globalCertificateLock.Unlock()
+
+	globalCertificate, globalPem = nil, ""
+}
+
+func newTLSCert() (*tls.Certificate, string, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+
 	notBefore := time.Now()
 	notAfter := notBefore.Add(365 * 24 * time.Hour)
 
 	cert, err := GenerateX509Cert(notBefore, notAfter)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	certPem, keyPem, err := GenerateX509PEMs(cert, priv)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	finalCert, err := tls.X509KeyPair(certPem, keyPem)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	globalCertificate = &finalCert
-	globalPem = string(certPem)
-	return nil
+	return &finalCert, string(certPem), nil
+}
+
+// globalTLSCert returns the global certificate, once generated
+func globalTLSCert() (*tls.Certificate, string) {
+	globalCertificateLock.RLock()
+	defer globalCertificateLock.RUnlock()
+	return globalCertificate, globalPem
 }
 
 func PublicTLSCert() (string, error) {
+	if isInsecureMode() {
+		return "", ErrInsecure
+	}
+
 	err := generateTLSCert()
 
 	if err != nil {
 		return "", err
 	}
 
-	return globalPem, nil
+	_, certPem := globalTLSCert()
+	return certPem, nil
+}
+
+// contextReader stops yielding data as soon as ctx is done, so that copying
+// a payload to a client that went away is aborted promptly.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// writePayload streams payload to w until it's fully written or the client
+// disconnects.
+func writePayload(w http.ResponseWriter, r *http.Request, payload []byte) error {
+	return copyPayload(w, r, bytes.NewReader(payload))
+}
+
+// copyPayload copies payload to w until it's fully read or the client
+// disconnects.
+func copyPayload(w http.ResponseWriter, r *http.Request, payload io.Reader) error {
+	_, err := io.Copy(w, &contextReader{ctx: r.Context(), r: payload})
+	return err
+}
+
+// Number of times a query failing because the database is busy is retried
+// before giving up, and the base delay between attempts
+const dbBusyRetries = 3
+const dbBusyBackoff = 50 * time.Millisecond
+
+func isDBBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
+
+// busyRetry is how many times a query failing because the database is busy
+// is retried, the delay between attempts growing by backoff every time
+type busyRetry struct {
+	retries int
+	backoff time.Duration
+}
+
+var defaultBusyRetry = busyRetry{retries: dbBusyRetries, backoff: dbBusyBackoff}
+
+// run runs query until it doesn't fail because the database is busy, until
+// the retries are exhausted or until ctx is done
+func (b busyRetry) run(ctx context.Context, query func() error) error {
+	for i := 0; ; i++ {
+		err := query()
+		if !isDBBusy(err) || i >= b.retries {
+			return err
+		}
+
+		timer := time.NewTimer(b.backoff * time.Duration(i+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Default maximum size of a media payload served, larger payloads are most
+// likely corrupt
+const defaultMaxPayloadBytes = 25 * 1024 * 1024
+
+// Default HTTP timeouts, so that slow or stuck clients don't hold on to
+// connections. Audio is streamed, so the write timeout leaves time for a slow
+// media player to read the largest payload allowed
+const defaultReadTimeout = 10 * time.Second
+const defaultWriteTimeout = 2 * time.Minute
+const defaultIdleTimeout = 60 * time.Second
+
+// payloadTooLarge responds with 413 when a payload of size bytes exceeds
+// maxBytes, if positive
+func payloadTooLarge(w http.ResponseWriter, logger *zap.Logger, kind string, size int64, maxBytes int64) bool {
+	if maxBytes <= 0 || size <= maxBytes {
+		return false
+	}
+
+	logger.Error("payload too large", zap.String("kind", kind), zap.Int64("size", size), zap.Int64("max", maxBytes))
+	http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	return true
+}
+
+// queryErrorStatus maps a failed media lookup to the response status code
+func queryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrMediaNotFound):
+		return http.StatusNotFound
+	case isDBBusy(err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 type imageHandler struct {
-	db     *sql.DB
-	logger *zap.Logger
+	store        MediaStore
+	logger       *zap.Logger
+	events       *eventbus.Bus
+	recent       *recentMedia
+	cacheControl string
+	maxBytes     int64
+	// webp transcodes the images to WebP when set, into variants
+	webp     ImageEncoder
+	variants *variantCache
+	// images caches the image payloads read from the store when set
+	images *variantCache
+	// stickers serves the content addressed images requested by hash, nil
+	// when no sticker fetcher is set
+	stickers *stickerHandler
 }
 
 type audioHandler struct {
-	db     *sql.DB
-	logger *zap.Logger
+	store        MediaStore
+	logger       *zap.Logger
+	events       *eventbus.Bus
+	recent       *recentMedia
+	cacheControl string
+	maxBytes     int64
 }
 
 type identiconHandler struct {
-	logger *zap.Logger
+	logger        *zap.Logger
+	events        *eventbus.Bus
+	defaultAvatar []byte
+	cacheControl  string
 }
 
 func (s *identiconHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
+
 	pks, ok := r.URL.Query()["publicKey"]
 	if !ok || len(pks) == 0 {
-		s.logger.Error("no publicKey")
+		if len(s.defaultAvatar) != 0 {
+			s.serveDefaultAvatar(w, logger)
+			return
+		}
+		logger.Error("no publicKey")
+		http.Error(w, "no publicKey", http.StatusBadRequest)
 		return
 	}
 	pk := pks[0]
+	if !isPublicKey(pk) {
+		logger.Error("invalid publicKey", zap.String("publicKey", pk))
+		http.Error(w, "invalid publicKey", http.StatusBadRequest)
+		return
+	}
+
 	image, err := identicon.Generate(pk)
 	if err != nil {
-		s.logger.Error("could not generate identicon")
+		// The identicon would be cached for good, so nothing is served
+		logger.Error("could not generate identicon", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Cache-Control", "max-age:290304000, public")
-	w.Header().Set("Expires", time.Now().AddDate(60, 0, 0).Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", s.cacheControl)
+	// The default policy relies on Expires as its max-age is malformed
+	if s.cacheControl == defaultIdenticonCacheControl {
+		w.Header().Set("Expires", time.Now().AddDate(60, 0, 0).Format(http.TimeFormat))
+	}
 
 	_, err = w.Write(image)
 	if err != nil {
-		s.logger.Error("failed to write image", zap.Error(err))
+		logger.Error("failed to write image", zap.Error(err))
+		return
+	}
+
+	s.events.Publish(eventbus.MediaServed, eventbus.MediaServedPayload{Kind: "identicon", ID: pk})
+}
+
+// isPublicKey tells whether pk is a hex encoded public key
+func isPublicKey(pk string) bool {
+	bytes, err := types.DecodeHex(pk)
+	if err != nil {
+		return false
+	}
+	_, err = crypto.UnmarshalPubkey(bytes)
+	return err == nil
+}
+
+type avatarHandler struct {
+	store        MediaStore
+	logger       *zap.Logger
+	events       *eventbus.Bus
+	cacheControl string
+	maxBytes     int64
+	// identiconCacheControl is the policy of the identicons route, which
+	// applies to the identicon fallback when it keeps them out of the caches
+	identiconCacheControl string
+}
+
+// ServeHTTP serves the avatar stored for the contact identified by publicKey,
+// or by the sender of messageId, falling back to its identicon
+func (s *avatarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
+
+	query := r.URL.Query()
+
+	publicKey := query.Get("publicKey")
+	if publicKey == "" && query.Get("messageId") != "" {
+		var err error
+		publicKey, err = s.store.Sender(r.Context(), query.Get("messageId"))
+		if err != nil {
+			logger.Error("failed to find message sender", zap.Error(err))
+			status := queryErrorStatus(err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+	}
+	if publicKey == "" {
+		logger.Error("no publicKey")
+		http.Error(w, "no publicKey", http.StatusBadRequest)
+		return
+	}
+	if !isPublicKey(publicKey) {
+		logger.Error("invalid publicKey", zap.String("publicKey", publicKey))
+		http.Error(w, "invalid publicKey", http.StatusBadRequest)
+		return
+	}
+
+	imageType := query.Get("imageType")
+	if imageType == "" {
+		imageType = userimages.SmallDimName
+	}
+
+	avatar, err := s.store.Avatar(r.Context(), publicKey, imageType)
+	if err != nil && !errors.Is(err, ErrMediaNotFound) {
+		logger.Error("failed to find avatar", zap.Error(err))
+		status := queryErrorStatus(err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	if payloadTooLarge(w, logger, "avatar", int64(len(avatar)), s.maxBytes) {
+		return
+	}
+
+	if len(avatar) != 0 {
+		mime, err := images.ImageMime(avatar)
+		if err != nil {
+			logger.Error("failed to get avatar mime", zap.Error(err))
+		}
+
+		// Contacts can change their avatar at any time
+		w.Header().Set("Content-Type", mime)
+		w.Header().Set("Cache-Control", s.cacheControl)
+	} else {
+		avatar, err = identicon.Generate(publicKey)
+		if err != nil {
+			logger.Error("could not generate identicon", zap.Error(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// The identicon never changes, but the contact might publish an avatar
+		// later so it has to be revalidated, unless identicons aren't cached
+		cacheControl := "no-cache"
+		if s.identiconCacheControl == privateIdenticonCacheControl {
+			cacheControl = privateIdenticonCacheControl
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	err = writePayload(w, r, avatar)
+	if errors.Is(err, context.Canceled) {
+		logger.Debug("client disconnected while writing avatar")
+		return
+	}
+	if err != nil {
+		logger.Error("failed to write avatar", zap.Error(err))
+		return
+	}
+
+	s.events.Publish(eventbus.MediaServed, eventbus.MediaServedPayload{Kind: "avatar", ID: publicKey})
+}
+
+func (s *identiconHandler) serveDefaultAvatar(w http.ResponseWriter, logger *zap.Logger) {
+	mime, err := images.ImageMime(s.defaultAvatar)
+	if err != nil {
+		logger.Error("failed to get default avatar mime", zap.Error(err))
+	}
+
+	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Cache-Control", "no-store")
+
+	_, err = w.Write(s.defaultAvatar)
+	if err != nil {
+		logger.Error("failed to write default avatar", zap.Error(err))
 	}
 }
 
 func (s *imageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
 
-	messageIDs, ok := r.URL.Query()["messageId"]
-	if !ok || len(messageIDs) == 0 {
-		s.logger.Error("no messageID")
+	query := r.URL.Query()
+	messageID, hash := query.Get("messageId"), query.Get("hash")
+	if (messageID == "") == (hash == "") {
+		logger.Error("expected either a messageID or a hash")
+		http.Error(w, "expected either messageId or hash", http.StatusBadRequest)
+		return
+	}
+
+	// Content addressed images never change, they're served like stickers
+	if hash != "" {
+		if s.stickers == nil {
+			logger.Error("no content addressed store", zap.String("hash", hash))
+			http.Error(w, "no image", http.StatusNotFound)
+			return
+		}
+		s.stickers.serve(w, r, logger, hash)
+		return
+	}
+
+	if r.Method == http.MethodHead {
+		s.serveHead(w, r, logger, messageID)
+		return
+	}
+
+	image, err := s.image(r.Context(), messageID)
+	if errors.Is(err, ErrMediaNotFound) || (err == nil && len(image) == 0) {
+		writeMissingImage(w, r, logger, messageID)
 		return
 	}
-	messageID := messageIDs[0]
-	var image []byte
-	err := s.db.QueryRow(`SELECT image_payload FROM user_messages WHERE id = ?`, messageID).Scan(&image)
 	if err != nil {
-		s.logger.Error("failed to find image", zap.Error(err))
+		logger.Error("failed to find image", zap.Error(err))
+		status := queryErrorStatus(err)
+		http.Error(w, http.StatusText(status), status)
 		return
 	}
-	if len(image) == 0 {
-		s.logger.Error("empty image")
+	if payloadTooLarge(w, logger, "image", int64(len(image)), s.maxBytes) {
 		return
 	}
 	mime, err := images.ImageMime(image)
 	if err != nil {
-		s.logger.Error("failed to get mime", zap.Error(err))
+		logger.Error("failed to get mime", zap.Error(err))
+	}
+
+	if s.webp != nil {
+		// The response depends on the formats accepted by the client
+		w.Header().Add("Vary", "Accept")
+		if s.transcodesToWebP(r, mime) {
+			variant, err := s.webpVariant(messageID, image)
+			if err != nil {
+				logger.Error("failed to transcode image to webp", zap.String("messageID", messageID), zap.Error(err))
+			} else {
+				image, mime = variant, "image/webp"
+			}
+		}
 	}
 
 	w.Header().Set("Content-Type", mime)
-	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Cache-Control", s.cacheControl)
+	setContentDisposition(w, r, messageID, mime)
 
-	_, err = w.Write(image)
+	err = writePayload(w, r, image)
+	if errors.Is(err, context.Canceled) {
+		logger.Debug("client disconnected while writing image")
+		return
+	}
+	if err != nil {
+		logger.Error("failed to write image", zap.Error(err))
+		return
+	}
+
+	s.recent.Record(messageID, "image")
+	s.events.Publish(eventbus.MediaServed, eventbus.MediaServedPayload{Kind: "image", ID: messageID})
+}
+
+// image returns the image payload of a message, from the image cache if
+// enabled
+func (s *imageHandler) image(ctx context.Context, messageID string) ([]byte, error) {
+	if s.images == nil {
+		return s.store.Image(ctx, messageID)
+	}
+
+	if image, ok := s.images.Get(messageID, ""); ok {
+		return image, nil
+	}
+
+	generation := s.images.Generation()
+	image, err := s.store.Image(ctx, messageID)
+	if err == nil && len(image) != 0 {
+		s.images.AddUnlessCleared(generation, messageID, "", image)
+	}
+	return image, err
+}
+
+// serveHead responds to HEAD requests with the image headers, without
+// loading the image
+func (s *imageHandler) serveHead(w http.ResponseWriter, r *http.Request, logger *zap.Logger, messageID string) {
+	head, err := s.store.MediaHead(r.Context(), messageID, "image")
+	if errors.Is(err, ErrMediaNotFound) || (err == nil && head.Size == 0) {
+		writeMissingImage(w, r, logger, messageID)
+		return
+	}
+	if err != nil {
+		logger.Error("failed to find image", zap.Error(err))
+		status := queryErrorStatus(err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if payloadTooLarge(w, logger, "image", head.Size, s.maxBytes) {
+		return
+	}
+	mime, err := images.ImageMime(head.Head)
 	if err != nil {
-		s.logger.Error("failed to write image", zap.Error(err))
+		logger.Error("failed to get mime", zap.Error(err))
+	}
+
+	contentLength := strconv.FormatInt(head.Size, 10)
+	if s.webp != nil {
+		// Same as GET, the response depends on the formats accepted
+		w.Header().Add("Vary", "Accept")
+		if s.transcodesToWebP(r, mime) {
+			// The length of the WebP variant is only known once transcoded
+			mime, contentLength = "image/webp", ""
+			if variant, ok := s.variants.Get(messageID, webpTransform); ok {
+				contentLength = strconv.Itoa(len(variant))
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", mime)
+	if contentLength != "" {
+		w.Header().Set("Content-Length", contentLength)
+	}
+	w.Header().Set("Cache-Control", s.cacheControl)
+	setContentDisposition(w, r, messageID, mime)
+}
+
+// writeMissingImage responds with 404 when the message has no image, or
+// with the placeholder image if the request asks for it
+func writeMissingImage(w http.ResponseWriter, r *http.Request, logger *zap.Logger, messageID string) {
+	if wantsPlaceholder(r) {
+		logger.Debug("no image, serving placeholder", zap.String("messageID", messageID))
+		servePlaceholder(w, logger)
+		return
 	}
+
+	logger.Error("no image", zap.String("messageID", messageID))
+	http.Error(w, "no image", http.StatusNotFound)
 }
 
 func (s *audioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
 
 	messageIDs, ok := r.URL.Query()["messageId"]
 	if !ok || len(messageIDs) == 0 {
-		s.logger.Error("no messageID")
+		logger.Error("no messageID")
 		return
 	}
 	messageID := messageIDs[0]
-	var audio []byte
-	err := s.db.QueryRow(`SELECT audio_payload FROM user_messages WHERE id = ?`, messageID).Scan(&audio)
+
+	if r.Method == http.MethodHead {
+		s.serveHead(w, r, logger, messageID)
+		return
+	}
+
+	var head PayloadHead
+	var payload io.Reader
+	var err error
+	if streamer, ok := s.store.(MediaStreamer); ok {
+		head, payload, err = streamer.AudioStream(r.Context(), messageID)
+	} else {
+		head, payload, err = s.loadAudio(r.Context(), messageID)
+	}
 	if err != nil {
-		s.logger.Error("failed to find image", zap.Error(err))
+		logger.Error("failed to find audio", zap.Error(err))
+		status := queryErrorStatus(err)
+		http.Error(w, http.StatusText(status), status)
 		return
 	}
-	if len(audio) == 0 {
-		s.logger.Error("empty audio")
+	if head.Size == 0 {
+		writeMissingAudio(w, logger, messageID, head.Stored)
+		return
+	}
+	if payloadTooLarge(w, logger, "audio", head.Size, s.maxBytes) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "audio/aac")
-	w.Header().Set("Cache-Control", "no-store")
+	mime := audioMime(head.Head)
+	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Content-Length", strconv.FormatInt(head.Size, 10))
+	w.Header().Set("Cache-Control", s.cacheControl)
+	setContentDisposition(w, r, messageID, mime)
 
-	_, err = w.Write(audio)
+	// The stream might yield more than its head tells
+	err = copyPayload(w, r, io.LimitReader(payload, head.Size))
+	if errors.Is(err, context.Canceled) {
+		logger.Debug("client disconnected while writing audio")
+		return
+	}
 	if err != nil {
-		s.logger.Error("failed to write audio", zap.Error(err))
+		logger.Error("failed to write audio", zap.Error(err))
+		return
 	}
-}
 
-type Server struct {
-	Port   int
-	run    bool
-	server *http.Server
-	logger *zap.Logger
-	db     *sql.DB
-	cert   *tls.Certificate
+	s.recent.Record(messageID, "audio")
+	s.events.Publish(eventbus.MediaServed, eventbus.MediaServedPayload{Kind: "audio", ID: messageID})
 }
 
-func NewServer(db *sql.DB, logger *zap.Logger) (*Server, error) {
-	err := generateTLSCert()
-
+// serveHead responds to HEAD requests with the audio headers, without
+// loading the audio
+func (s *audioHandler) serveHead(w http.ResponseWriter, r *http.Request, logger *zap.Logger, messageID string) {
+	head, err := s.store.MediaHead(r.Context(), messageID, "audio")
 	if err != nil {
-		return nil, err
+		logger.Error("failed to find audio", zap.Error(err))
+		status := queryErrorStatus(err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if head.Size == 0 {
+		writeMissingAudio(w, logger, messageID, head.Stored)
+		return
+	}
+	if payloadTooLarge(w, logger, "audio", head.Size, s.maxBytes) {
+		return
 	}
 
-	return &Server{db: db, logger: logger, cert: globalCertificate, Port: 0}, nil
+	mime := audioMime(head.Head)
+	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Content-Length", strconv.FormatInt(head.Size, 10))
+	w.Header().Set("Cache-Control", s.cacheControl)
+	setContentDisposition(w, r, messageID, mime)
 }
 
-func (s *Server) listenAndServe() {
-	cfg := &tls.Config{Certificates: []tls.Certificate{*s.cert}, ServerName: "localhost", MinVersion: tls.VersionTLS12}
+// loadAudio loads the whole audio of a message from stores that can't stream
+// it
+func (s *audioHandler) loadAudio(ctx context.Context, messageID string) (PayloadHead, io.Reader, error) {
+	audio, err := s.store.Audio(ctx, messageID)
+	if err != nil {
+		return PayloadHead{}, nil, err
+	}
+
+	if len(audio) == 0 {
+		// Stores may not tell a missing payload from an empty one, the
+		// payload head does
+		head, err := s.store.MediaHead(ctx, messageID, "audio")
+		return head, nil, err
+	}
 
-	// in case of restart, we should use the same port as the first start in order not to break existing links
-	addr := fmt.Sprintf("localhost:%d", s.Port)
+	return PayloadHead{Stored: true, Size: int64(len(audio)), Head: audio}, bytes.NewReader(audio), nil
+}
 
-	listener, err := tls.Listen("tcp", addr, cfg)
+// audioMime returns the MIME type of the audio starting with head, audio
+// messages being AAC unless detected otherwise
+func audioMime(head []byte) string {
+	mime, err := audio.Mime(head)
 	if err != nil {
-		s.logger.Error("failed to start server, retrying", zap.Error(err))
-		s.Port = 0
-		err = s.Start()
-		if err != nil {
-			s.logger.Error("server start failed, giving up", zap.Error(err))
-		}
-		return
+		return "audio/aac"
 	}
+	return mime
+}
 
-	s.Port = listener.Addr().(*net.TCPAddr).Port
-	s.run = true
-	err = s.server.Serve(listener)
-	if err != http.ErrServerClosed {
-		s.logger.Error("server failed unexpectedly, restarting", zap.Error(err))
-		err = s.Start()
-		if err != nil {
-			s.logger.Error("server start failed, giving up", zap.Error(err))
-		}
+// writeMissingAudio responds with 404 when no audio was ever stored for the
+// message, and with 422 when the stored audio is empty, hence corrupt
+func writeMissingAudio(w http.ResponseWriter, logger *zap.Logger, messageID string, stored bool) {
+	if !stored {
+		logger.Debug("no audio", zap.String("messageID", messageID))
+		http.Error(w, "no audio", http.StatusNotFound)
 		return
 	}
 
-	s.run = false
+	logger.Error("empty audio payload", zap.String("messageID", messageID))
+	http.Error(w, "empty audio payload", http.StatusUnprocessableEntity)
 }
 
-func (s *Server) Start() error {
-	handler := http.NewServeMux()
-	handler.Handle("/messages/images", &imageHandler{db: s.db, logger: s.logger})
-	handler.Handle("/messages/audio", &audioHandler{db: s.db, logger: s.logger})
-	handler.Handle("/messages/identicons", &identiconHandler{logger: s.logger})
-	s.server = &http.Server{Handler: handler}
+type Server struct {
+	Port int
+	// Network selects the loopback addresses listened on: NetworkTCP4, the
+	// default, NetworkTCP6, or NetworkTCP for both
+	Network string
+	// Insecure serves plain HTTP instead of HTTPS, for local debugging only
+	Insecure bool
+	// TLSConfigurator, when set, adjusts the TLS config once the server has
+	// set it up, e.g. to restrict the cipher suites or set curve preferences.
+	// Overriding Certificates or GetCertificate is unsupported
+	TLSConfigurator func(*tls.Config)
 
-	go s.listenAndServe()
+	logger *zap.Logger
+	store  MediaStore
+	events *eventbus.Bus
 
-	return nil
+	// sqlStore reads the media from the database given to NewServer or SetDB,
+	// it's the store unless one was set WithMediaStore
+	sqlStore *sqlMediaStore
+
+	// certLock guards cert, which changes when the certificate is rotated.
+	// The certificate is the global one unless ownCert is set
+	certLock sync.RWMutex
+	cert     *tls.Certificate
+	ownCert  bool
+
+	defaultAvatar []byte
+	variants      *variantCache
+	images        *variantCache
+	webpEncoder   ImageEncoder
+	recent        *recentMedia
+	clientCAs     *x509.CertPool
+	minTLSVersion uint16
+	auth          *basicAuth
+	cachePolicy   CachePolicy
+
+	stickers       *variantCache
+	stickerFetcher StickerFetcher
+
+	ipfs        *variantCache
+	ipfsFetcher IPFSFetcher
+
+	// maxPayloadBytes is the size above which media payloads aren't served
+	maxPayloadBytes int64
+	// limiter bounds the requests served at once, nil when unbounded
+	limiter *concurrencyLimiter
+	// janitor trims the caches while the server runs, nil when disabled
+	janitor *cacheJanitor
+	// trimmers trim the caches of other services along with the server ones
+	trimmers []CacheTrimmer
+	// statsSources report the counters of the caches of other services
+	statsSources []CacheStatsSource
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	metricsEnabled bool
+	metrics        *metrics
+	compression    bool
+
+	// portMin and portMax bound the ports tried when the preferred port is
+	// busy, zero when no range is set
+	portMin int
+	portMax int
+
+	// socketPath is the Unix domain socket listened on instead of a TCP port
+	// when set, without TLS if socketPlaintext is set
+	socketPath      string
+	socketPlaintext bool
+
+	// stateLock guards the running state, which changes from the serving
+	// goroutine. listener is the active listener and listenAddr its address,
+	// nil when the server isn't listening. insecureStarted is set while the
+	// server is counted among the running insecure servers
+	stateLock       sync.RWMutex
+	run             bool
+	server          *http.Server
+	listener        *pausableListener
+	listenAddr      net.Addr
+	insecureStarted bool
+
+	// handlers are the custom routes registered with Handle
+	handlersLock sync.Mutex
+	handlers     map[string]http.Handler
 }
 
-func (s *Server) Stop() error {
-	if s.server != nil {
-		return s.server.Shutdown(context.Background())
+// Option configures optional Server behaviour
+type Option func(*Server) error
+
+// WithDefaultAvatar makes the identicons endpoint serve the given image when
+// a request is missing the publicKey, instead of responding with an error
+func WithDefaultAvatar(image []byte) Option {
+	return func(s *Server) error {
+		s.defaultAvatar = image
+		return nil
 	}
+}
 
-	return nil
+// WithEventBus makes the server publish lifecycle events, such as served
+// media, on the given bus
+func WithEventBus(bus *eventbus.Bus) Option {
+	return func(s *Server) error {
+		s.events = bus
+		return nil
+	}
 }
 
-func (s *Server) ToForeground() {
-	if !s.run && (s.server != nil) {
+// WithVariantCacheSize sets the total size in bytes of the derived image
+// variants, such as transcoded or resized images, kept in memory
+func WithVariantCacheSize(maxBytes int64) Option {
+	return func(s *Server) error {
+		if maxBytes < 0 {
+			return errors.New("negative variant cache size")
+		}
+		s.variants.SetMaxBytes(maxBytes)
+		return nil
+	}
+}
+
+// WithImageCache keeps up to maxBytes of image payloads in memory, sparing
+// database queries when the same images are requested again
+func WithImageCache(maxBytes int64) Option {
+	return func(s *Server) error {
+		if maxBytes <= 0 {
+			return errors.New("image cache size must be positive")
+		}
+		s.images = newVariantCache("images", maxBytes, nil)
+		return nil
+	}
+}
+
+// WithRecentMedia makes the server remember the last size images and audio
+// messages it served, see RecentMedia
+func WithRecentMedia(size int) Option {
+	return func(s *Server) error {
+		if size <= 0 {
+			return errors.New("recent media size must be positive")
+		}
+		s.recent = newRecentMedia(size)
+		return nil
+	}
+}
+
+// WithClientCAs requires clients to present a certificate signed by one of
+// the given CAs, see ClientCertificate
+func WithClientCAs(pool *x509.CertPool) Option {
+	return func(s *Server) error {
+		if pool == nil {
+			return errors.New("nil client CA pool")
+		}
+		s.clientCAs = pool
+		return nil
+	}
+}
+
+// WithMetrics exposes the server metrics in the Prometheus format on the
+// /metrics route. Metrics are disabled by default
+func WithMetrics() Option {
+	return func(s *Server) error {
+		s.metricsEnabled = true
+		return nil
+	}
+}
+
+// WithMediaStore serves the media of store instead of those of the database
+// given to NewServer
+func WithMediaStore(store MediaStore) Option {
+	return func(s *Server) error {
+		if store == nil {
+			return errors.New("nil media store")
+		}
+		s.store = store
+		return nil
+	}
+}
+
+// WithDBBusyRetry sets how many times the queries to the database failing
+// because it's busy are retried, and the delay added between attempts every
+// time, instead of 3 times with a 50ms backoff. Zero retries disables them
+func WithDBBusyRetry(retries int, backoff time.Duration) Option {
+	return func(s *Server) error {
+		if retries < 0 || backoff < 0 {
+			return errors.New("invalid database busy retry")
+		}
+		s.sqlStore.busy = busyRetry{retries: retries, backoff: backoff}
+		return nil
+	}
+}
+
+// WithCompression compresses the text responses, such as JSON or SVG, of
+// clients accepting gzip or deflate encoding. Images and audio are never
+// compressed
+func WithCompression() Option {
+	return func(s *Server) error {
+		s.compression = true
+		return nil
+	}
+}
+
+// WithUnixSocket makes the server listen on the Unix domain socket at path
+// instead of a localhost TCP port, in which case Port isn't used. As the
+// socket is only reachable through the filesystem, TLS can be disabled with
+// plaintext
+func WithUnixSocket(path string, plaintext bool) Option {
+	return func(s *Server) error {
+		if path == "" {
+			return errors.New("empty socket path")
+		}
+		s.socketPath = path
+		s.socketPlaintext = plaintext
+		return nil
+	}
+}
+
+// WithPortRange makes the server listen on the first free port between min
+// and max, both included, when Port isn't set or is busy. Any free port is
+// used when the whole range is busy
+func WithPortRange(min, max int) Option {
+	return func(s *Server) error {
+		if min <= 0 || max > 65535 || min > max {
+			return fmt.Errorf("invalid port range [%d, %d]", min, max)
+		}
+		s.portMin = min
+		s.portMax = max
+		return nil
+	}
+}
+
+// WithMaxPayloadBytes sets the size in bytes above which image, audio and
+// avatar payloads are rejected with 413 Payload Too Large instead of being
+// served. It defaults to 25MB
+func WithMaxPayloadBytes(maxBytes int64) Option {
+	return func(s *Server) error {
+		if maxBytes <= 0 {
+			return errors.New("max payload size must be positive")
+		}
+		s.maxPayloadBytes = maxBytes
+		return nil
+	}
+}
+
+// WithTimeouts sets the time allowed to read a request, to write a response,
+// and to wait for the next request on an idle connection. A zero timeout
+// disables it
+func WithTimeouts(read, write, idle time.Duration) Option {
+	return func(s *Server) error {
+		if read < 0 || write < 0 || idle < 0 {
+			return errors.New("negative timeout")
+		}
+		s.readTimeout = read
+		s.writeTimeout = write
+		s.idleTimeout = idle
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version accepted by the server,
+// either tls.VersionTLS12 or tls.VersionTLS13. It defaults to TLS 1.2
+func WithMinTLSVersion(version uint16) Option {
+	return func(s *Server) error {
+		if version != tls.VersionTLS12 && version != tls.VersionTLS13 {
+			return fmt.Errorf("unsupported minimum TLS version %#x", version)
+		}
+		s.minTLSVersion = version
+		return nil
+	}
+}
+
+// WithOwnCertificate makes the server generate its own certificate instead
+// of sharing the global one returned by PublicTLSCert, see CertificatePEM
+func WithOwnCertificate() Option {
+	return func(s *Server) error {
+		s.ownCert = true
+		return nil
+	}
+}
+
+// WithStickerFetcher serves stickers by hash on the /stickers route,
+// downloading them with fetch
+func WithStickerFetcher(fetch StickerFetcher) Option {
+	return func(s *Server) error {
+		s.stickerFetcher = fetch
+		return nil
+	}
+}
+
+// WithIPFSFetcher serves IPFS content by CID on the /ipfs route, downloading
+// it with fetch
+func WithIPFSFetcher(fetch IPFSFetcher) Option {
+	return func(s *Server) error {
+		s.ipfsFetcher = fetch
+		return nil
+	}
+}
+
+func NewServer(db *sql.DB, logger *zap.Logger, opts ...Option) (*Server, error) {
+	sqlStore := &sqlMediaStore{db: db, busy: defaultBusyRetry}
+	s := &Server{store: sqlStore, sqlStore: sqlStore, logger: logger, Port: 0, Network: NetworkTCP4}
+	s.variants = newVariantCache("variants", defaultVariantCacheBytes, nil)
+	s.stickers = newVariantCache("stickers", defaultStickerCacheBytes, nil)
+	s.ipfs = newVariantCache("ipfs", defaultIPFSCacheBytes, nil)
+	s.cachePolicy = defaultCachePolicy()
+	s.maxPayloadBytes = defaultMaxPayloadBytes
+	s.readTimeout = defaultReadTimeout
+	s.writeTimeout = defaultWriteTimeout
+	s.idleTimeout = defaultIdleTimeout
+	s.minTLSVersion = tls.VersionTLS12
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if !s.Insecure {
+		if err := s.ensureCertificate(); err != nil {
+			return nil, err
+		}
+	}
+	s.variants.events = s.events
+	s.stickers.events = s.events
+	s.ipfs.events = s.events
+	if s.images != nil {
+		s.images.events = s.events
+	}
+
+	if s.metricsEnabled {
+		metrics, err := newMetrics(s)
+		if err != nil {
+			return nil, err
+		}
+		s.metrics = metrics
+	}
+
+	return s, nil
+}
+
+// SetDB replaces the database the media are read from, without restarting
+// the server, and clears the cached images. Requests being served keep
+// reading from the previous database
+func (s *Server) SetDB(db *sql.DB) error {
+	if db == nil {
+		return ErrNoDatabase
+	}
+	if s.store != MediaStore(s.sqlStore) {
+		return errors.New("server doesn't read media from a database")
+	}
+
+	s.sqlStore.setDB(db)
+
+	// The cached images, and the variants derived from them, may be missing
+	// or different in the new database
+	if s.images != nil {
+		s.images.Clear()
+	}
+	s.variants.Clear()
+	return nil
+}
+
+// certificate returns the certificate currently served
+func (s *Server) certificate() (*tls.Certificate, error) {
+	s.certLock.RLock()
+	defer s.certLock.RUnlock()
+
+	if s.cert == nil || len(s.cert.Certificate) == 0 {
+		return nil, errors.New("no certificate")
+	}
+	return s.cert, nil
+}
+
+// RotateCertificate replaces the certificate served by the server, as well as
+// the global certificate returned by PublicTLSCert unless the server has its
+// own certificate, with a newly generated one. New TLS handshakes use the new
+// certificate while established connections are kept. Client certificates
+// signed by the previous certificate are no longer accepted unless it's
+// still in the client CAs
+func (s *Server) RotateCertificate() error {
+	cert, certPem, err := newTLSCert()
+	if err != nil {
+		return err
+	}
+
+	if !s.ownCert {
+		globalCertificateLock.Lock()
+		globalCertificate, globalPem = cert, certPem
+		globalCertificateLock.Unlock()
+	}
+
+	s.certLock.Lock()
+	s.cert = cert
+	s.certLock.Unlock()
+
+	return nil
+}
+
+// CertificatePEM returns the PEM encoded certificate served by the server,
+// which is the one returned by PublicTLSCert unless the server has its own
+func (s *Server) CertificatePEM() (string, error) {
+	cert, err := s.certificate()
+	if err != nil {
+		return "", err
+	}
+
+	certPem, _, err := encodeTLSCertPEMs(cert)
+	if err != nil {
+		return "", err
+	}
+	return string(certPem), nil
+}
+
+// CertificateChain returns the DER encoded certificates served by the server,
+// starting with the leaf certificate
+func (s *Server) CertificateChain() ([][]byte, error) {
+	cert, err := s.certificate()
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([][]byte, len(cert.Certificate))
+	for i, der := range cert.Certificate {
+		chain[i] = append([]byte(nil), der...)
+	}
+
+	return chain, nil
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of the DER encoded
+// leaf certificate served by the server, as colon separated hex bytes
+func (s *Server) CertificateFingerprint() (string, error) {
+	cert, err := s.certificate()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(cert.Certificate[0])
+	hexBytes := make([]string, len(sum))
+	for i, b := range sum {
+		hexBytes[i] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(hexBytes, ":"), nil
+}
+
+// ExportCertificate writes the certificate served by the server and its
+// private key to cert.pem and key.pem in dir, so that external tools can trust
+// or inspect the server. The key is only readable by the owner
+func (s *Server) ExportCertificate(dir string) error {
+	cert, err := s.certificate()
+	if err != nil {
+		return err
+	}
+
+	certPem, keyPem, err := encodeTLSCertPEMs(cert)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(filepath.Join(dir, "cert.pem"), certPem, 0644)
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "key.pem"), keyPem, 0600)
+}
+
+// writeFile writes data to path, setting perm before writing even if the file
+// already exists
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	err = f.Chmod(perm)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+var ErrUnknownMediaKind = errors.New("unknown media kind")
+
+// MediaInfo returns the size and MIME type of the image or audio payload of a
+// message, without loading the whole payload in memory
+func (s *Server) MediaInfo(messageID, kind string) (int64, string, error) {
+	if kind != "image" && kind != "audio" {
+		return 0, "", ErrUnknownMediaKind
+	}
+
+	head, err := s.store.MediaHead(context.Background(), messageID, kind)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if kind == "audio" {
+		return head.Size, audioMime(head.Head), nil
+	}
+
+	mime, err := images.ImageMime(head.Head)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return head.Size, mime, nil
+}
+
+// RecentMedia returns the last media served, most recent first. It's empty
+// unless the server was created WithRecentMedia
+func (s *Server) RecentMedia() []MediaAccess {
+	return s.recent.List()
+}
+
+// ClientCertificate generates a PEM encoded client certificate and key signed
+// by the server certificate, to be used with WithClientCAs
+func (s *Server) ClientCertificate() ([]byte, []byte, error) {
+	cert, err := s.certificate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notBefore := time.Now()
+	return GenerateClientCertPEMs(cert, notBefore, notBefore.Add(365*24*time.Hour))
+}
+
+func (s *Server) tlsConfig() *tls.Config {
+	cfg := &tls.Config{ServerName: "localhost", MinVersion: s.minTLSVersion}
+	// The certificate is looked up on every handshake so that rotating it
+	// doesn't require a restart
+	cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return s.certificate()
+	}
+	if s.clientCAs != nil {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = s.clientCAs
+	}
+	if s.TLSConfigurator != nil {
+		s.TLSConfigurator(cfg)
+	}
+	return cfg
+}
+
+// ErrNotSocket is returned when the configured socket path holds a file that
+// isn't a socket, which is never removed
+var ErrNotSocket = errors.New("socket path isn't a socket")
+
+// removeSocket removes the socket file at path, if any
+func removeSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%w: %s", ErrNotSocket, path)
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// listenUnix listens on the configured Unix domain socket, replacing the
+// socket file a previous run may have left behind
+func (s *Server) listenUnix() (net.Listener, error) {
+	err := removeSocket(s.socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.socketPlaintext || s.Insecure {
+		return listener, nil
+	}
+	return tls.NewListener(listener, s.tlsConfig()), nil
+}
+
+// ListenAddr returns the address the server listens on, either a TCP or a
+// Unix domain socket address, or nil when it isn't listening
+func (s *Server) ListenAddr() net.Addr {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+	return s.listenAddr
+}
+
+// ListenNetwork returns the network the server listens on: NetworkTCP4 or
+// NetworkTCP6 when it listens on a single loopback address, NetworkTCP when
+// it listens on both, "unix" for a Unix domain socket, or an empty string
+// when it isn't listening
+func (s *Server) ListenNetwork() string {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.network
+}
+
+// Addr returns the address clients connect to: localhost with the port
+// listened on, or the path of the Unix domain socket. It's empty when the
+// server isn't listening
+func (s *Server) Addr() string {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+
+	switch addr := s.listenAddr.(type) {
+	case *net.TCPAddr:
+		return net.JoinHostPort("localhost", strconv.Itoa(addr.Port))
+	case *net.UnixAddr:
+		return addr.Name
+	default:
+		return ""
+	}
+}
+
+// BaseURL returns the URL the routes of the server are relative to, such as
+// https://localhost:<port>, with the scheme served. Over a Unix domain
+// socket, the host is localhost and requests have to be dialed to the
+// socket. It's empty when the server isn't listening
+func (s *Server) BaseURL() string {
+	s.stateLock.RLock()
+	listenAddr := s.listenAddr
+	s.stateLock.RUnlock()
+
+	scheme := "https"
+	host := "localhost"
+	switch addr := listenAddr.(type) {
+	case *net.TCPAddr:
+		host = net.JoinHostPort(host, strconv.Itoa(addr.Port))
+		if s.Insecure {
+			scheme = "http"
+		}
+	case *net.UnixAddr:
+		if s.socketPlaintext || s.Insecure {
+			scheme = "http"
+		}
+	default:
+		return ""
+	}
+
+	return scheme + "://" + host
+}
+
+// Running tells whether the server is serving requests
+func (s *Server) Running() bool {
+	s.stateLock.RLock()
+	defer s.stateLock.RUnlock()
+	return s.run
+}
+
+// setRunning records the state of srv, unless it was replaced by a restart
+// in the meantime. Listening on another port than before is notified
+func (s *Server) setRunning(srv *http.Server, listener *pausableListener) {
+	s.stateLock.Lock()
+
+	if s.server != srv {
+		s.stateLock.Unlock()
+		return
+	}
+
+	previousPort := s.Port
+	s.run = listener != nil
+	s.listener = listener
+	s.listenAddr = nil
+	if listener != nil {
+		s.listenAddr = listener.Addr()
+		if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+			s.Port = addr.Port
+		}
+	}
+	port := s.Port
+	s.stateLock.Unlock()
+
+	if previousPort != 0 && port != previousPort {
+		s.portChanged(port)
+	}
+}
+
+// portChanged notifies that the server listens on port, so that the media
+// URLs can be rebuilt
+func (s *Server) portChanged(port int) {
+	baseURL := s.BaseURL()
+	s.logger.Info("server port changed", zap.Int("port", port), zap.String("baseURL", baseURL))
+	signal.SendMediaServerPortChanged(port, baseURL)
+	s.events.Publish(eventbus.MediaPortChanged, eventbus.MediaPortChangedPayload{Port: port, BaseURL: baseURL})
+}
+
+func (s *Server) listenAndServe(srv *http.Server) {
+	if s.socketPath != "" {
+		listener, err := s.listenUnix()
+		if err != nil {
+			s.logger.Error("failed to start server on socket", zap.String("path", s.socketPath), zap.Error(err))
+			return
+		}
+		s.serve(srv, listener, "unix")
+		return
+	}
+
+	cfg := s.tlsConfig()
+
+	// in case of restart, we should use the same port as the first start in order not to break existing links
+	s.stateLock.RLock()
+	port := s.Port
+	s.stateLock.RUnlock()
+
+	listener, network, err := s.listenPorts(port)
+	if err != nil {
+		s.logger.Error("failed to start server, giving up", zap.String("network", s.Network), zap.Error(err))
+		return
+	}
+
+	if s.Insecure {
+		s.logger.Warn("serving plain HTTP, for local debugging only")
+		s.serve(srv, listener, network)
+		return
+	}
+
+	s.serve(srv, tls.NewListener(listener, cfg), network)
+}
+
+func (s *Server) serve(srv *http.Server, listener net.Listener, network string) {
+	pausable := newPausableListener(listener, network)
+	s.setRunning(srv, pausable)
+	err := srv.Serve(pausable)
+	s.setRunning(srv, nil)
+	if err != http.ErrServerClosed {
+		s.logger.Error("server failed unexpectedly, restarting", zap.Error(err))
+		err = s.Start()
+		if err != nil {
+			s.logger.Error("server start failed, giving up", zap.Error(err))
+		}
+	}
+}
+
+// builtinRoutes returns the routes served by every server
+func (s *Server) builtinRoutes() map[string]http.Handler {
+	var stickers *stickerHandler
+	if s.stickerFetcher != nil {
+		stickers = &stickerHandler{fetch: s.stickerFetcher, cache: s.stickers, logger: s.logger, events: s.events, cacheControl: s.cachePolicy["/stickers"]}
+	}
+
+	routes := map[string]http.Handler{
+		"/messages/images":     &imageHandler{store: s.store, logger: s.logger, events: s.events, recent: s.recent, cacheControl: s.cachePolicy["/messages/images"], maxBytes: s.maxPayloadBytes, webp: s.webpEncoder, variants: s.variants, images: s.images, stickers: stickers},
+		"/messages/audio":      &audioHandler{store: s.store, logger: s.logger, events: s.events, recent: s.recent, cacheControl: s.cachePolicy["/messages/audio"], maxBytes: s.maxPayloadBytes},
+		"/messages/avatar":     &avatarHandler{store: s.store, logger: s.logger, events: s.events, cacheControl: s.cachePolicy["/messages/avatar"], maxBytes: s.maxPayloadBytes, identiconCacheControl: s.cachePolicy["/messages/identicons"]},
+		"/health":              &healthHandler{store: s.store, logger: s.logger},
+		"/messages/identicons": &identiconHandler{logger: s.logger, events: s.events, defaultAvatar: s.defaultAvatar, cacheControl: s.cachePolicy["/messages/identicons"]},
+	}
+	if stickers != nil {
+		routes["/stickers"] = stickers
+	}
+	if s.ipfsFetcher != nil {
+		routes["/ipfs"] = &ipfsHandler{fetch: s.ipfsFetcher, cache: s.ipfs, logger: s.logger, events: s.events, cacheControl: s.cachePolicy["/ipfs"], maxBytes: s.maxPayloadBytes, timeout: ipfsFetchTimeout}
+	}
+	return routes
+}
+
+// Handle registers an additional route, served from the next time the server
+// starts. Like http.ServeMux, it panics if pattern is already registered
+func (s *Server) Handle(pattern string, h http.Handler) {
+	if _, exists := s.builtinRoutes()[pattern]; exists || (pattern == "/metrics" && s.metrics != nil) {
+		panic("server: multiple registrations for " + pattern)
+	}
+
+	s.handlersLock.Lock()
+	defer s.handlersLock.Unlock()
+
+	if _, exists := s.handlers[pattern]; exists {
+		panic("server: multiple registrations for " + pattern)
+	}
+	if s.handlers == nil {
+		s.handlers = make(map[string]http.Handler)
+	}
+	s.handlers[pattern] = h
+}
+
+func (s *Server) routes() http.Handler {
+	routes := s.builtinRoutes()
+
+	s.handlersLock.Lock()
+	for pattern, h := range s.handlers {
+		routes[pattern] = h
+	}
+	s.handlersLock.Unlock()
+
+	handler := http.NewServeMux()
+	for pattern, h := range routes {
+		if s.metrics != nil {
+			h = s.metrics.instrument(pattern, h)
+		}
+		handler.Handle(pattern, h)
+	}
+
+	if s.metrics != nil {
+		handler.Handle("/metrics", s.metrics.handler())
+	}
+
+	var h http.Handler = handler
+	if s.limiter != nil {
+		h = s.limiter.limit(h)
+	}
+	if s.compression {
+		h = compress(h)
+	}
+	if s.auth != nil {
+		h = s.auth.authenticate(h)
+	}
+	return withRequestID(h)
+}
+
+func (s *Server) Start() error {
+	if s.socketPath == "" && !validNetwork(s.Network) {
+		return ErrInvalidNetwork
+	}
+
+	// Insecure may have been unset after the server was created without
+	// certificate
+	if !s.Insecure {
+		if err := s.ensureCertificate(); err != nil {
+			return err
+		}
+	}
+
+	srv := &http.Server{
+		Handler:      s.routes(),
+		ReadTimeout:  s.readTimeout,
+		WriteTimeout: s.writeTimeout,
+		IdleTimeout:  s.idleTimeout,
+	}
+
+	s.stateLock.Lock()
+	s.server = srv
+	if s.Insecure && !s.insecureStarted {
+		s.insecureStarted = true
+		startInsecure()
+	}
+	s.stateLock.Unlock()
+
+	go s.listenAndServe(srv)
+
+	if s.janitor != nil {
+		s.janitor.start(s.TrimCaches)
+	}
+
+	return nil
+}
+
+func (s *Server) Stop() error {
+	// The janitor is stopped even if the server fails to shut down
+	if s.janitor != nil {
+		defer s.janitor.stopAndWait()
+	}
+
+	s.stateLock.RLock()
+	srv := s.server
+	s.stateLock.RUnlock()
+
+	if srv != nil {
+		err := srv.Shutdown(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	s.stateLock.Lock()
+	if s.insecureStarted {
+		s.insecureStarted = false
+		stopInsecure()
+	}
+	s.stateLock.Unlock()
+
+	if s.socketPath != "" {
+		return removeSocket(s.socketPath)
+	}
+
+	return nil
+}
+
+func (s *Server) ToForeground() {
+	s.stateLock.RLock()
+	start := !s.run && s.server != nil
+	s.stateLock.RUnlock()
+
+	if start {
 		err := s.Start()
 		if err != nil {
 			s.logger.Error("server start failed during foreground transition", zap.Error(err))
@@ -241,7 +1604,7 @@ func (s *Server) ToForeground() {
 }
 
 func (s *Server) ToBackground() {
-	if s.run {
+	if s.Running() {
 		err := s.Stop()
 		if err != nil {
 			s.logger.Error("server stop failed during background transition", zap.Error(err))
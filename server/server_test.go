@@ -0,0 +1,1129 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/appdatabase"
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func setupTestDB(t *testing.T) (*sql.DB, func()) {
+	db, stop, err := appdatabase.SetupTestSQLDB("server-tests-")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS user_messages (id VARCHAR PRIMARY KEY, source TEXT, image_payload BLOB, audio_payload BLOB)`)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS chat_identity_contacts (contact_id VARCHAR NOT NULL, image_type VARCHAR NOT NULL, clock_value INT NOT NULL, payload BLOB NOT NULL)`)
+	require.NoError(t, err)
+
+	return db, func() {
+		require.NoError(t, stop())
+	}
+}
+
+func TestAudioHandlerStopsOnClientDisconnect(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	audio := make([]byte, 64*1024*1024)
+	_, err := db.Exec(`INSERT INTO user_messages (id, audio_payload) VALUES (?, ?)`, "1", audio)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	handler := &audioHandler{store: NewSQLMediaStore(db), logger: zap.NewNop()}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?messageId=1")
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	_, err = resp.Body.Read(buf)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler kept streaming after the client disconnected")
+	}
+}
+
+func TestAudioHandlerStreamsInChunks(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	audio := make([]byte, 3*payloadChunkSize+17)
+	_, err := rand.Read(audio)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_messages (id, audio_payload) VALUES (?, ?)`, "1", audio)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(&audioHandler{store: NewSQLMediaStore(db), logger: zap.NewNop()})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?messageId=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(len(audio)), resp.ContentLength)
+	require.Equal(t, audio, body)
+}
+
+func TestIdenticonHandlerMissingPublicKey(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/messages/identicons")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// testPublicKey is the secp256k1 generator point, a valid public key
+const testPublicKey = "0x0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+
+const otherTestPublicKey = "0x04c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee51ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a"
+
+func TestIdenticonHandler(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	expected, err := identicon.Generate(testPublicKey)
+	require.NoError(t, err)
+
+	resp, err := http.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, expected, body)
+
+	for _, pk := range []string{"0x04aa", "04aa", "0xzz", testPublicKey[2:]} {
+		resp, err := http.Get(ts.URL + "/messages/identicons?publicKey=" + pk)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, pk)
+		require.Empty(t, resp.Header.Get("Expires"), pk)
+	}
+}
+
+func TestIdenticonHandlerDefaultAvatar(t *testing.T) {
+	avatar, err := identicon.Generate("0x04")
+	require.NoError(t, err)
+
+	s, err := NewServer(nil, zap.NewNop(), WithDefaultAvatar(avatar))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/messages/identicons")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, avatar, body)
+}
+
+func TestAvatarHandler(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	avatar, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO chat_identity_contacts (contact_id, image_type, clock_value, payload) VALUES (?, ?, ?, ?)`, testPublicKey, "thumbnail", 1, avatar)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_messages (id, source) VALUES (?, ?)`, "1", testPublicKey)
+	require.NoError(t, err)
+
+	fallback, err := identicon.Generate(otherTestPublicKey)
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		name         string
+		query        string
+		body         []byte
+		cacheControl string
+	}{
+		{"stored avatar", "?publicKey=" + testPublicKey, avatar, "no-store"},
+		{"stored avatar of message sender", "?messageId=1", avatar, "no-store"},
+		{"identicon fallback", "?publicKey=" + otherTestPublicKey, fallback, "no-cache"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + "/messages/avatar" + tc.query)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+			require.Equal(t, tc.cacheControl, resp.Header.Get("Cache-Control"))
+			require.Equal(t, tc.body, body)
+		})
+	}
+
+	for _, query := range []string{"", "?publicKey=0x04bb"} {
+		resp, err := http.Get(ts.URL + "/messages/avatar" + query)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestMediaInfo(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+	image = append(image, make([]byte, 1024*1024)...)
+
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload, audio_payload) VALUES (?, ?, ?)`, "1", image, make([]byte, 2048))
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	size, mime, err := s.MediaInfo("1", "image")
+	require.NoError(t, err)
+	require.Equal(t, int64(len(image)), size)
+	require.Equal(t, "image/png", mime)
+
+	size, mime, err = s.MediaInfo("1", "audio")
+	require.NoError(t, err)
+	require.Equal(t, int64(2048), size)
+	require.Equal(t, "audio/aac", mime)
+
+	_, _, err = s.MediaInfo("2", "image")
+	require.Equal(t, ErrMediaNotFound, err)
+
+	_, _, err = s.MediaInfo("1", "video")
+	require.Equal(t, ErrUnknownMediaKind, err)
+}
+
+func TestMediaHandlersHead(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload, audio_payload) VALUES (?, ?, ?)`, "1", image, make([]byte, 2048))
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	testCases := []struct {
+		path          string
+		status        int
+		contentType   string
+		contentLength int64
+	}{
+		{"/messages/images?messageId=1", http.StatusOK, "image/png", int64(len(image))},
+		{"/messages/audio?messageId=1", http.StatusOK, "audio/aac", 2048},
+		{"/messages/images?messageId=2", http.StatusNotFound, "", 0},
+		{"/messages/audio?messageId=2", http.StatusNotFound, "", 0},
+	}
+
+	for _, tc := range testCases {
+		resp, err := http.Head(ts.URL + tc.path)
+		require.NoError(t, err)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Empty(t, body)
+
+		require.Equal(t, tc.status, resp.StatusCode, tc.path)
+		if tc.status == http.StatusOK {
+			require.Equal(t, tc.contentType, resp.Header.Get("Content-Type"), tc.path)
+			require.Equal(t, tc.contentLength, resp.ContentLength, tc.path)
+		}
+	}
+}
+
+func TestMaxPayloadBytes(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+	image = append(image, make([]byte, 2048)...)
+
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload, audio_payload) VALUES (?, ?, ?), (?, NULL, ?)`, "large", image, make([]byte, 2048), "small", make([]byte, 512))
+	require.NoError(t, err)
+
+	_, err = NewServer(db, zap.NewNop(), WithMaxPayloadBytes(0))
+	require.Error(t, err)
+
+	s, err := NewServer(db, zap.NewNop(), WithMaxPayloadBytes(1024))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	for _, path := range []string{"/messages/images?messageId=large", "/messages/audio?messageId=large"} {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, path)
+		require.True(t, len(body) < 1024, path)
+
+		resp, err = http.Head(ts.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, path)
+	}
+
+	resp, err := http.Get(ts.URL + "/messages/audio?messageId=small")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, body, 512)
+}
+
+func TestAudioHandlerMissingAudio(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	_, err := db.Exec(`INSERT INTO user_messages (id, audio_payload) VALUES (?, NULL), (?, x'')`, "null", "empty")
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(&audioHandler{store: NewSQLMediaStore(db), logger: zap.NewNop()})
+	defer ts.Close()
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		for messageID, status := range map[string]int{
+			"null":  http.StatusNotFound,
+			"empty": http.StatusUnprocessableEntity,
+		} {
+			req, err := http.NewRequest(method, ts.URL+"?messageId="+messageID, nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, status, resp.StatusCode, "%s %s", method, messageID)
+		}
+	}
+}
+
+func TestAudioHandlerMime(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	_, err := db.Exec(`INSERT INTO user_messages (id, audio_payload) VALUES (?, ?), (?, ?)`, "ogg", []byte("OggS\x00\x02audio"), "unknown", []byte("audio"))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(&audioHandler{store: NewSQLMediaStore(db), logger: zap.NewNop()})
+	defer ts.Close()
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		for messageID, mime := range map[string]string{"ogg": "audio/ogg", "unknown": "audio/aac"} {
+			req, err := http.NewRequest(method, ts.URL+"?messageId="+messageID, nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, mime, resp.Header.Get("Content-Type"), "%s %s", method, messageID)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	s.Handle("/custom", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("custom"))
+	}))
+	require.Panics(t, func() { s.Handle("/custom", http.NotFoundHandler()) })
+	require.Panics(t, func() { s.Handle("/messages/images", http.NotFoundHandler()) })
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/custom")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "custom", string(body))
+
+	// Built-in routes are still served
+	resp, err = http.Get(ts.URL + "/messages/identicons")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// busyConnector opens connections whose queries fail with a busy error until
+// failures are exhausted, and then return payload
+type busyConnector struct {
+	mu       sync.Mutex
+	failures int
+	queries  int
+	payload  []byte
+}
+
+func (c *busyConnector) Connect(context.Context) (driver.Conn, error) { return &busyConn{c}, nil }
+func (c *busyConnector) Driver() driver.Driver                        { return nil }
+
+type busyConn struct{ c *busyConnector }
+
+func (c *busyConn) Prepare(query string) (driver.Stmt, error) {
+	return &busyStmt{c: c.c, withSize: strings.Contains(query, "length(")}, nil
+}
+func (c *busyConn) Close() error              { return nil }
+func (c *busyConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+// busyStmt returns the payload, preceded by its size when the query reads it
+type busyStmt struct {
+	c        *busyConnector
+	withSize bool
+}
+
+func (s *busyStmt) Close() error  { return nil }
+func (s *busyStmt) NumInput() int { return -1 }
+func (s *busyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s *busyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	s.c.queries++
+	if s.c.failures > 0 {
+		s.c.failures--
+		return nil, errors.New("database is locked")
+	}
+	if s.withSize {
+		return &payloadRows{columns: []string{"size", "payload"}, values: []driver.Value{int64(len(s.c.payload)), s.c.payload}}, nil
+	}
+	return &payloadRows{columns: []string{"payload"}, values: []driver.Value{s.c.payload}}, nil
+}
+
+type payloadRows struct {
+	columns []string
+	values  []driver.Value
+	done    bool
+}
+
+func (r *payloadRows) Columns() []string { return r.columns }
+func (r *payloadRows) Close() error      { return nil }
+func (r *payloadRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	copy(dest, r.values)
+	r.done = true
+	return nil
+}
+
+func TestAudioHandlerRetriesBusyDB(t *testing.T) {
+	connector := &busyConnector{failures: 1, payload: []byte("audio")}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ts := httptest.NewServer(&audioHandler{store: NewSQLMediaStore(db), logger: zap.NewNop()})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?messageId=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []byte("audio"), body)
+	require.Equal(t, 2, connector.queries)
+}
+
+func TestAudioHandlerBusyDBExhaustsRetries(t *testing.T) {
+	connector := &busyConnector{failures: dbBusyRetries + 1, payload: []byte("audio")}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	ts := httptest.NewServer(&audioHandler{store: NewSQLMediaStore(db), logger: zap.NewNop()})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "?messageId=1")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, dbBusyRetries+1, connector.queries)
+}
+
+func TestDBBusyRetryOption(t *testing.T) {
+	connector := &busyConnector{failures: 5, payload: []byte("audio")}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	_, err := NewServer(db, zap.NewNop(), WithDBBusyRetry(-1, 0))
+	require.Error(t, err)
+
+	s, err := NewServer(db, zap.NewNop(), WithDBBusyRetry(5, time.Millisecond))
+	require.NoError(t, err)
+
+	audio, err := s.sqlStore.Audio(context.Background(), "1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("audio"), audio)
+	require.Equal(t, 6, connector.queries)
+
+	// Without retries, the first busy error is returned
+	connector.failures, connector.queries = 1, 0
+	s, err = NewServer(db, zap.NewNop(), WithDBBusyRetry(0, 0))
+	require.NoError(t, err)
+
+	_, err = s.sqlStore.Audio(context.Background(), "1")
+	require.True(t, isDBBusy(err), err)
+	require.Equal(t, 1, connector.queries)
+}
+
+func TestRetryBusyHonorsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queries := 0
+	err := defaultBusyRetry.run(ctx, func() error {
+		queries++
+		// The client disconnects while the database is busy
+		cancel()
+		return errors.New("database is locked")
+	})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 1, queries)
+}
+
+func TestCertificateChain(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	chain, err := s.CertificateChain()
+	require.NoError(t, err)
+	require.NotEmpty(t, chain)
+
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+
+	block, _ := pem.Decode([]byte(certPem))
+	require.NotNil(t, block)
+	require.Equal(t, block.Bytes, chain[0])
+
+	leaf, err := x509.ParseCertificate(chain[0])
+	require.NoError(t, err)
+	require.Equal(t, []string{"localhost"}, leaf.DNSNames)
+}
+
+func TestCertificateFingerprint(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	fingerprint, err := s.CertificateFingerprint()
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9A-F]{2}(:[0-9A-F]{2}){31}$`, fingerprint)
+
+	chain, err := s.CertificateChain()
+	require.NoError(t, err)
+	sum := sha256.Sum256(chain[0])
+	require.Equal(t, strings.ToUpper(hex.EncodeToString(sum[:])), strings.Replace(fingerprint, ":", "", -1))
+
+	_, err = (&Server{}).CertificateFingerprint()
+	require.Error(t, err)
+}
+
+func TestExportCertificate(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "server-export-cert")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// An existing key file gets restricted too
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(keyPath, nil, 0644))
+
+	require.NoError(t, s.ExportCertificate(dir))
+
+	certPem, err := ioutil.ReadFile(filepath.Join(dir, "cert.pem"))
+	require.NoError(t, err)
+	publicPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	require.Equal(t, publicPem, string(certPem))
+
+	keyPem, err := ioutil.ReadFile(keyPath)
+	require.NoError(t, err)
+	info, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	pair, err := tls.X509KeyPair(certPem, keyPem)
+	require.NoError(t, err)
+	chain, err := s.CertificateChain()
+	require.NoError(t, err)
+	require.Equal(t, chain, pair.Certificate)
+
+	require.Error(t, (&Server{}).ExportCertificate(dir))
+}
+
+func TestRotateCertificate(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(s.routes())
+	ts.TLS = s.tlsConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	clientFor := func(certPem string) *http.Client {
+		pool := x509.NewCertPool()
+		require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    pool,
+			ServerName: "localhost",
+			MinVersion: tls.VersionTLS12,
+		}}}
+	}
+	peerCertificate := func(client *http.Client) []byte {
+		resp, err := client.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.TLS.PeerCertificates[0].Raw
+	}
+
+	oldPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	oldFingerprint, err := s.CertificateFingerprint()
+	require.NoError(t, err)
+
+	oldClient := clientFor(oldPem)
+	oldCert := peerCertificate(oldClient)
+
+	require.NoError(t, s.RotateCertificate())
+
+	newPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	require.NotEqual(t, oldPem, newPem)
+	newFingerprint, err := s.CertificateFingerprint()
+	require.NoError(t, err)
+	require.NotEqual(t, oldFingerprint, newFingerprint)
+
+	chain, err := s.CertificateChain()
+	require.NoError(t, err)
+	block, _ := pem.Decode([]byte(newPem))
+	require.Equal(t, block.Bytes, chain[0])
+
+	// The established connection is reused with the previous certificate
+	require.Equal(t, oldCert, peerCertificate(oldClient))
+
+	// New connections are served the new certificate
+	require.Equal(t, chain[0], peerCertificate(clientFor(newPem)))
+}
+
+func TestClientCertificateAuth(t *testing.T) {
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+
+	s, err := NewServer(nil, zap.NewNop(), WithClientCAs(pool))
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(s.routes())
+	ts.TLS = s.tlsConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	clientCertPem, clientKeyPem, err := s.ClientCertificate()
+	require.NoError(t, err)
+	clientCert, err := tls.X509KeyPair(clientCertPem, clientKeyPem)
+	require.NoError(t, err)
+
+	get := func(certificates []tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      pool,
+			ServerName:   "localhost",
+			Certificates: certificates,
+			MinVersion:   tls.VersionTLS12,
+		}}}
+		return client.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	}
+
+	_, err = get(nil)
+	require.Error(t, err)
+
+	resp, err := get([]tls.Certificate{clientCert})
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Without client CAs, client certificates aren't requested
+	s, err = NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, tls.NoClientCert, s.tlsConfig().ClientAuth)
+}
+
+func TestPublicTLSCertConcurrent(t *testing.T) {
+	ResetGlobalCertificate()
+
+	const callers = 20
+	pems := make([]string, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			certPem, err := PublicTLSCert()
+			require.NoError(t, err)
+			pems[i] = certPem
+		}(i)
+	}
+	wg.Wait()
+
+	require.NotEmpty(t, pems[0])
+	for _, certPem := range pems {
+		require.Equal(t, pems[0], certPem)
+	}
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	chain, err := s.CertificateChain()
+	require.NoError(t, err)
+	block, _ := pem.Decode([]byte(pems[0]))
+	require.Equal(t, block.Bytes, chain[0])
+}
+
+func TestOwnCertificate(t *testing.T) {
+	shared, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	first, err := NewServer(nil, zap.NewNop(), WithOwnCertificate())
+	require.NoError(t, err)
+	second, err := NewServer(nil, zap.NewNop(), WithOwnCertificate())
+	require.NoError(t, err)
+
+	globalPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	sharedPem, err := shared.CertificatePEM()
+	require.NoError(t, err)
+	require.Equal(t, globalPem, sharedPem)
+
+	firstPem, err := first.CertificatePEM()
+	require.NoError(t, err)
+	secondPem, err := second.CertificatePEM()
+	require.NoError(t, err)
+	require.NotEqual(t, firstPem, secondPem)
+	require.NotEqual(t, globalPem, firstPem)
+	require.NotEqual(t, globalPem, secondPem)
+
+	// Rotating an own certificate leaves the global one alone
+	require.NoError(t, first.RotateCertificate())
+	rotatedPem, err := first.CertificatePEM()
+	require.NoError(t, err)
+	require.NotEqual(t, firstPem, rotatedPem)
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	require.Equal(t, globalPem, certPem)
+
+	// Servers keep their certificate when the global one is reset
+	ResetGlobalCertificate()
+	certPem, err = PublicTLSCert()
+	require.NoError(t, err)
+	require.NotEqual(t, globalPem, certPem)
+	sharedPem, err = shared.CertificatePEM()
+	require.NoError(t, err)
+	require.Equal(t, globalPem, sharedPem)
+}
+
+// waitListening waits for the server started in the background to listen
+func waitListening(t *testing.T, s *Server) net.Addr {
+	for i := 0; i < 100; i++ {
+		if addr := s.ListenAddr(); addr != nil {
+			return addr
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server isn't listening")
+	return nil
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server-socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+
+	for _, plaintext := range []bool{true, false} {
+		path := filepath.Join(dir, "media.sock")
+		// A stale socket file is replaced on start
+		stale, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, stale.Close())
+
+		s, err := NewServer(nil, zap.NewNop(), WithUnixSocket(path, plaintext))
+		require.NoError(t, err)
+		require.Nil(t, s.ListenAddr())
+		require.NoError(t, s.Start())
+
+		addr := waitListening(t, s)
+		require.Equal(t, "unix", addr.Network())
+		require.Equal(t, path, addr.String())
+
+		scheme := "https"
+		if plaintext {
+			scheme = "http"
+		}
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		}}
+		require.Equal(t, scheme+"://localhost", s.BaseURL())
+		require.Equal(t, path, s.Addr())
+		resp, err := client.Get(s.BaseURL() + "/messages/identicons?publicKey=" + testPublicKey)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, plaintext, resp.TLS == nil)
+		require.Equal(t, 0, s.Port)
+
+		require.NoError(t, s.Stop())
+		_, err = os.Stat(path)
+		require.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestUnixSocketKeepsOtherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server-socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "media.sock")
+	require.NoError(t, ioutil.WriteFile(path, []byte("data"), 0600))
+
+	s, err := NewServer(nil, zap.NewNop(), WithUnixSocket(path, true))
+	require.NoError(t, err)
+
+	_, err = s.listenUnix()
+	require.True(t, errors.Is(err, ErrNotSocket), err)
+	err = s.Stop()
+	require.True(t, errors.Is(err, ErrNotSocket), err)
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), data)
+}
+
+func TestBaseURL(t *testing.T) {
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+
+	for _, insecure := range []bool{false, true} {
+		var opts []Option
+		scheme := "https"
+		if insecure {
+			opts = append(opts, WithInsecure())
+			scheme = "http"
+		}
+
+		s, err := NewServer(nil, zap.NewNop(), opts...)
+		require.NoError(t, err)
+		require.Empty(t, s.Addr())
+		require.Empty(t, s.BaseURL())
+
+		require.NoError(t, s.Start())
+		waitListening(t, s)
+		require.Equal(t, fmt.Sprintf("localhost:%d", s.Port), s.Addr())
+		require.Equal(t, scheme+"://"+s.Addr(), s.BaseURL())
+
+		resp, err := client.Get(s.BaseURL() + "/messages/identicons?publicKey=" + testPublicKey)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, insecure, resp.TLS == nil)
+
+		client.CloseIdleConnections()
+		require.NoError(t, s.Stop())
+		require.Eventually(t, func() bool { return s.BaseURL() == "" }, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestRunningState(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.False(t, s.Running())
+
+	require.NoError(t, s.Start())
+	waitListening(t, s)
+	require.True(t, s.Running())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s.ToBackground()
+				s.ToForeground()
+				_ = s.Running()
+				_ = s.ListenAddr()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, s.Stop())
+	for i := 0; i < 100 && s.Running(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.False(t, s.Running())
+
+	s.ToForeground()
+	waitListening(t, s)
+	require.True(t, s.Running())
+	require.NoError(t, s.Stop())
+}
+
+func TestTimeouts(t *testing.T) {
+	_, err := NewServer(nil, zap.NewNop(), WithTimeouts(-time.Second, 0, 0))
+	require.Error(t, err)
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	waitListening(t, s)
+	s.stateLock.RLock()
+	srv := s.server
+	s.stateLock.RUnlock()
+	require.Equal(t, defaultReadTimeout, srv.ReadTimeout)
+	require.Equal(t, defaultWriteTimeout, srv.WriteTimeout)
+	require.Equal(t, defaultIdleTimeout, srv.IdleTimeout)
+	require.NoError(t, s.Stop())
+
+	s, err = NewServer(nil, zap.NewNop(), WithTimeouts(100*time.Millisecond, time.Second, time.Second))
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	addr := waitListening(t, s)
+
+	// A client that never completes its request is disconnected
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+	conn, err := tls.Dial("tcp", addr.String(), &tls.Config{RootCAs: pool, ServerName: "localhost", MinVersion: tls.VersionTLS12})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /messages/identicons HTTP/1.1\r\nHost: localhost\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = ioutil.ReadAll(conn)
+	var netErr net.Error
+	require.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection wasn't closed by the server")
+	require.NoError(t, s.Stop())
+}
+
+func TestMinTLSVersion(t *testing.T) {
+	_, err := NewServer(nil, zap.NewNop(), WithMinTLSVersion(tls.VersionTLS11))
+	require.Error(t, err)
+	_, err = NewServer(nil, zap.NewNop(), WithMinTLSVersion(0x0305))
+	require.Error(t, err)
+
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), s.tlsConfig().MinVersion)
+
+	s, err = NewServer(nil, zap.NewNop(), WithMinTLSVersion(tls.VersionTLS13))
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(s.routes())
+	ts.TLS = s.tlsConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+
+	get := func(maxVersion uint16) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    pool,
+			ServerName: "localhost",
+			MinVersion: tls.VersionTLS12,
+			MaxVersion: maxVersion,
+		}}}
+		return client.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	}
+
+	_, err = get(tls.VersionTLS12)
+	require.Error(t, err)
+
+	resp, err := get(tls.VersionTLS13)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+}
+
+func TestTLSConfigurator(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	configured := 0
+	s.TLSConfigurator = func(cfg *tls.Config) {
+		configured++
+		require.Equal(t, "localhost", cfg.ServerName)
+		require.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+		require.NotNil(t, cfg.GetCertificate)
+
+		cfg.MaxVersion = tls.VersionTLS12
+		cfg.CipherSuites = []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305}
+		cfg.CurvePreferences = []tls.CurveID{tls.X25519}
+	}
+
+	ts := httptest.NewUnstartedServer(s.routes())
+	ts.TLS = s.tlsConfig()
+	ts.StartTLS()
+	defer ts.Close()
+	require.Equal(t, 1, configured)
+
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+
+	get := func(cipherSuites []uint16) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      pool,
+			ServerName:   "localhost",
+			MinVersion:   tls.VersionTLS12,
+			CipherSuites: cipherSuites,
+		}}}
+		return client.Get(ts.URL + "/messages/identicons?publicKey=" + testPublicKey)
+	}
+
+	_, err = get([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256})
+	require.Error(t, err)
+
+	resp, err := get(nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, uint16(tls.VersionTLS12), resp.TLS.Version)
+	require.Equal(t, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305, resp.TLS.CipherSuite)
+}
+
+func TestRestartKeepsPort(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	certPem, err := PublicTLSCert()
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(certPem)))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:    pool,
+		ServerName: "localhost",
+		MinVersion: tls.VersionTLS12,
+	}}}
+
+	require.NoError(t, s.Start())
+	addr := waitListening(t, s)
+	port := s.Port
+
+	// The connection closed by the server on Stop is left in TIME_WAIT
+	resp, err := client.Get("https://" + addr.String() + "/messages/identicons?publicKey=" + testPublicKey)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, s.Stop())
+	for i := 0; i < 100 && s.Running(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.False(t, s.Running())
+
+	require.NoError(t, s.Start())
+	for i := 0; i < 100 && !s.Running(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, s.Running())
+	require.Equal(t, port, s.Port)
+	require.NoError(t, s.Stop())
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/eventbus"
+	"github.com/status-im/status-go/protocol/images"
+)
+
+// Default total size of the stickers kept in memory
+const defaultStickerCacheBytes = 16 * 1024 * 1024
+
+// Maximum size of a sticker served, larger content is rejected
+const maxStickerBytes = 2 * 1024 * 1024
+
+// Time allowed to download a sticker
+const stickerFetchTimeout = 30 * time.Second
+
+// StickerFetcher downloads the content of a sticker by hash
+type StickerFetcher func(ctx context.Context, hash string) ([]byte, error)
+
+type stickerHandler struct {
+	fetch        StickerFetcher
+	cache        *variantCache
+	logger       *zap.Logger
+	events       *eventbus.Bus
+	cacheControl string
+}
+
+func (s *stickerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, s.logger)
+
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		logger.Error("no sticker hash")
+		http.Error(w, "no hash", http.StatusBadRequest)
+		return
+	}
+
+	s.serve(w, r, logger, hash)
+}
+
+// serve responds with the sticker of hash, fetching it unless cached. It
+// also serves the images requested by hash on the images route
+func (s *stickerHandler) serve(w http.ResponseWriter, r *http.Request, logger *zap.Logger, hash string) {
+	sticker, ok := s.cache.Get(hash, "")
+	if !ok {
+		ctx, cancel := context.WithTimeout(r.Context(), stickerFetchTimeout)
+		defer cancel()
+
+		var err error
+		sticker, err = s.fetch(ctx, hash)
+		if err == nil && len(sticker) > maxStickerBytes {
+			err = errors.New("sticker too large")
+		}
+		if err != nil {
+			logger.Error("failed to fetch sticker", zap.String("hash", hash), zap.Error(err))
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+
+		s.cache.Add(hash, "", sticker)
+	}
+
+	mime, err := images.ImageMime(sticker)
+	if err != nil {
+		mime = http.DetectContentType(sticker)
+	}
+
+	// Stickers are content addressed, so they never change
+	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Cache-Control", s.cacheControl)
+
+	err = writePayload(w, r, sticker)
+	if errors.Is(err, context.Canceled) {
+		logger.Debug("client disconnected while writing sticker")
+		return
+	}
+	if err != nil {
+		logger.Error("failed to write sticker", zap.Error(err))
+		return
+	}
+
+	s.events.Publish(eventbus.MediaServed, eventbus.MediaServedPayload{Kind: "sticker", ID: hash})
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func TestStickerHandler(t *testing.T) {
+	sticker, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	fetches := 0
+	fetch := func(ctx context.Context, hash string) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+
+		switch hash {
+		case "sticker":
+			return sticker, nil
+		case "huge":
+			return make([]byte, maxStickerBytes+1), nil
+		default:
+			return nil, errors.New("gateway unavailable")
+		}
+	}
+
+	s, err := NewServer(nil, zap.NewNop(), WithStickerFetcher(fetch))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL + "/stickers?hash=sticker")
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		require.Contains(t, resp.Header.Get("Cache-Control"), "immutable")
+		require.Equal(t, sticker, body)
+	}
+	require.Equal(t, 1, fetches, "sticker wasn't cached")
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1}, s.CacheStats()["stickers"])
+
+	for query, status := range map[string]int{"": http.StatusBadRequest, "?hash=missing": http.StatusBadGateway, "?hash=huge": http.StatusBadGateway} {
+		resp, err := http.Get(ts.URL + "/stickers" + query)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, status, resp.StatusCode, query)
+	}
+}
+
+func TestStickerHandlerDisabled(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/stickers?hash=sticker")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestImageByHash(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	image, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+	sticker, err := identicon.Generate("0x04bb")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?)`, "1", image)
+	require.NoError(t, err)
+
+	fetches := 0
+	fetch := func(ctx context.Context, hash string) ([]byte, error) {
+		fetches++
+		if hash == "sticker" {
+			return sticker, nil
+		}
+		return nil, errors.New("gateway unavailable")
+	}
+
+	get := func(ts *httptest.Server, query string) (*http.Response, []byte) {
+		resp, err := http.Get(ts.URL + "/messages/images?" + query)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp, body
+	}
+
+	s, err := NewServer(db, zap.NewNop(), WithStickerFetcher(fetch))
+	require.NoError(t, err)
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, body := get(ts, "hash=sticker")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		require.Equal(t, s.cachePolicy["/stickers"], resp.Header.Get("Cache-Control"))
+		require.Equal(t, sticker, body)
+	}
+	require.Equal(t, 1, fetches, "image wasn't cached")
+
+	resp, body := get(ts, "messageId=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	require.Equal(t, image, body)
+
+	for query, status := range map[string]int{
+		"":                     http.StatusBadRequest,
+		"messageId=":           http.StatusBadRequest,
+		"messageId=1&hash=abc": http.StatusBadRequest,
+		"hash=missing":         http.StatusBadGateway,
+	} {
+		resp, _ := get(ts, query)
+		require.Equal(t, status, resp.StatusCode, query)
+	}
+
+	// Without a content addressed store, images can't be served by hash
+	s, err = NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+	ts2 := httptest.NewServer(s.routes())
+	defer ts2.Close()
+
+	resp, _ = get(ts2, "hash=sticker")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/status-im/status-go/eventbus"
+)
+
+// Default total size of the derived image variants kept in memory
+const defaultVariantCacheBytes = 32 * 1024 * 1024
+
+// variantKey identifies an image derived from a message payload, transform
+// names the applied transformation (e.g. "webp", "thumbnail:128")
+type variantKey struct {
+	messageID string
+	transform string
+}
+
+type variantEntry struct {
+	key     variantKey
+	payload []byte
+}
+
+// variantCache is an LRU cache of derived image variants bounded by the total
+// size of the payloads it holds, shared by all the transcoding features
+type variantCache struct {
+	// hits and misses are first to be 64-bit aligned for atomic operations
+	hits   uint64
+	misses uint64
+
+	mu       sync.Mutex
+	name     string
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[variantKey]*list.Element
+	events   *eventbus.Bus
+	// generation changes every time the cache is cleared
+	generation uint64
+}
+
+func newVariantCache(name string, maxBytes int64, events *eventbus.Bus) *variantCache {
+	return &variantCache{
+		name:     name,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[variantKey]*list.Element),
+		events:   events,
+	}
+}
+
+func (c *variantCache) Get(messageID, transform string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[variantKey{messageID, transform}]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+	c.order.MoveToFront(element)
+	return element.Value.(*variantEntry).payload, true
+}
+
+// Add stores the variant, evicting the least recently used ones until the
+// cache fits its budget. Payloads larger than the whole budget are not cached
+func (c *variantCache) Add(messageID, transform string, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(messageID, transform, payload)
+}
+
+// AddUnlessCleared stores the variant like Add, unless the cache was cleared
+// since generation was returned by Generation, as the variant may come from
+// stale data
+func (c *variantCache) AddUnlessCleared(generation uint64, messageID, transform string, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation == generation {
+		c.add(messageID, transform, payload)
+	}
+}
+
+func (c *variantCache) add(messageID, transform string, payload []byte) {
+	key := variantKey{messageID, transform}
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+
+	if int64(len(payload)) > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&variantEntry{key: key, payload: payload})
+	c.size += int64(len(payload))
+
+	c.evict()
+}
+
+// Generation returns the number of times the cache was cleared
+func (c *variantCache) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// Clear drops every variant
+func (c *variantCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[variantKey]*list.Element)
+	c.size = 0
+	c.generation++
+}
+
+// SetMaxBytes changes the budget of the cache, evicting variants if needed
+func (c *variantCache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes = maxBytes
+	c.evict()
+}
+
+func (c *variantCache) Stats() CacheStats {
+	return CacheStats{Hits: atomic.LoadUint64(&c.hits), Misses: atomic.LoadUint64(&c.misses)}
+}
+
+// Size returns the total size of the cached payloads
+func (c *variantCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Trim drops the least recently used variants until the cache holds at most
+// lowWater of its budget, which is left unchanged
+func (c *variantCache) Trim(lowWater float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictTo(int64(float64(c.maxBytes) * lowWater))
+}
+
+// evict drops the least recently used variants until the cache fits its budget
+func (c *variantCache) evict() {
+	c.evictTo(c.maxBytes)
+}
+
+func (c *variantCache) evictTo(maxBytes int64) {
+	for c.size > maxBytes && c.order.Len() > 0 {
+		evicted := c.order.Back()
+		c.removeElement(evicted)
+
+		key := evicted.Value.(*variantEntry).key
+		c.events.Publish(eventbus.CacheEvicted, eventbus.CacheEvictedPayload{Cache: c.name, Key: key.messageID + "/" + key.transform})
+	}
+}
+
+func (c *variantCache) removeElement(element *list.Element) {
+	entry := element.Value.(*variantEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.payload))
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/eventbus"
+)
+
+func TestVariantCacheBudget(t *testing.T) {
+	const budget = 1000
+	bus := eventbus.New()
+	evictions := bus.Subscribe(eventbus.CacheEvicted)
+	cache := newVariantCache("variants", budget, bus)
+
+	for i := 0; i < 20; i++ {
+		cache.Add(fmt.Sprintf("message-%d", i), "webp", make([]byte, 100))
+		require.True(t, cache.Size() <= budget, "cache size %d exceeds budget", cache.Size())
+	}
+
+	require.Equal(t, int64(budget), cache.Size())
+	require.Len(t, evictions, 10)
+
+	// Most recently added variants are kept, the oldest are evicted
+	_, ok := cache.Get("message-19", "webp")
+	require.True(t, ok)
+	_, ok = cache.Get("message-0", "webp")
+	require.False(t, ok)
+	_, ok = cache.Get("message-19", "thumbnail")
+	require.False(t, ok)
+
+	// Variants larger than the whole budget are not cached
+	cache.Add("huge", "webp", make([]byte, budget+1))
+	_, ok = cache.Get("huge", "webp")
+	require.False(t, ok)
+
+	cache.SetMaxBytes(250)
+	require.Equal(t, int64(200), cache.Size())
+}
+
+func TestVariantCacheReplacesVariant(t *testing.T) {
+	cache := newVariantCache("variants", 1000, nil)
+
+	cache.Add("message", "webp", make([]byte, 100))
+	cache.Add("message", "webp", make([]byte, 300))
+
+	payload, ok := cache.Get("message", "webp")
+	require.True(t, ok)
+	require.Len(t, payload, 300)
+	require.Equal(t, int64(300), cache.Size())
+}
+
+func TestVariantCacheClear(t *testing.T) {
+	cache := newVariantCache("variants", 1000, nil)
+
+	generation := cache.Generation()
+	cache.Add("message", "webp", make([]byte, 100))
+	cache.Clear()
+	require.Zero(t, cache.Size())
+	_, ok := cache.Get("message", "webp")
+	require.False(t, ok)
+
+	// Variants computed before clearing are not stored
+	cache.AddUnlessCleared(generation, "message", "webp", make([]byte, 100))
+	_, ok = cache.Get("message", "webp")
+	require.False(t, ok)
+
+	cache.AddUnlessCleared(cache.Generation(), "message", "webp", make([]byte, 100))
+	_, ok = cache.Get("message", "webp")
+	require.True(t, ok)
+}
+
+func TestWithVariantCacheSize(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop(), WithVariantCacheSize(10))
+	require.NoError(t, err)
+	require.Equal(t, int64(10), s.variants.maxBytes)
+
+	_, err = NewServer(nil, zap.NewNop(), WithVariantCacheSize(-1))
+	require.Error(t, err)
+}
+
+func TestVariantCacheStats(t *testing.T) {
+	s, err := NewServer(nil, zap.NewNop())
+	require.NoError(t, err)
+
+	stats := s.CacheStats()["variants"]
+	require.Equal(t, CacheStats{}, stats)
+	require.Zero(t, stats.HitRatio())
+
+	s.variants.Add("message", "webp", []byte("webp"))
+	for i := 0; i < 3; i++ {
+		_, ok := s.variants.Get("message", "webp")
+		require.True(t, ok)
+	}
+	_, ok := s.variants.Get("message", "thumbnail")
+	require.False(t, ok)
+
+	stats = s.CacheStats()["variants"]
+	require.Equal(t, CacheStats{Hits: 3, Misses: 1}, stats)
+	require.Equal(t, 0.75, stats.HitRatio())
+}
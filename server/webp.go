@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	// Register the formats transcoded to WebP
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Transform name of the WebP variants in the variant cache
+const webpTransform = "webp"
+
+// ImageEncoder encodes an image in a given format
+type ImageEncoder func(img image.Image) ([]byte, error)
+
+// WithWebPEncoder makes the images endpoint transcode JPEG and PNG images to
+// WebP with encode, for clients requesting it with format=webp or accepting
+// image/webp. The transcoded images are kept in the variant cache. No WebP
+// encoder is bundled, so images are served in their original format unless
+// one is provided
+func WithWebPEncoder(encode ImageEncoder) Option {
+	return func(s *Server) error {
+		if encode == nil {
+			return errors.New("nil WebP encoder")
+		}
+		s.webpEncoder = encode
+		return nil
+	}
+}
+
+// acceptsWebP tells whether the client asks for WebP images
+func acceptsWebP(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "webp" {
+		return true
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != "image/webp" {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// transcodesToWebP tells whether an image of type mime is served as WebP to
+// the client of r
+func (s *imageHandler) transcodesToWebP(r *http.Request, mime string) bool {
+	return s.webp != nil && (mime == "image/jpeg" || mime == "image/png") && acceptsWebP(r)
+}
+
+// webpVariant returns the image of the message transcoded to WebP, from the
+// variant cache if it was already transcoded
+func (s *imageHandler) webpVariant(messageID string, payload []byte) ([]byte, error) {
+	if variant, ok := s.variants.Get(messageID, webpTransform); ok {
+		return variant, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := s.webp(img)
+	if err != nil {
+		return nil, err
+	}
+
+	s.variants.Add(messageID, webpTransform, variant)
+	return variant, nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"image"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/status-im/status-go/protocol/identity/identicon"
+)
+
+func TestWebPTranscoding(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	png, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?)`, "1", png)
+	require.NoError(t, err)
+
+	_, err = NewServer(db, zap.NewNop(), WithWebPEncoder(nil))
+	require.Error(t, err)
+
+	var mu sync.Mutex
+	encodes := 0
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")
+	s, err := NewServer(db, zap.NewNop(), WithWebPEncoder(func(img image.Image) ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		encodes++
+		return webp, nil
+	}))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	get := func(query, accept string) (string, []byte) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/messages/images?messageId=1"+query, nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "Accept", resp.Header.Get("Vary"))
+		return resp.Header.Get("Content-Type"), body
+	}
+
+	for _, tc := range []struct {
+		query  string
+		accept string
+		webp   bool
+	}{
+		{"", "", false},
+		{"", "image/png,image/*;q=0.8", false},
+		{"", "image/webp;q=0", false},
+		{"", "image/avif,image/webp,*/*", true},
+		{"&format=webp", "", true},
+	} {
+		contentType, body := get(tc.query, tc.accept)
+		if tc.webp {
+			require.Equal(t, "image/webp", contentType, tc)
+			require.Equal(t, webp, body, tc)
+		} else {
+			require.Equal(t, "image/png", contentType, tc)
+			require.Equal(t, png, body, tc)
+		}
+	}
+	require.Equal(t, 1, encodes, "webp variant wasn't cached")
+
+	head := func(accept string) *http.Response {
+		req, err := http.NewRequest(http.MethodHead, ts.URL+"/messages/images?messageId=2", nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "Accept", resp.Header.Get("Vary"))
+		return resp
+	}
+
+	// HEAD reports the format served by GET, and the length of the WebP
+	// variant once it is transcoded
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?)`, "2", png)
+	require.NoError(t, err)
+
+	resp := head("")
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, int64(len(png)), resp.ContentLength)
+
+	resp = head("image/webp")
+	require.Equal(t, "image/webp", resp.Header.Get("Content-Type"))
+	require.Equal(t, int64(-1), resp.ContentLength)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/messages/images?messageId=2&format=webp", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	resp = head("image/webp")
+	require.Equal(t, "image/webp", resp.Header.Get("Content-Type"))
+	require.Equal(t, int64(len(webp)), resp.ContentLength)
+
+	// Images failing to be transcoded are served as is
+	failing, err := NewServer(db, zap.NewNop(), WithWebPEncoder(func(image.Image) ([]byte, error) {
+		return nil, errors.New("encoding failed")
+	}))
+	require.NoError(t, err)
+	fts := httptest.NewServer(failing.routes())
+	defer fts.Close()
+
+	resp, err = http.Get(fts.URL + "/messages/images?messageId=1&format=webp")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, png, body)
+}
+
+func TestWebPWithoutEncoder(t *testing.T) {
+	db, stop := setupTestDB(t)
+	defer stop()
+
+	png, err := identicon.Generate("0x04aa")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO user_messages (id, image_payload) VALUES (?, ?)`, "1", png)
+	require.NoError(t, err)
+
+	s, err := NewServer(db, zap.NewNop())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/messages/images?messageId=1&format=webp")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Empty(t, resp.Header.Get("Vary"))
+}
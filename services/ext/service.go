@@ -37,6 +37,7 @@ import (
 	"github.com/status-im/status-go/protocol/pushnotificationclient"
 	"github.com/status-im/status-go/protocol/pushnotificationserver"
 	"github.com/status-im/status-go/protocol/transport"
+	"github.com/status-im/status-go/server"
 	"github.com/status-im/status-go/services/ext/mailservers"
 	localnotifications "github.com/status-im/status-go/services/local-notifications"
 	mailserversDB "github.com/status-im/status-go/services/mailservers"
@@ -67,6 +68,8 @@ type Service struct {
 	accountsDB      *accounts.Database
 	multiAccountsDB *multiaccounts.Database
 	account         *multiaccounts.Account
+
+	mediaServerOptions []server.Option
 }
 
 // Make sure that Service implements node.Service interface.
@@ -147,6 +150,7 @@ func (s *Service) InitProtocol(nodeName string, identity *ecdsa.PrivateKey, db *
 	if err != nil {
 		return err
 	}
+	options = append(options, protocol.WithMediaServerOptions(s.mediaServerOptions...))
 
 	messenger, err := protocol.NewMessenger(
 		nodeName,
@@ -497,3 +501,18 @@ func (s *Service) ConnectionChanged(state connection.State) {
 func (s *Service) Messenger() *protocol.Messenger {
 	return s.messenger
 }
+
+// SetMediaServerOptions sets the options of the media server created by the
+// messenger. It must be called before InitProtocol.
+func (s *Service) SetMediaServerOptions(opts ...server.Option) {
+	s.mediaServerOptions = opts
+}
+
+// MediaServerURL returns the base URL of the messenger media server, or an
+// empty string if the messenger is not running.
+func (s *Service) MediaServerURL() string {
+	if s.messenger == nil {
+		return ""
+	}
+	return s.messenger.MediaServerURL()
+}
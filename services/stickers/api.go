@@ -2,26 +2,30 @@ package stickers
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
-	"io/ioutil"
+	"fmt"
+	"math"
 	"math/big"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/multiformats/go-multibase"
-	"github.com/wealdtech/go-multicodec"
-	"github.com/zenthangplus/goccm"
+	"golang.org/x/time/rate"
 	"olympos.io/encoding/edn"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/status-im/status-go/account"
 	"github.com/status-im/status-go/contracts"
-	"github.com/status-im/status-go/contracts/stickers"
 	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/eventbus"
 	"github.com/status-im/status-go/multiaccounts/accounts"
 	"github.com/status-im/status-go/params"
 	"github.com/status-im/status-go/rpc"
@@ -29,9 +33,55 @@ import (
 	"github.com/status-im/status-go/services/wallet/bigint"
 )
 
-const ipfsGateway = ".ipfs.infura-ipfs.io/"
+// Content is addressed as a subdomain of the gateway host, as in
+// https://<cid>.ipfs.infura-ipfs.io/
+const defaultGatewayBaseURL = "https://ipfs.infura-ipfs.io/"
 const maxConcurrentRequests = 3
 
+// Maximum number of pack data kept in memory
+const maxCachedPackData = 1024
+
+const defaultDecodeConcurrency = 8
+
+// Default limits for the rate at which contract calls are made, to stay under
+// the RPC provider limits during bulk operations
+const defaultContractCallsPerSecond = 10
+const defaultContractCallsBurst = 5
+
+var ErrPackNotFound = errors.New("sticker pack not found")
+var ErrTooManyPending = errors.New("too many pending sticker packs")
+var ErrInvalidPack = errors.New("invalid sticker pack")
+var ErrAlreadyPending = errors.New("sticker pack is already pending")
+var ErrContractUnavailable = errors.New("sticker contract unavailable")
+var ErrInvalidHash = errors.New("invalid sticker hash")
+
+// wrappedError matches both its sentinel and the underlying error with
+// errors.Is, so that callers can tell the kind of failure without losing its
+// detail
+type wrappedError struct {
+	sentinel error
+	err      error
+}
+
+func wrapError(sentinel error, err error) error {
+	return &wrappedError{sentinel: sentinel, err: err}
+}
+
+func (e *wrappedError) Error() string {
+	return e.sentinel.Error() + ": " + e.err.Error()
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+func (e *wrappedError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// UncategorizedPacks is the category of the packs without category metadata
+const UncategorizedPacks = "uncategorized"
+
 // ConnectionType constants
 type stickerStatus int
 
@@ -42,14 +92,96 @@ const (
 	statusPurchased
 )
 
+// stickerPackData is the pack information returned by the StickerType contract
+type stickerPackData = struct {
+	Category    [][4]byte
+	Owner       common.Address
+	Mintable    bool
+	Timestamp   *big.Int
+	Price       *big.Int
+	Contenthash []byte
+}
+
+// stickerTypeContract is the subset of the StickerType contract used by the API
+type stickerTypeContract interface {
+	GetPackData(opts *bind.CallOpts, packID *big.Int) (stickerPackData, error)
+	PackCount(opts *bind.CallOpts) (*big.Int, error)
+}
+
 type API struct {
 	contractMaker   *contracts.ContractMaker
+	stickerType     func(chainID uint64) (stickerTypeContract, error)
 	accountsManager *account.GethManager
 	accountsDB      *accounts.Database
 	rpcFiltersSrvc  *rpcfilters.Service
 	config          *params.NodeConfig
 	ctx             context.Context
 	client          *http.Client
+
+	// mu serializes read-modify-write cycles of the stickers settings
+	mu sync.Mutex
+
+	// metadata caches the pack metadata downloaded from IPFS by content hash,
+	// which never changes for a given hash, within a byte budget
+	metadata *byteCache
+
+	// packData caches the pack information last returned by the contracts,
+	// so that packs can be added to the pending packs while offline and
+	// prices aren't read again for every market listing
+	packDataLock sync.Mutex
+	packData     map[packKey]cachedPackData
+
+	// content keeps the preview, thumbnail and stickers content prefetched
+	// when installing packs, keyed by content hash, within a byte budget
+	content *byteCache
+
+	// gateway is the IPFS subdomain gateway used to build content URLs, see
+	// SetGatewayBaseURL
+	gatewayLock sync.RWMutex
+	gateway     *url.URL
+
+	// RateLimiter gates every sticker contract call, nil disables it
+	RateLimiter *rate.Limiter
+	// RetryPolicy applies to sticker contract calls failing with transient errors
+	RetryPolicy RetryPolicy
+	// MediaServerURL returns the base URL of the local media server serving
+	// the stickers on its /stickers route. Sticker URLs point at the IPFS
+	// gateway while it is nil or returns an empty string
+	MediaServerURL func() string
+	// DecodeConcurrency bounds the number of sticker hashes decoded in parallel
+	DecodeConcurrency int
+	// VerifyContent fetches the raw IPFS blocks of the pack metadata and
+	// sticker content, rejecting those not matching their hash and content
+	// spanning several blocks, which can't be verified
+	VerifyContent bool
+	// StickerContracts selects the sticker packs contract of a chain, chains
+	// without an entry use the legacy contracts
+	StickerContracts map[uint64]StickerContract
+	// Events receives the sticker packs lifecycle events, nil disables them
+	Events *eventbus.Bus
+	// MaxPendingPacks caps the number of pending sticker packs, zero disables
+	// the cap
+	MaxPendingPacks int
+	// ExchangeRates converts pack prices to fiat currencies, nil disables
+	// the conversion
+	ExchangeRates ExchangeRateSource
+	// PrefetchPending downloads the preview and thumbnail of the packs added to
+	// the pending packs in the background, so that they are served from the
+	// content cache once the packs are displayed
+	PrefetchPending bool
+	// RecoverCorruptedPending ignores the pending packs setting when it can't
+	// be decoded, instead of failing with ErrPendingCorrupted, after backing
+	// it up. The pending packs are lost, see RepairPending
+	RecoverCorruptedPending bool
+	// FetchTimeout bounds the time taken by every IPFS gateway fetch, zero
+	// disables the timeout
+	FetchTimeout time.Duration
+	// MaxContentBytes bounds the size of every content fetched from the IPFS
+	// gateway, stickers being bounded to 2 MiB anyway. Zero disables the limit
+	MaxContentBytes int64
+	// PriceCacheTTL is how long the pack prices read from the contracts are
+	// reused by PackPriceInFiat and the market listings, zero disables it
+	PriceCacheTTL time.Duration
 }
 
 type Sticker struct {
@@ -67,8 +199,24 @@ type StickerPack struct {
 	Preview   string         `json:"preview"`
 	Thumbnail string         `json:"thumbnail"`
 	Stickers  []Sticker      `json:"stickers"`
+	// Category and Tags are set when the pack metadata has them, packs
+	// without category are uncategorized
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
 
 	Status stickerStatus `json:"status,omitempty"`
+	// AddedAt is the unix time at which the pack was added to the pending
+	// packs, zero when unknown
+	AddedAt int64 `json:"addedAt,omitempty"`
+	// ChainID is the chain on which a pending pack is being bought, zero when
+	// unknown
+	ChainID uint64 `json:"chainID,omitempty"`
+	// Broken tells that some hashes of the pack couldn't be decoded, their
+	// URLs being left empty
+	Broken bool `json:"broken,omitempty"`
+	// Unverified tells that a pending pack was added from cached data while
+	// the contract was unreachable, see ReconcilePending
+	Unverified bool `json:"unverified,omitempty"`
 }
 
 type StickerPackCollection map[uint]StickerPack
@@ -83,16 +231,20 @@ type ednStickerPack struct {
 	Thumbnail string
 	Preview   string
 	Stickers  []ednSticker
+	Category  string
+	Tags      []string
 }
 type ednStickerPackInfo struct {
 	Meta ednStickerPack
 }
 
 func NewAPI(ctx context.Context, acc *accounts.Database, rpcClient *rpc.Client, accountsManager *account.GethManager, rpcFiltersSrvc *rpcfilters.Service, config *params.NodeConfig) *API {
-	return &API{
-		contractMaker: &contracts.ContractMaker{
-			RPCClient: rpcClient,
-		},
+	contractMaker := &contracts.ContractMaker{
+		RPCClient: rpcClient,
+	}
+
+	api := &API{
+		contractMaker:   contractMaker,
 		accountsManager: accountsManager,
 		accountsDB:      acc,
 		rpcFiltersSrvc:  rpcFiltersSrvc,
@@ -101,17 +253,171 @@ func NewAPI(ctx context.Context, acc *accounts.Database, rpcClient *rpc.Client,
 		client: &http.Client{
 			Timeout: time.Second * 5,
 		},
+		RateLimiter:       rate.NewLimiter(defaultContractCallsPerSecond, defaultContractCallsBurst),
+		RetryPolicy:       defaultRetryPolicy,
+		DecodeConcurrency: defaultDecodeConcurrency,
+		metadata:          newByteCache(defaultMetadataCacheBytes),
+		FetchTimeout:      defaultFetchTimeout,
+		MaxContentBytes:   defaultMaxContentBytes,
+		PriceCacheTTL:     defaultPriceCacheTTL,
+		packData:          make(map[packKey]cachedPackData),
+		content:           newByteCache(defaultContentCacheBytes),
 	}
+	api.stickerType = api.contractStickerType
+	api.gateway, _ = parseGatewayBaseURL(defaultGatewayBaseURL)
+	if config != nil {
+		api.StickerContracts = stickerContracts(config.StickersConfig)
+		if config.StickersConfig.GatewayBaseURL != "" {
+			err := api.SetGatewayBaseURL(config.StickersConfig.GatewayBaseURL)
+			if err != nil {
+				log.Error("ignoring the configured IPFS gateway", "error", err)
+			}
+		}
+	}
+
+	return api
+}
+
+// parseGatewayBaseURL validates the base URL of an IPFS subdomain gateway
+func parseGatewayBaseURL(rawURL string) (*url.URL, error) {
+	gateway, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IPFS gateway URL: %w", err)
+	}
+
+	if gateway.Scheme != "http" && gateway.Scheme != "https" {
+		return nil, fmt.Errorf("invalid IPFS gateway URL %q: scheme must be http or https", rawURL)
+	}
+
+	if gateway.Host == "" {
+		return nil, fmt.Errorf("invalid IPFS gateway URL %q: missing host", rawURL)
+	}
+
+	if gateway.Path == "" {
+		gateway.Path = "/"
+	}
+
+	return gateway, nil
+}
+
+// SetGatewayBaseURL validates the base URL of the IPFS subdomain gateway used
+// to build content URLs and uses it from now on
+func (api *API) SetGatewayBaseURL(rawURL string) error {
+	gateway, err := parseGatewayBaseURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	api.gatewayLock.Lock()
+	api.gateway = gateway
+	api.gatewayLock.Unlock()
+	return nil
+}
+
+// waitRateLimit blocks until a contract call is allowed by the rate limiter
+// or ctx is done
+func (api *API) waitRateLimit(ctx context.Context) error {
+	if api.RateLimiter == nil {
+		return nil
+	}
+	return api.RateLimiter.Wait(ctx)
 }
 
 func (api *API) Market(chainID uint64) ([]StickerPack, error) {
-	// TODO: eventually this should be changed to include pagination
-	accs, err := api.accountsDB.GetAccounts()
+	allStickerPacks, err := api.getContractPacks(chainID)
 	if err != nil {
 		return nil, err
 	}
 
-	allStickerPacks, err := api.getContractPacks(chainID)
+	purchasedPacks, err := api.purchasedPacks(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []StickerPack
+	for _, pack := range allStickerPacks {
+		packID := uint(pack.ID.Uint64())
+		_, isPurchased := purchasedPacks[packID]
+		if isPurchased {
+			pack.Status = statusPurchased
+		} else {
+			pack.Status = statusAvailable
+		}
+		result = append(result, pack)
+	}
+
+	return result, nil
+}
+
+// SearchPacks returns the sticker packs of the contract whose name or author
+// contains query, ignoring case, paginated with offset and limit like
+// MarketPage. An empty query matches every pack
+func (api *API) SearchPacks(ctx context.Context, chainID uint64, query string, offset int, limit int) ([]StickerPack, error) {
+	packs, err := api.MarketPage(ctx, chainID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	var result []StickerPack
+	for _, pack := range packs {
+		if strings.Contains(strings.ToLower(pack.Name), query) || strings.Contains(strings.ToLower(pack.Author), query) {
+			result = append(result, pack)
+		}
+	}
+
+	if offset < 0 || offset >= len(result) {
+		return nil, nil
+	}
+	result = result[offset:]
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// PacksByCategory returns the sticker packs of the market whose category or
+// one of whose tags is category, ignoring case. The packs without category
+// are returned for UncategorizedPacks
+func (api *API) PacksByCategory(chainID uint64, category string) ([]StickerPack, error) {
+	packs, err := api.Market(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []StickerPack
+	for _, pack := range packs {
+		if packInCategory(pack, category) {
+			result = append(result, pack)
+		}
+	}
+
+	return result, nil
+}
+
+func packInCategory(pack StickerPack, category string) bool {
+	if pack.Category == "" && strings.EqualFold(category, UncategorizedPacks) {
+		return true
+	}
+
+	if strings.EqualFold(pack.Category, category) {
+		return true
+	}
+
+	for _, tag := range pack.Tags {
+		if strings.EqualFold(tag, category) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// purchasedPacks returns the IDs of the packs purchased by any of the
+// accounts
+func (api *API) purchasedPacks(chainID uint64) (map[uint]struct{}, error) {
+	accs, err := api.accountsDB.GetAccounts()
 	if err != nil {
 		return nil, err
 	}
@@ -135,21 +441,301 @@ func (api *API) Market(chainID uint64) ([]StickerPack, error) {
 			}
 
 		case <-doneChan:
-			var result []StickerPack
-			for _, pack := range allStickerPacks {
-				packID := uint(pack.ID.Uint64())
-				_, isPurchased := purchasedPacks[packID]
-				if isPurchased {
-					pack.Status = statusPurchased
-				} else {
-					pack.Status = statusAvailable
-				}
-				result = append(result, pack)
+			return purchasedPacks, nil
+		}
+	}
+}
+
+// MarketPage returns the sticker packs of the contract ordered by pack ID and
+// paginated with offset and limit, unlike Market it includes the installed
+// and pending packs. A non positive limit returns all the remaining packs.
+// Packs that can't be retrieved are skipped
+func (api *API) MarketPage(ctx context.Context, chainID uint64, offset int, limit int) ([]StickerPack, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.waitRateLimit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	numPacks, err := stickerType.PackCount(&bind.CallOpts{Context: ctx, Pending: false})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := pageRange(numPacks, offset, limit)
+
+	installedPacks, err := api.installedStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	purchasedPacks, err := api.purchasedPacks(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var packsLock sync.Mutex
+	var packs []*StickerPack
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentRequests)
+	for i := start; i < end; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(packID *big.Int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			stickerPack, err := api.fetchMarketPack(ctx, chainID, stickerType, packID, true)
+			if err != nil {
+				log.Warn("Could not retrieve stickerpack data", "packID", packID, "error", err)
+				return
 			}
+			packsLock.Lock()
+			packs = append(packs, stickerPack)
+			packsLock.Unlock()
+		}(new(big.Int).SetUint64(i))
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
-			return result, nil
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].ID.Cmp(packs[j].ID.Int) < 0
+	})
+
+	var result []StickerPack
+	for _, stickerPack := range packs {
+		packID := uint(stickerPack.ID.Uint64())
+		if _, ok := installedPacks[packID]; ok {
+			stickerPack.Status = statusInstalled
+		} else if _, ok := pendingPacks[chainID][packID]; ok {
+			stickerPack.Status = statusPending
+		} else if _, ok := purchasedPacks[packID]; ok {
+			stickerPack.Status = statusPurchased
+		} else {
+			stickerPack.Status = statusAvailable
 		}
+		result = append(result, *stickerPack)
+	}
+
+	return result, nil
+}
+
+// pageRange returns the pack IDs [start, end) of the page at offset with
+// limit among the count packs of the contract. The IDs aren't materialized,
+// as count comes from the contract
+func pageRange(count *big.Int, offset int, limit int) (uint64, uint64) {
+	if offset < 0 || count.Sign() <= 0 {
+		return 0, 0
+	}
+
+	end := uint64(math.MaxUint64)
+	if count.IsUint64() {
+		end = count.Uint64()
 	}
+
+	start := uint64(offset)
+	if start >= end {
+		return 0, 0
+	}
+
+	if limit > 0 && uint64(limit) < end-start {
+		end = start + uint64(limit)
+	}
+
+	return start, end
+}
+
+// GetPack returns the data of a single sticker pack, with its hashes decoded
+// into URLs, without adding it to the pending or installed packs
+func (api *API) GetPack(chainID uint64, packID *bigint.BigInt) (*StickerPack, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	stickerPack, err := api.fetchPackData(chainID, stickerType, packID.Int, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validatePack(stickerPack)
+	if err != nil {
+		return nil, err
+	}
+
+	return stickerPack, nil
+}
+
+// Owned returns the sticker packs owned on chain by account, ordered by pack
+// ID and paginated with offset and limit. A non positive limit returns all the
+// remaining packs
+func (api *API) Owned(chainID uint64, account types.Address, offset int, limit int) ([]StickerPack, error) {
+	purchasedPackIDs, err := api.getPurchasedPackIDs(chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	uniquePackIDs := make(map[uint64]struct{})
+	var packIDs []*big.Int
+	for _, packID := range purchasedPackIDs {
+		if _, exists := uniquePackIDs[packID.Uint64()]; exists {
+			continue
+		}
+		uniquePackIDs[packID.Uint64()] = struct{}{}
+		packIDs = append(packIDs, packID)
+	}
+
+	sort.Slice(packIDs, func(i, j int) bool { return packIDs[i].Cmp(packIDs[j]) < 0 })
+
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []StickerPack
+	for _, packID := range paginate(packIDs, offset, limit) {
+		stickerPack, err := api.fetchPackData(chainID, stickerType, packID, true)
+		if err != nil {
+			return nil, err
+		}
+		stickerPack.Status = statusPurchased
+		result = append(result, *stickerPack)
+	}
+
+	return result, nil
+}
+
+// AllPacks returns the installed packs, the packs pending on chain and the
+// packs owned on chain by account, each with its status. A pack installed
+// and pending is returned as installed, and a pack pending and owned as
+// pending. Hashes that can't be decoded are left without URL and their pack
+// is flagged as broken
+func (api *API) AllPacks(ctx context.Context, chainID uint64, account types.Address) (StickerPackCollection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	installedPacks, err := api.installedStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	purchasedPackIDs, err := api.getPurchasedPackIDs(chainID, account)
+	if err != nil {
+		return nil, err
+	}
+
+	stickerPacks := make(StickerPackCollection)
+	for packID, stickerPack := range installedPacks {
+		stickerPack.Status = statusInstalled
+		stickerPacks[packID] = stickerPack
+	}
+
+	for packID, stickerPack := range pendingPacks[chainID] {
+		if _, exists := stickerPacks[packID]; exists {
+			continue
+		}
+		stickerPack.Status = statusPending
+		stickerPack.ChainID = chainID
+		stickerPacks[packID] = stickerPack
+	}
+
+	var stickerType stickerTypeContract
+	for _, packID := range purchasedPackIDs {
+		if _, exists := stickerPacks[uint(packID.Uint64())]; exists {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if stickerType == nil {
+			stickerType, err = api.newStickerType(chainID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		stickerPack, err := api.fetchPackData(chainID, stickerType, packID, false)
+		if err != nil {
+			return nil, err
+		}
+		stickerPack.Status = statusPurchased
+		stickerPacks[uint(packID.Uint64())] = *stickerPack
+	}
+
+	// The hashes of every pack are decoded at once, listed in the order of
+	// packIDs
+	packIDs := make([]uint, 0, len(stickerPacks))
+	var hashes []string
+	for packID, stickerPack := range stickerPacks {
+		packIDs = append(packIDs, packID)
+		hashes = append(hashes, stickerPack.Preview, stickerPack.Thumbnail)
+		for _, sticker := range stickerPack.Stickers {
+			hashes = append(hashes, sticker.Hash)
+		}
+	}
+
+	urls, errs := api.decodeHashes(hashes)
+	i := 0
+	for _, packID := range packIDs {
+		stickerPack := stickerPacks[packID]
+		for j := i; j < i+2+len(stickerPack.Stickers); j++ {
+			if errs[j] != nil {
+				log.Warn("failed to decode sticker pack hash", "packID", packID, "hash", hashes[j], "error", errs[j])
+				stickerPack.Broken = true
+			}
+		}
+
+		stickerPack.Preview = urls[i]
+		stickerPack.Thumbnail = urls[i+1]
+		i += 2
+		for k := range stickerPack.Stickers {
+			stickerPack.Stickers[k].URL = urls[i]
+			i++
+		}
+
+		stickerPacks[packID] = stickerPack
+	}
+
+	return stickerPacks, nil
+}
+
+func paginate(packIDs []*big.Int, offset int, limit int) []*big.Int {
+	if offset < 0 || offset >= len(packIDs) {
+		return nil
+	}
+
+	packIDs = packIDs[offset:]
+	if limit > 0 && limit < len(packIDs) {
+		packIDs = packIDs[:limit]
+	}
+
+	return packIDs
 }
 
 func (api *API) execTokenPackID(chainID uint64, tokenIDs []*big.Int, resultChan chan<- *big.Int, errChan chan<- error, doneChan chan<- struct{}) {
@@ -169,11 +755,18 @@ func (api *API) execTokenPackID(chainID uint64, tokenIDs []*big.Int, resultChan
 
 	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
 
-	c := goccm.New(maxConcurrentRequests)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentRequests)
 	for _, tokenID := range tokenIDs {
-		c.Wait()
+		slots <- struct{}{}
+		wg.Add(1)
 		go func(tokenID *big.Int) {
-			defer c.Done()
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := api.waitRateLimit(callOpts.Context); err != nil {
+				errChan <- err
+				return
+			}
 			packID, err := stickerPack.TokenPackId(callOpts, tokenID)
 			if err != nil {
 				errChan <- err
@@ -182,7 +775,7 @@ func (api *API) execTokenPackID(chainID uint64, tokenIDs []*big.Int, resultChan
 			resultChan <- packID
 		}(tokenID)
 	}
-	c.WaitAllDone()
+	wg.Wait()
 }
 
 func (api *API) getTokenPackIDs(chainID uint64, tokenIDs []*big.Int) ([]*big.Int, error) {
@@ -212,49 +805,45 @@ func (api *API) getTokenPackIDs(chainID uint64, tokenIDs []*big.Int) ([]*big.Int
 func (api *API) getPurchasedPackIDs(chainID uint64, account types.Address) ([]*big.Int, error) {
 	// TODO: this should be replaced in the future by something like TheGraph to reduce the number of requests to infura
 
-	stickerPack, err := api.contractMaker.NewStickerPack(chainID)
+	stickerType, err := api.newStickerType(chainID)
 	if err != nil {
 		return nil, err
 	}
 
-	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
+	if balances, ok := stickerType.(packBalanceContract); ok {
+		return api.getBalancePackIDs(stickerType, balances, account)
+	}
 
-	balance, err := stickerPack.BalanceOf(callOpts, common.Address(account))
+	stickerPack, err := api.contractMaker.NewStickerPack(chainID)
 	if err != nil {
 		return nil, err
 	}
 
-	tokenIDs, err := api.getTokenOwnerOfIndex(chainID, account, balance)
+	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
+
+	err = api.waitRateLimit(callOpts.Context)
 	if err != nil {
 		return nil, err
 	}
 
-	return api.getTokenPackIDs(chainID, tokenIDs)
-}
-
-func hashToURL(hash []byte) (string, error) {
-	// contract response includes a contenthash, which needs to be decoded to reveal
-	// an IPFS identifier. Once decoded, download the content from IPFS. This content
-	// is in EDN format, ie https://ipfs.infura.io/ipfs/QmWVVLwVKCwkVNjYJrRzQWREVvEk917PhbHYAUhA1gECTM
-	// and it also needs to be decoded in to a nim type
-
-	data, codec, err := multicodec.RemoveCodec(hash)
+	balance, err := stickerPack.BalanceOf(callOpts, common.Address(account))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	codecName, err := multicodec.Name(codec)
+	tokenIDs, err := api.getTokenOwnerOfIndex(chainID, account, balance)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if codecName != "ipfs-ns" {
-		return "", errors.New("codecName is not ipfs-ns")
-	}
+	return api.getTokenPackIDs(chainID, tokenIDs)
+}
 
-	thisCID, err := cid.Parse(data)
-	if err != nil {
-		return "", err
+// cidToURL returns the URL of the content on the configured IPFS gateway.
+// Subdomains being case insensitive, CIDv0 are converted to base32 CIDv1
+func (api *API) cidToURL(thisCID cid.Cid) (string, error) {
+	if thisCID.Version() == 0 {
+		thisCID = cid.NewCidV1(thisCID.Type(), thisCID.Hash())
 	}
 
 	str, err := thisCID.StringOfBase(multibase.Base32)
@@ -262,7 +851,11 @@ func hashToURL(hash []byte) (string, error) {
 		return "", err
 	}
 
-	return "https://" + str + ipfsGateway, nil
+	api.gatewayLock.RLock()
+	gateway := api.gateway
+	api.gatewayLock.RUnlock()
+
+	return gateway.Scheme + "://" + str + "." + gateway.Host + gateway.Path, nil
 }
 
 func (api *API) fetchStickerPacks(chainID uint64, resultChan chan<- *StickerPack, errChan chan<- error, doneChan chan<- struct{}) {
@@ -282,7 +875,7 @@ func (api *API) fetchStickerPacks(chainID uint64, resultChan chan<- *StickerPack
 		return
 	}
 
-	stickerType, err := api.contractMaker.NewStickerType(chainID)
+	stickerType, err := api.newStickerType(chainID)
 	if err != nil {
 		errChan <- err
 		return
@@ -290,6 +883,12 @@ func (api *API) fetchStickerPacks(chainID uint64, resultChan chan<- *StickerPack
 
 	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
 
+	err = api.waitRateLimit(callOpts.Context)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
 	numPacks, err := stickerType.PackCount(callOpts)
 	if err != nil {
 		errChan <- err
@@ -300,11 +899,14 @@ func (api *API) fetchStickerPacks(chainID uint64, resultChan chan<- *StickerPack
 		return
 	}
 
-	c := goccm.New(maxConcurrentRequests)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentRequests)
 	for i := uint64(0); i < numPacks.Uint64(); i++ {
-		c.Wait()
+		slots <- struct{}{}
+		wg.Add(1)
 		go func(i uint64) {
-			defer c.Done()
+			defer wg.Done()
+			defer func() { <-slots }()
 
 			packID := new(big.Int).SetUint64(i)
 
@@ -313,12 +915,12 @@ func (api *API) fetchStickerPacks(chainID uint64, resultChan chan<- *StickerPack
 				return // We already have the sticker pack data, no need to query it
 			}
 
-			_, exists = pendingPacks[uint(i)]
+			_, exists = pendingPacks[chainID][uint(i)]
 			if exists {
 				return // We already have the sticker pack data, no need to query it
 			}
 
-			stickerPack, err := api.fetchPackData(stickerType, packID, true)
+			stickerPack, err := api.fetchMarketPack(api.ctx, chainID, stickerType, packID, true)
 			if err != nil {
 				log.Warn("Could not retrieve stickerpack data", "packID", packID, "error", err)
 				return
@@ -328,29 +930,43 @@ func (api *API) fetchStickerPacks(chainID uint64, resultChan chan<- *StickerPack
 		}(i)
 	}
 
-	c.WaitAllDone()
+	wg.Wait()
 }
 
-func (api *API) fetchPackData(stickerType *stickers.StickerType, packID *big.Int, translateHashes bool) (*StickerPack, error) {
-	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
-
-	packData, err := stickerType.GetPackData(callOpts, packID)
+func (api *API) fetchPackData(chainID uint64, stickerType stickerTypeContract, packID *big.Int, translateHashes bool) (*StickerPack, error) {
+	packData, err := api.getPackData(stickerType, packID)
 	if err != nil {
 		return nil, err
 	}
 
-	packDetailsURL, err := hashToURL(packData.Contenthash)
+	return api.packFromData(chainID, packID, packData, translateHashes)
+}
+
+// fetchMarketPack is fetchPackData reusing the pack data cached with a price
+// read less than PriceCacheTTL ago
+func (api *API) fetchMarketPack(ctx context.Context, chainID uint64, stickerType stickerTypeContract, packID *big.Int, translateHashes bool) (*StickerPack, error) {
+	packData, err := api.currentPackData(ctx, chainID, stickerType, packID)
 	if err != nil {
 		return nil, err
 	}
 
+	return api.packFromData(chainID, packID, packData, translateHashes)
+}
+
+func (api *API) packFromData(chainID uint64, packID *big.Int, packData stickerPackData, translateHashes bool) (*StickerPack, error) {
+	if !packExists(packData) {
+		return nil, ErrPackNotFound
+	}
+
+	api.cachePackData(chainID, packID, packData)
+
 	stickerPack := &StickerPack{
 		ID:    &bigint.BigInt{Int: packID},
 		Owner: packData.Owner,
 		Price: &bigint.BigInt{Int: packData.Price},
 	}
 
-	err = api.downloadIPFSData(stickerPack, packDetailsURL, translateHashes)
+	err := api.downloadIPFSData(stickerPack, packData.Contenthash, translateHashes)
 	if err != nil {
 		return nil, err
 	}
@@ -358,34 +974,70 @@ func (api *API) fetchPackData(stickerType *stickers.StickerType, packID *big.Int
 	return stickerPack, nil
 }
 
-func (api *API) downloadIPFSData(stickerPack *StickerPack, packDetailsURL string, translateHashes bool) error {
-	// This can be improved by adding a cache using packDetailsURL as key
+// validatePack checks that the pack metadata has everything needed to display
+// the pack, as a broken metadata fetch can leave it without a preview, a
+// thumbnail or stickers
+func validatePack(stickerPack *StickerPack) error {
+	if stickerPack.Preview == "" {
+		return fmt.Errorf("%w: pack %s has no preview", ErrInvalidPack, stickerPack.ID)
+	}
+
+	if stickerPack.Thumbnail == "" {
+		return fmt.Errorf("%w: pack %s has no thumbnail", ErrInvalidPack, stickerPack.ID)
+	}
+
+	if len(stickerPack.Stickers) == 0 {
+		return fmt.Errorf("%w: pack %s has no stickers", ErrInvalidPack, stickerPack.ID)
+	}
+
+	for i, sticker := range stickerPack.Stickers {
+		if sticker.Hash == "" {
+			return fmt.Errorf("%w: sticker %d of pack %s has no hash", ErrInvalidPack, i, stickerPack.ID)
+		}
+	}
+
+	return nil
+}
+
+func (api *API) downloadIPFSData(stickerPack *StickerPack, contenthash []byte, translateHashes bool) error {
+	body, cached := api.cachedMetadata(contenthash)
+	if cached {
+		return api.populateStickerPackAttributes(stickerPack, body, translateHashes)
+	}
 
-	req, err := http.NewRequest(http.MethodGet, packDetailsURL, nil)
+	body, err := api.fetchIPFSData(contenthash)
 	if err != nil {
 		return err
 	}
 
-	resp, err := api.client.Do(req)
+	err = api.populateStickerPackAttributes(stickerPack, body, translateHashes)
 	if err != nil {
 		return err
 	}
 
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Error("failed to close the stickerpack request body", "err", err)
-		}
-	}()
+	api.metadata.Add(hex.EncodeToString(contenthash), body)
+
+	return nil
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+// cachedMetadata returns the pack metadata downloaded for contenthash, if any
+func (api *API) cachedMetadata(contenthash []byte) ([]byte, bool) {
+	return api.metadata.Get(hex.EncodeToString(contenthash))
+}
+
+// fetchIPFSData downloads the pack metadata stored at contenthash
+func (api *API) fetchIPFSData(contenthash []byte) ([]byte, error) {
+	// The contenthash is EIP-1577 encoded, the pack metadata it references
+	// is in EDN format and decoded by populateStickerPackAttributes
+	contentID, err := decodeContenthash(contenthash)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return populateStickerPackAttributes(stickerPack, body, translateHashes)
+	return api.downloadContent(api.ctx, contentID, "sticker pack metadata", 0)
 }
 
-func populateStickerPackAttributes(stickerPack *StickerPack, ednSource []byte, translateHashes bool) error {
+func (api *API) populateStickerPackAttributes(stickerPack *StickerPack, ednSource []byte, translateHashes bool) error {
 	var stickerpackIPFSInfo ednStickerPackInfo
 	err := edn.Unmarshal(ednSource, &stickerpackIPFSInfo)
 	if err != nil {
@@ -394,14 +1046,16 @@ func populateStickerPackAttributes(stickerPack *StickerPack, ednSource []byte, t
 
 	stickerPack.Author = stickerpackIPFSInfo.Meta.Author
 	stickerPack.Name = stickerpackIPFSInfo.Meta.Name
+	stickerPack.Category = stickerpackIPFSInfo.Meta.Category
+	stickerPack.Tags = stickerpackIPFSInfo.Meta.Tags
 
 	if translateHashes {
-		stickerPack.Preview, err = decodeStringHash(stickerpackIPFSInfo.Meta.Preview)
+		stickerPack.Preview, err = api.decodeStringHash(stickerpackIPFSInfo.Meta.Preview)
 		if err != nil {
 			return err
 		}
 
-		stickerPack.Thumbnail, err = decodeStringHash(stickerpackIPFSInfo.Meta.Thumbnail)
+		stickerPack.Thumbnail, err = api.decodeStringHash(stickerpackIPFSInfo.Meta.Thumbnail)
 		if err != nil {
 			return err
 		}
@@ -411,14 +1065,9 @@ func populateStickerPackAttributes(stickerPack *StickerPack, ednSource []byte, t
 	}
 
 	for _, s := range stickerpackIPFSInfo.Meta.Stickers {
-		url := ""
+		stickerURL := ""
 		if translateHashes {
-			hash, err := hexutil.Decode("0x" + s.Hash)
-			if err != nil {
-				return err
-			}
-
-			url, err = hashToURL(hash)
+			stickerURL, err = api.decodeStringHash(s.Hash)
 			if err != nil {
 				return err
 			}
@@ -426,7 +1075,7 @@ func populateStickerPackAttributes(stickerPack *StickerPack, ednSource []byte, t
 
 		stickerPack.Stickers = append(stickerPack.Stickers, Sticker{
 			PackID: stickerPack.ID,
-			URL:    url,
+			URL:    stickerURL,
 			Hash:   s.Hash,
 		})
 	}
@@ -434,18 +1083,46 @@ func populateStickerPackAttributes(stickerPack *StickerPack, ednSource []byte, t
 	return nil
 }
 
-func decodeStringHash(input string) (string, error) {
-	hash, err := hexutil.Decode("0x" + input)
+// decodeStringHash returns the URL the content of hash is displayed from,
+// served by the local media server when it is running
+func (api *API) decodeStringHash(input string) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+
+	var baseURL string
+	if api.MediaServerURL != nil {
+		baseURL = api.MediaServerURL()
+	}
+	if baseURL == "" {
+		return api.gatewayURL(input)
+	}
+
+	_, err := decodeHashCID(input)
 	if err != nil {
 		return "", err
 	}
 
-	url, err := hashToURL(hash)
+	return baseURL + "/stickers?hash=" + url.QueryEscape(input), nil
+}
+
+// gatewayURL returns the URL of the content of hash on the IPFS gateway
+func (api *API) gatewayURL(input string) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+
+	contentID, err := decodeHashCID(input)
 	if err != nil {
 		return "", err
 	}
 
-	return url, nil
+	contentURL, err := api.cidToURL(contentID)
+	if err != nil {
+		return "", wrapError(ErrInvalidHash, err)
+	}
+
+	return contentURL, nil
 }
 
 func (api *API) getContractPacks(chainID uint64) ([]StickerPack, error) {
@@ -482,11 +1159,14 @@ func (api *API) getAccountsPurchasedPack(chainID uint64, accs []accounts.Account
 		return
 	}
 
-	c := goccm.New(maxConcurrentRequests)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentRequests)
 	for _, account := range accs {
-		c.Wait()
+		slots <- struct{}{}
+		wg.Add(1)
 		go func(acc accounts.Account) {
-			defer c.Done()
+			defer wg.Done()
+			defer func() { <-slots }()
 			packs, err := api.getPurchasedPackIDs(chainID, acc.Address)
 			if err != nil {
 				errChan <- err
@@ -498,7 +1178,7 @@ func (api *API) getAccountsPurchasedPack(chainID uint64, accs []accounts.Account
 			}
 		}(account)
 	}
-	c.WaitAllDone()
+	wg.Wait()
 }
 
 func (api *API) execTokenOwnerOfIndex(chainID uint64, account types.Address, balance *big.Int, resultChan chan<- *big.Int, errChan chan<- error, doneChan chan<- struct{}) {
@@ -518,11 +1198,18 @@ func (api *API) execTokenOwnerOfIndex(chainID uint64, account types.Address, bal
 
 	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
 
-	c := goccm.New(maxConcurrentRequests)
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentRequests)
 	for i := uint64(0); i < balance.Uint64(); i++ {
-		c.Wait()
+		slots <- struct{}{}
+		wg.Add(1)
 		go func(i uint64) {
-			defer c.Done()
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := api.waitRateLimit(callOpts.Context); err != nil {
+				errChan <- err
+				return
+			}
 			tokenID, err := stickerPack.TokenOfOwnerByIndex(callOpts, common.Address(account), new(big.Int).SetUint64(i))
 			if err != nil {
 				errChan <- err
@@ -532,7 +1219,7 @@ func (api *API) execTokenOwnerOfIndex(chainID uint64, account types.Address, bal
 			resultChan <- tokenID
 		}(i)
 	}
-	c.WaitAllDone()
+	wg.Wait()
 }
 
 func (api *API) getTokenOwnerOfIndex(chainID uint64, account types.Address, balance *big.Int) ([]*big.Int, error) {
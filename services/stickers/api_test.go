@@ -0,0 +1,679 @@
+package stickers
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/go-multicodec"
+	"golang.org/x/time/rate"
+	"olympos.io/encoding/edn"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/status-im/status-go/appdatabase"
+	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/multiaccounts/accounts"
+	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/params"
+	"github.com/status-im/status-go/services/wallet/bigint"
+)
+
+const testChainID = 1
+
+type fakeStickerType struct {
+	mu    sync.Mutex
+	packs map[uint64]stickerPackData
+	err   error
+	calls int
+
+	// failures is the number of GetPackData calls failing with failureErr
+	failures   int
+	failureErr error
+}
+
+func (f *fakeStickerType) GetPackData(opts *bind.CallOpts, packID *big.Int) (stickerPackData, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return stickerPackData{}, f.err
+	}
+
+	if f.failures > 0 {
+		f.failures--
+		return stickerPackData{}, f.failureErr
+	}
+
+	return f.packs[packID.Uint64()], nil
+}
+
+func (f *fakeStickerType) PackCount(opts *bind.CallOpts) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return big.NewInt(int64(len(f.packs))), nil
+}
+
+func (f *fakeStickerType) setPack(packID uint64, data stickerPackData) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.packs[packID] = data
+}
+
+func (f *fakeStickerType) removePack(packID uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.packs, packID)
+}
+
+// fakeIPFS serves in-memory content for the subdomain gateway URLs built by
+// cidToURL, regardless of the gateway host. The raw blocks of the content
+// added with addUnixFS are served for format=raw requests
+type fakeIPFS struct {
+	mu       sync.Mutex
+	content  map[string][]byte
+	blocks   map[string][]byte
+	requests int
+}
+
+func (f *fakeIPFS) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Like http.Transport, give up on canceled requests
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	key := strings.SplitN(req.URL.Host, ".", 2)[0]
+	f.mu.Lock()
+	f.requests++
+	content, ok := f.content[key]
+	if block, isBlock := f.blocks[key]; isBlock && req.URL.Query().Get("format") == "raw" {
+		content, ok = block, true
+	}
+	f.mu.Unlock()
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(content)),
+		Request:    req,
+	}, nil
+}
+
+// add stores data and returns its content hash as found in pack metadata
+func (f *fakeIPFS) add(t *testing.T, data []byte) string {
+	return f.addWithCodec(t, data, cid.DagProtobuf)
+}
+
+func (f *fakeIPFS) addWithCodec(t *testing.T, data []byte, codec uint64) string {
+	mh, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	require.NoError(t, err)
+
+	contentID := cid.NewCidV1(codec, mh)
+
+	hash, err := multicodec.AddCodec("ipfs-ns", contentID.Bytes())
+	require.NoError(t, err)
+
+	key, err := contentID.StringOfBase(multibase.Base32)
+	require.NoError(t, err)
+
+	f.mu.Lock()
+	f.content[key] = data
+	f.mu.Unlock()
+
+	return hex.EncodeToString(hash)
+}
+
+// addUnixFS stores data as a single block UnixFS file, as added by IPFS
+// nodes, and returns its CIDv0 and its content hash as found in pack metadata
+func (f *fakeIPFS) addUnixFS(t *testing.T, data []byte) (cid.Cid, string) {
+	contentID := cid.NewCidV0(sum(t, unixfsBlock(data)))
+
+	hash, err := multicodec.AddCodec("ipfs-ns", contentID.Bytes())
+	require.NoError(t, err)
+
+	f.mu.Lock()
+	if f.blocks == nil {
+		f.blocks = make(map[string][]byte)
+	}
+	key := blockKey(t, contentID)
+	f.content[key] = data
+	f.blocks[key] = unixfsBlock(data)
+	f.mu.Unlock()
+
+	return contentID, hex.EncodeToString(hash)
+}
+
+// unixfsBlock encodes data as a dag-pb node holding a UnixFS file
+func unixfsBlock(data []byte) []byte {
+	file := []byte{1<<3 | wireVarint, unixfsFile}
+	file = protobufField(file, 2, data)
+	return protobufField(nil, 1, file)
+}
+
+// protobufField appends the bytes field number with value to message
+func protobufField(message []byte, number uint64, value []byte) []byte {
+	varint := make([]byte, binary.MaxVarintLen64)
+	message = append(message, varint[:binary.PutUvarint(varint, number<<3|wireBytes)]...)
+	message = append(message, varint[:binary.PutUvarint(varint, uint64(len(value)))]...)
+	return append(message, value...)
+}
+
+func sum(t *testing.T, data []byte) multihash.Multihash {
+	mh, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	require.NoError(t, err)
+	return mh
+}
+
+// blockKey returns the subdomain of the content on the gateway
+func blockKey(t *testing.T, contentID cid.Cid) string {
+	key, err := cid.NewCidV1(contentID.Type(), contentID.Hash()).StringOfBase(multibase.Base32)
+	require.NoError(t, err)
+	return key
+}
+
+type testSetup struct {
+	api      *API
+	contract *fakeStickerType
+	ipfs     *fakeIPFS
+}
+
+func setupTestAPI(t *testing.T) (*testSetup, func()) {
+	db, stop, err := appdatabase.SetupTestSQLDB("stickers-tests-")
+	require.NoError(t, err)
+
+	acc, err := accounts.NewDB(db)
+	require.NoError(t, err)
+
+	networks := json.RawMessage("{}")
+	err = acc.CreateSettings(settings.Settings{
+		Address:        types.HexToAddress("0xdC540f3745Ff2964AFC1171a5A0DD726d1F6B472"),
+		CurrentNetwork: "mainnet_rpc",
+		KeyUID:         "0x4e8129f3edfc004875be17bf468a784098a9f69b53c095be1f52deff286935ab",
+		Networks:       &networks,
+	}, params.NodeConfig{})
+	require.NoError(t, err)
+
+	contract := &fakeStickerType{packs: make(map[uint64]stickerPackData)}
+	ipfs := &fakeIPFS{content: make(map[string][]byte)}
+
+	api := NewAPI(context.Background(), acc, nil, nil, nil, nil)
+	api.stickerType = func(chainID uint64) (stickerTypeContract, error) {
+		return contract, nil
+	}
+	api.client = &http.Client{Transport: ipfs}
+	api.RateLimiter = nil
+	api.RetryPolicy = RetryPolicy{}
+
+	return &testSetup{api: api, contract: contract, ipfs: ipfs}, func() {
+		require.NoError(t, stop())
+	}
+}
+
+// publishPack uploads the pack metadata and its stickers to IPFS and
+// registers the pack on the contract
+func (s *testSetup) publishPack(t *testing.T, packID uint64, name string, price int64, numStickers int) {
+	meta := ednStickerPack{
+		Name:      name,
+		Author:    "author " + name,
+		Preview:   s.ipfs.add(t, []byte("preview "+name)),
+		Thumbnail: s.ipfs.add(t, []byte("thumbnail "+name)),
+	}
+
+	for i := 0; i < numStickers; i++ {
+		meta.Stickers = append(meta.Stickers, ednSticker{Hash: s.ipfs.add(t, []byte(fmt.Sprintf("sticker %s %d", name, i)))})
+	}
+
+	s.publishMeta(t, packID, price, meta)
+}
+
+// publishMeta uploads the given pack metadata to IPFS and registers the pack
+// on the contract
+func (s *testSetup) publishMeta(t *testing.T, packID uint64, price int64, meta ednStickerPack) {
+	data, err := edn.Marshal(ednStickerPackInfo{Meta: meta})
+	require.NoError(t, err)
+
+	contenthash, err := hex.DecodeString(s.ipfs.add(t, data))
+	require.NoError(t, err)
+
+	s.contract.setPack(packID, stickerPackData{
+		Owner:       common.HexToAddress("0x01"),
+		Price:       big.NewInt(price),
+		Contenthash: contenthash,
+	})
+}
+
+func packID(id uint64) *bigint.BigInt {
+	return &bigint.BigInt{Int: new(big.Int).SetUint64(id)}
+}
+
+func uint64s(ids []*bigint.BigInt) []uint64 {
+	var result []uint64
+	for _, id := range ids {
+		result = append(result, id.Uint64())
+	}
+	return result
+}
+
+func TestContractCallsRateLimit(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	const callsPerSecond = 20
+	const numPacks = 10
+
+	for id := uint64(1); id <= numPacks; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+	}
+
+	s.api.RateLimiter = rate.NewLimiter(callsPerSecond, 1)
+
+	start := time.Now()
+	for id := uint64(1); id <= numPacks; id++ {
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+	elapsed := time.Since(start)
+
+	require.Equal(t, numPacks, s.contract.calls)
+	// The first call is allowed immediately, the rest are spaced by the limiter
+	require.True(t, elapsed >= time.Duration(numPacks-1)*time.Second/callsPerSecond, "calls were not rate limited: %s", elapsed)
+}
+
+func TestGetPack(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 2)
+
+	pack, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, "pack", pack.Name)
+	require.Equal(t, big.NewInt(10), pack.Price.Int)
+	require.Contains(t, pack.Preview, ".ipfs.infura-ipfs.io/")
+	require.Contains(t, pack.Thumbnail, ".ipfs.infura-ipfs.io/")
+	require.Len(t, pack.Stickers, 2)
+	for _, sticker := range pack.Stickers {
+		require.Contains(t, sticker.URL, ".ipfs.infura-ipfs.io/")
+	}
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	_, err = s.api.GetPack(testChainID, packID(2))
+	require.True(t, errors.Is(err, ErrPackNotFound))
+}
+
+func TestInvalidPack(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	preview := s.ipfs.add(t, []byte("preview"))
+	thumbnail := s.ipfs.add(t, []byte("thumbnail"))
+	sticker := ednSticker{Hash: s.ipfs.add(t, []byte("sticker"))}
+
+	for id, meta := range map[uint64]ednStickerPack{
+		1: {Name: "no preview", Thumbnail: thumbnail, Stickers: []ednSticker{sticker}},
+		2: {Name: "no thumbnail", Preview: preview, Stickers: []ednSticker{sticker}},
+		3: {Name: "no stickers", Preview: preview, Thumbnail: thumbnail},
+		4: {Name: "empty sticker", Preview: preview, Thumbnail: thumbnail, Stickers: []ednSticker{sticker, {}}},
+	} {
+		s.publishMeta(t, id, 10, meta)
+
+		_, err := s.api.GetPack(testChainID, packID(id))
+		require.True(t, errors.Is(err, ErrInvalidPack), meta.Name)
+
+		err = s.api.AddPending(testChainID, packID(id))
+		require.True(t, errors.Is(err, ErrInvalidPack), meta.Name)
+	}
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestPaginate(t *testing.T) {
+	var ids []*big.Int
+	for i := int64(0); i < 5; i++ {
+		ids = append(ids, big.NewInt(i))
+	}
+
+	require.Equal(t, ids, paginate(ids, 0, 0))
+	require.Equal(t, ids[1:3], paginate(ids, 1, 2))
+	require.Equal(t, ids[3:], paginate(ids, 3, 10))
+	require.Empty(t, paginate(ids, 5, 2))
+	require.Empty(t, paginate(ids, -1, 2))
+}
+
+func TestPageRange(t *testing.T) {
+	for _, tc := range []struct {
+		count         *big.Int
+		offset, limit int
+		start, end    uint64
+	}{
+		{big.NewInt(5), 0, 0, 0, 5},
+		{big.NewInt(5), 1, 2, 1, 3},
+		{big.NewInt(5), 3, 10, 3, 5},
+		{big.NewInt(5), 5, 2, 0, 0},
+		{big.NewInt(5), -1, 2, 0, 0},
+		{big.NewInt(0), 0, 2, 0, 0},
+		// Counts read from a malicious contract
+		{big.NewInt(-1), 0, 2, 0, 0},
+		{new(big.Int).Lsh(big.NewInt(1), 200), 10, 2, 10, 12},
+		{new(big.Int).SetUint64(math.MaxUint64), math.MaxInt64, math.MaxInt64, math.MaxInt64, math.MaxUint64 - 1},
+	} {
+		start, end := pageRange(tc.count, tc.offset, tc.limit)
+		require.Equal(t, tc.start, start, tc)
+		require.Equal(t, tc.end, end, tc)
+	}
+}
+
+func TestMarketPageHonorsContext(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 0, "pack 0", 10, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.api.MarketPage(ctx, testChainID, 0, 0)
+	require.True(t, errors.Is(err, context.Canceled), err)
+}
+
+func TestCustomGateway(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 2)
+	require.NoError(t, s.api.SetGatewayBaseURL("http://localhost:8080"))
+
+	pack, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Len(t, pack.Stickers, 2)
+	for _, sticker := range pack.Stickers {
+		require.Regexp(t, `^http://[a-z0-9]+\.localhost:8080/$`, sticker.URL)
+	}
+	require.Regexp(t, `^http://[a-z0-9]+\.localhost:8080/$`, pack.Preview)
+
+	url, err := s.api.decodeStringHash("")
+	require.NoError(t, err)
+	require.Empty(t, url)
+
+	// Invalid gateways are rejected, keeping the current one
+	for _, gateway := range []string{"", "localhost:8080", "ftp://example.com", "https://"} {
+		require.Error(t, s.api.SetGatewayBaseURL(gateway), gateway)
+	}
+	pack, err = s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Regexp(t, `^http://[a-z0-9]+\.localhost:8080/$`, pack.Preview)
+
+	// The node config gateway is used when valid
+	api := NewAPI(context.Background(), nil, nil, nil, nil, &params.NodeConfig{StickersConfig: params.StickersConfig{GatewayBaseURL: "https://gateway.example"}})
+	contentURL, err := api.gatewayURL(pack.Stickers[0].Hash)
+	require.NoError(t, err)
+	require.Regexp(t, `^https://[a-z0-9]+\.gateway\.example/$`, contentURL)
+
+	api = NewAPI(context.Background(), nil, nil, nil, nil, &params.NodeConfig{StickersConfig: params.StickersConfig{GatewayBaseURL: "gateway.example"}})
+	contentURL, err = api.gatewayURL(pack.Stickers[0].Hash)
+	require.NoError(t, err)
+	require.Regexp(t, `^https://[a-z0-9]+\.ipfs\.infura-ipfs\.io/$`, contentURL)
+}
+
+func TestMarketPage(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(0); id < 5; id++ {
+		s.publishPack(t, id, fmt.Sprintf("pack %d", id), 10, 1)
+	}
+	require.NoError(t, s.api.Install(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(2)))
+
+	packs, err := s.api.MarketPage(context.Background(), testChainID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, packs, 5)
+	statuses := make([]stickerStatus, len(packs))
+	for i, pack := range packs {
+		require.Equal(t, uint64(i), pack.ID.Uint64())
+		require.Len(t, pack.Stickers, 1)
+		require.Contains(t, pack.Stickers[0].URL, ".ipfs.infura-ipfs.io/")
+		statuses[i] = pack.Status
+	}
+	require.Equal(t, []stickerStatus{statusAvailable, statusInstalled, statusPending, statusAvailable, statusAvailable}, statuses)
+
+	packs, err = s.api.MarketPage(context.Background(), testChainID, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, packs, 2)
+	require.Equal(t, "pack 1", packs[0].Name)
+	require.Equal(t, "pack 2", packs[1].Name)
+
+	packs, err = s.api.MarketPage(context.Background(), testChainID, 5, 2)
+	require.NoError(t, err)
+	require.Empty(t, packs)
+
+	// Packs that can't be retrieved are skipped
+	s.contract.setPack(3, stickerPackData{Owner: common.HexToAddress("0x01"), Price: big.NewInt(1), Contenthash: []byte{0x01}})
+	require.NoError(t, s.api.RefreshPrices(context.Background(), testChainID))
+	packs, err = s.api.MarketPage(context.Background(), testChainID, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, packs, 2)
+	require.Equal(t, uint64(2), packs[0].ID.Uint64())
+	require.Equal(t, uint64(4), packs[1].ID.Uint64())
+}
+
+func TestSearchPacks(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id, name := range []string{"Cats", "Dogs", "Cool cats", "Birds"} {
+		s.publishPack(t, uint64(id), name, 10, 1)
+	}
+
+	names := func(packs []StickerPack) []string {
+		var result []string
+		for _, pack := range packs {
+			require.Contains(t, pack.Preview, ".ipfs.infura-ipfs.io/")
+			result = append(result, pack.Name)
+		}
+		return result
+	}
+
+	packs, err := s.api.SearchPacks(context.Background(), testChainID, "CAT", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Cats", "Cool cats"}, names(packs))
+
+	// The author of each pack is "author <name>"
+	packs, err = s.api.SearchPacks(context.Background(), testChainID, "author do", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Dogs"}, names(packs))
+
+	packs, err = s.api.SearchPacks(context.Background(), testChainID, "", 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Dogs", "Cool cats"}, names(packs))
+
+	packs, err = s.api.SearchPacks(context.Background(), testChainID, "fish", 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, packs)
+
+	// The pack metadata is only downloaded once
+	require.Equal(t, 4, s.ipfs.requests)
+}
+
+func TestPacksByCategory(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id, pack := range []struct {
+		name     string
+		category string
+		tags     []string
+	}{
+		{"Cats", "Animals", []string{"cute"}},
+		{"Memes", "Humor", []string{"animals"}},
+		{"Plain", "", nil},
+	} {
+		s.publishMeta(t, uint64(id), 10, ednStickerPack{
+			Name:      pack.name,
+			Preview:   s.ipfs.add(t, []byte("preview "+pack.name)),
+			Thumbnail: s.ipfs.add(t, []byte("thumbnail "+pack.name)),
+			Stickers:  []ednSticker{{Hash: s.ipfs.add(t, []byte("sticker "+pack.name))}},
+			Category:  pack.category,
+			Tags:      pack.tags,
+		})
+	}
+
+	names := func(category string) []string {
+		packs, err := s.api.PacksByCategory(testChainID, category)
+		require.NoError(t, err)
+
+		var result []string
+		for _, pack := range packs {
+			result = append(result, pack.Name)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	require.Equal(t, []string{"Cats", "Memes"}, names("animals"))
+	require.Equal(t, []string{"Cats"}, names("CUTE"))
+	require.Equal(t, []string{"Plain"}, names(UncategorizedPacks))
+	require.Empty(t, names("sports"))
+
+	// The category is kept with the pending packs
+	require.NoError(t, s.api.AddPending(testChainID, packID(0)))
+	pending, err := s.api.Pending()
+	require.NoError(t, err)
+	require.Equal(t, "Animals", pending[testChainID][0].Category)
+	require.Equal(t, []string{"cute"}, pending[testChainID][0].Tags)
+}
+
+func TestAllPacks(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(0); id < 5; id++ {
+		s.publishPack(t, id, fmt.Sprintf("pack %d", id), 10, 2)
+	}
+	contract := setupERC1155(t, s)
+
+	require.NoError(t, s.api.Install(testChainID, packID(0)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(0)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(2)))
+
+	account := types.HexToAddress("0x02")
+	contract.balances[common.Address(account)] = map[uint64]int64{0: 1, 1: 1, 3: 1}
+
+	packs, err := s.api.AllPacks(context.Background(), testChainID, account)
+	require.NoError(t, err)
+	require.Len(t, packs, 4)
+
+	require.Equal(t, statusInstalled, packs[0].Status)
+	require.Equal(t, statusPending, packs[1].Status)
+	require.Equal(t, statusPending, packs[2].Status)
+	require.Equal(t, uint64(testChainID), packs[2].ChainID)
+	require.Equal(t, statusPurchased, packs[3].Status)
+	require.NotContains(t, packs, uint(4))
+
+	for packID, pack := range packs {
+		require.Equal(t, fmt.Sprintf("pack %d", packID), pack.Name)
+		require.True(t, strings.HasPrefix(pack.Preview, "https://"), pack.Preview)
+		require.True(t, strings.HasPrefix(pack.Thumbnail, "https://"), pack.Thumbnail)
+		for _, sticker := range pack.Stickers {
+			require.True(t, strings.HasPrefix(sticker.URL, "https://"), sticker.URL)
+		}
+		require.False(t, pack.Broken)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.api.AllPacks(ctx, testChainID, account)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestSentinelErrors(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.True(t, errors.Is(s.api.AddPending(testChainID, packID(1)), ErrAlreadyPending))
+
+	require.True(t, errors.Is(s.api.AddPending(testChainID, packID(2)), ErrPackNotFound))
+
+	// The underlying error is kept
+	s.contract.failures = 1
+	s.contract.failureErr = errConnRefused
+	_, err := s.api.GetPack(testChainID, packID(1))
+	require.True(t, errors.Is(err, ErrContractUnavailable))
+	require.True(t, errors.Is(err, syscall.ECONNREFUSED))
+
+	s.api.stickerType = func(chainID uint64) (stickerTypeContract, error) {
+		return nil, errors.New("no RPC client")
+	}
+	_, err = s.api.GetPack(testChainID, packID(1))
+	require.True(t, errors.Is(err, ErrContractUnavailable))
+	require.Contains(t, err.Error(), "no RPC client")
+
+	_, err = s.api.decodeStringHash("not a hash")
+	require.True(t, errors.Is(err, ErrInvalidHash))
+	require.True(t, errors.Is(ValidateHash("e301"), ErrInvalidHash))
+}
+
+func TestMarketAllPending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(0); id < 10; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+
+	// No pack is fetched as every pack is pending, which must not block.
+	// Blocking depends on scheduling, so the market is listed repeatedly
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			_, err := s.api.Market(testChainID)
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Market blocked with every pack pending")
+	}
+}
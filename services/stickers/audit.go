@@ -0,0 +1,122 @@
+package stickers
+
+import (
+	"sort"
+
+	"github.com/status-im/status-go/services/wallet/bigint"
+)
+
+type PriceChange struct {
+	PackID   *bigint.BigInt `json:"packID"`
+	OldPrice *bigint.BigInt `json:"oldPrice"`
+	NewPrice *bigint.BigInt `json:"newPrice"`
+}
+
+// PackError is a pack that couldn't be checked, with the reason why
+type PackError struct {
+	PackID *bigint.BigInt `json:"packID"`
+	Error  string         `json:"error"`
+}
+
+// AuditReport describes how the pending sticker packs differ from what is
+// currently published on chain
+type AuditReport struct {
+	Healthy            []*bigint.BigInt `json:"healthy"`
+	Missing            []*bigint.BigInt `json:"missing"`
+	MetadataMismatches []*bigint.BigInt `json:"metadataMismatches"`
+	PriceChanges       []PriceChange    `json:"priceChanges"`
+	Unreachable        []PackError      `json:"unreachable"`
+}
+
+// AuditPending cross-checks every sticker pack pending on the chain against
+// the sticker contract, reporting packs that no longer exist, whose metadata
+// changed or whose price changed since they were added. Packs whose contract
+// data or metadata can't be fetched are reported as unreachable
+func (api *API) AuditPending(chainID uint64) (AuditReport, error) {
+	report := AuditReport{}
+
+	pendingByChain, err := api.pendingStickerPacks()
+	if err != nil {
+		return report, err
+	}
+	pendingPacks := pendingByChain[chainID]
+
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return report, err
+	}
+
+	packIDs := make([]uint, 0, len(pendingPacks))
+	for packID := range pendingPacks {
+		packIDs = append(packIDs, packID)
+	}
+	sort.Slice(packIDs, func(i, j int) bool { return packIDs[i] < packIDs[j] })
+
+	for _, packID := range packIDs {
+		pending := pendingPacks[packID]
+
+		packData, err := api.getPackData(stickerType, pending.ID.Int)
+		if err != nil {
+			report.Unreachable = append(report.Unreachable, PackError{PackID: pending.ID, Error: err.Error()})
+			continue
+		}
+
+		if !packExists(packData) {
+			report.Missing = append(report.Missing, pending.ID)
+			continue
+		}
+
+		onchain := &StickerPack{ID: pending.ID}
+		err = api.downloadIPFSData(onchain, packData.Contenthash, false)
+		if err != nil {
+			report.Unreachable = append(report.Unreachable, PackError{PackID: pending.ID, Error: err.Error()})
+			continue
+		}
+
+		healthy := true
+
+		if pending.Price == nil || pending.Price.Cmp(packData.Price) != 0 {
+			healthy = false
+			report.PriceChanges = append(report.PriceChanges, PriceChange{
+				PackID:   pending.ID,
+				OldPrice: pending.Price,
+				NewPrice: &bigint.BigInt{Int: packData.Price},
+			})
+		}
+
+		if !sameMetadata(pending, *onchain) {
+			healthy = false
+			report.MetadataMismatches = append(report.MetadataMismatches, pending.ID)
+		}
+
+		if healthy {
+			report.Healthy = append(report.Healthy, pending.ID)
+		}
+	}
+
+	return report, nil
+}
+
+// packExists reports whether the contract returned data for an actual pack,
+// unknown pack IDs are returned as zeroed values
+func packExists(packData stickerPackData) bool {
+	return len(packData.Contenthash) > 0
+}
+
+func sameMetadata(a, b StickerPack) bool {
+	if a.Name != b.Name || a.Author != b.Author || a.Preview != b.Preview || a.Thumbnail != b.Thumbnail {
+		return false
+	}
+
+	if len(a.Stickers) != len(b.Stickers) {
+		return false
+	}
+
+	for i := range a.Stickers {
+		if a.Stickers[i].Hash != b.Stickers[i].Hash {
+			return false
+		}
+	}
+
+	return true
+}
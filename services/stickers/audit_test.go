@@ -0,0 +1,47 @@
+package stickers
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAuditPending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id, name := range map[uint64]string{1: "healthy", 2: "missing", 3: "repriced", 4: "changed"} {
+		s.publishPack(t, id, name, 10, 3)
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+
+	s.publishPack(t, 5, "unreachable", 10, 3)
+	require.NoError(t, s.api.AddPending(testChainID, packID(5)))
+
+	s.contract.removePack(2)
+	s.publishPack(t, 3, "repriced", 20, 3)
+	s.publishPack(t, 4, "changed again", 10, 3)
+
+	// The metadata of pack 5 moves to content the gateway doesn't have
+	unreachable, err := hex.DecodeString((&fakeIPFS{content: make(map[string][]byte)}).add(t, []byte("unreachable")))
+	require.NoError(t, err)
+	s.contract.setPack(5, stickerPackData{Owner: common.HexToAddress("0x01"), Price: big.NewInt(10), Contenthash: unreachable})
+
+	report, err := s.api.AuditPending(testChainID)
+	require.NoError(t, err)
+
+	require.Equal(t, []uint64{1}, uint64s(report.Healthy))
+	require.Equal(t, []uint64{2}, uint64s(report.Missing))
+	require.Equal(t, []uint64{4}, uint64s(report.MetadataMismatches))
+	require.Len(t, report.PriceChanges, 1)
+	require.Equal(t, uint64(3), report.PriceChanges[0].PackID.Uint64())
+	require.Equal(t, big.NewInt(10), report.PriceChanges[0].OldPrice.Int)
+	require.Equal(t, big.NewInt(20), report.PriceChanges[0].NewPrice.Int)
+	require.Len(t, report.Unreachable, 1)
+	require.Equal(t, uint64(5), report.Unreachable[0].PackID.Uint64())
+	require.NotEmpty(t, report.Unreachable[0].Error)
+}
@@ -0,0 +1,130 @@
+package stickers
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/status-im/status-go/server"
+)
+
+// Default total size of the sticker content kept in memory
+const defaultContentCacheBytes = 32 * 1024 * 1024
+
+// Default total size of the pack metadata kept in memory
+const defaultMetadataCacheBytes = 8 * 1024 * 1024
+
+type cacheEntry struct {
+	key  string
+	data []byte
+}
+
+// byteCache is an LRU cache bounded by the total size of the values it holds
+type byteCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+
+	hits   uint64
+	misses uint64
+}
+
+func newByteCache(maxBytes int64) *byteCache {
+	return &byteCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *byteCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddUint64(&c.hits, 1)
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).data, true
+}
+
+// Add stores the value, evicting the least recently used ones until the cache
+// fits its budget. Values larger than the whole budget are not cached
+func (c *byteCache) Add(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+
+	c.evictTo(c.maxBytes)
+}
+
+func (c *byteCache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// SetMaxBytes changes the budget of the cache, evicting values if needed
+func (c *byteCache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes = maxBytes
+	c.evictTo(maxBytes)
+}
+
+// Trim drops the least recently used values until the cache holds at most
+// lowWater of its budget, which is left unchanged
+func (c *byteCache) Trim(lowWater float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictTo(int64(float64(c.maxBytes) * lowWater))
+}
+
+// Size returns the total size of the cached values
+func (c *byteCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Stats returns the lookup counters of the cache
+func (c *byteCache) Stats() server.CacheStats {
+	return server.CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+func (c *byteCache) evictTo(maxBytes int64) {
+	for c.size > maxBytes && c.order.Len() > 0 {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *byteCache) removeElement(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.data))
+}
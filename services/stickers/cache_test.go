@@ -0,0 +1,76 @@
+package stickers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentCacheBounded(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	service := &Service{api: s.api}
+	service.SetContentCacheBytes(100)
+
+	for _, hash := range []string{"a", "b", "c", "d"} {
+		s.api.cacheContent(hash, make([]byte, 30))
+	}
+	require.Equal(t, int64(90), s.api.content.Size())
+
+	// The least recently used content is evicted
+	_, ok := s.api.cachedContent("a")
+	require.False(t, ok)
+	_, ok = s.api.cachedContent("b")
+	require.True(t, ok)
+
+	// Content larger than the whole budget isn't cached
+	s.api.cacheContent("large", make([]byte, 101))
+	_, ok = s.api.cachedContent("large")
+	require.False(t, ok)
+
+	service.TrimCaches(0.5)
+	require.Equal(t, int64(30), s.api.content.Size())
+	_, ok = s.api.cachedContent("b")
+	require.True(t, ok, "the most recently used content is kept")
+}
+
+func TestMetadataCacheBounded(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(1); id <= 3; id++ {
+		s.publishPack(t, id, fmt.Sprintf("pack %d", id), 10, 1)
+	}
+
+	service := &Service{api: s.api}
+	_, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	size := s.api.metadata.Size()
+	require.NotZero(t, size)
+
+	// Only the metadata of the most recently fetched pack fits
+	service.SetMetadataCacheBytes(size + size/2)
+	for _, id := range []uint64{2, 3} {
+		_, err = s.api.GetPack(testChainID, packID(id))
+		require.NoError(t, err)
+	}
+	require.LessOrEqual(t, s.api.metadata.Size(), size+size/2)
+
+	before := s.ipfs.requests
+	_, err = s.api.GetPack(testChainID, packID(3))
+	require.NoError(t, err)
+	require.Equal(t, before, s.ipfs.requests, "the metadata of pack 3 wasn't cached")
+
+	_, err = s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, before+1, s.ipfs.requests, "the metadata of pack 1 wasn't evicted")
+
+	stats := service.CacheStats()["sticker-metadata"]
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(4), stats.Misses)
+
+	service.TrimCaches(0)
+	require.Zero(t, s.api.metadata.Size())
+}
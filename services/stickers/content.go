@@ -0,0 +1,104 @@
+package stickers
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/services/wallet/bigint"
+)
+
+// stickerContent returns the prefetched content of a hash, or downloads it
+// when it wasn't prefetched
+func (api *API) stickerContent(ctx context.Context, hash string) ([]byte, error) {
+	if data, ok := api.cachedContent(hash); ok {
+		return data, nil
+	}
+
+	return api.downloadSticker(ctx, hash)
+}
+
+func (api *API) cachedContent(hash string) ([]byte, bool) {
+	return api.content.Get(hash)
+}
+
+func (api *API) cacheContent(hash string, data []byte) {
+	api.content.Add(hash, data)
+}
+
+// trimCaches drops the least recently used cached content and metadata until
+// the caches hold at most lowWater of their budget
+func (api *API) trimCaches(lowWater float64) {
+	api.content.Trim(lowWater)
+	api.metadata.Trim(lowWater)
+}
+
+// prefetchContent downloads the content of the pack hashes that isn't cached
+// yet and caches it. Failures are only logged, the content being downloaded
+// again when requested
+func (api *API) prefetchContent(packID *bigint.BigInt, hashes []string) {
+	for _, hash := range hashes {
+		if _, ok := api.cachedContent(hash); ok {
+			continue
+		}
+
+		data, err := api.downloadSticker(api.ctx, hash)
+		if err != nil {
+			log.Warn("failed to prefetch sticker pack content", "packID", packID, "hash", hash, "err", err)
+			continue
+		}
+
+		api.cacheContent(hash, data)
+	}
+}
+
+// evictPackContent drops the cached content of removed packs, except the
+// content still referenced by installed or pending packs. Failures are only
+// logged, the content staying cached until restart
+func (api *API) evictPackContent(removed ...StickerPack) {
+	installedPacks, err := api.installedStickerPacks()
+	if err != nil {
+		log.Warn("failed to evict removed sticker packs content", "err", err)
+		return
+	}
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		log.Warn("failed to evict removed sticker packs content", "err", err)
+		return
+	}
+
+	referenced := make(map[string]bool)
+	reference := func(stickerPack StickerPack) {
+		for _, entry := range packContents(&stickerPack) {
+			referenced[entry.Hash] = true
+		}
+	}
+	for _, stickerPack := range installedPacks {
+		reference(stickerPack)
+	}
+	for _, packs := range pendingPacks {
+		for _, stickerPack := range packs {
+			reference(stickerPack)
+		}
+	}
+
+	for _, stickerPack := range removed {
+		for _, entry := range packContents(&stickerPack) {
+			if !referenced[entry.Hash] {
+				api.content.Remove(entry.Hash)
+			}
+		}
+	}
+}
+
+// evictPendingContent drops the cached content of removed pending packs like
+// evictPackContent
+func (api *API) evictPendingContent(removed StickerPacksByChain) {
+	var packs []StickerPack
+	for _, chainPacks := range removed {
+		for _, stickerPack := range chainPacks {
+			packs = append(packs, stickerPack)
+		}
+	}
+	api.evictPackContent(packs...)
+}
@@ -0,0 +1,42 @@
+package stickers
+
+// DeduplicatedPacks holds sticker packs whose stickers reference a shared
+// hash to URL table, so stickers that are identical across packs are only
+// resolved and transferred once
+type DeduplicatedPacks struct {
+	URLs  map[string]string   `json:"urls"`
+	Packs StickerPacksByChain `json:"packs"`
+}
+
+// PendingDeduplicated returns the pending sticker packs with their stickers
+// deduplicated by content hash
+func (api *API) PendingDeduplicated() (*DeduplicatedPacks, error) {
+	stickerPacks, err := api.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	return deduplicateStickers(stickerPacks), nil
+}
+
+func deduplicateStickers(stickerPacks StickerPacksByChain) *DeduplicatedPacks {
+	result := &DeduplicatedPacks{
+		URLs:  make(map[string]string),
+		Packs: stickerPacks,
+	}
+
+	for _, chainPacks := range stickerPacks {
+		for packID, stickerPack := range chainPacks {
+			for i, sticker := range stickerPack.Stickers {
+				if _, exists := result.URLs[sticker.Hash]; !exists {
+					result.URLs[sticker.Hash] = sticker.URL
+				}
+				sticker.URL = ""
+				stickerPack.Stickers[i] = sticker
+			}
+			chainPacks[packID] = stickerPack
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,25 @@
+package stickers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicateStickers(t *testing.T) {
+	shared := Sticker{Hash: "shared", URL: "https://shared"}
+
+	result := deduplicateStickers(StickerPacksByChain{
+		1: {1: {ID: packID(1), Stickers: []Sticker{shared, {Hash: "a", URL: "https://a"}}}},
+		3: {1: {ID: packID(1), Stickers: []Sticker{{Hash: "b", URL: "https://b"}, shared}}},
+	})
+
+	require.Equal(t, map[string]string{
+		"shared": "https://shared",
+		"a":      "https://a",
+		"b":      "https://b",
+	}, result.URLs)
+
+	require.Equal(t, []Sticker{{Hash: "shared"}, {Hash: "a"}}, result.Packs[1][1].Stickers)
+	require.Equal(t, []Sticker{{Hash: "b"}, {Hash: "shared"}}, result.Packs[3][1].Stickers)
+}
@@ -0,0 +1,195 @@
+package stickers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/wealdtech/go-multicodec"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/contracts/stickers"
+	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/params"
+)
+
+type StickerContractType string
+
+const (
+	// StickerContractLegacy is the StickerType/StickerPack contracts pair
+	StickerContractLegacy StickerContractType = "legacy"
+	// StickerContractERC1155 is a multi-token contract where each pack is a token ID
+	StickerContractERC1155 StickerContractType = "erc1155"
+)
+
+// StickerContract describes the sticker packs contract deployed on a chain
+type StickerContract struct {
+	Type    StickerContractType `json:"type"`
+	Address common.Address      `json:"address"`
+}
+
+// stickerContracts reads the sticker contracts configured per chain. Invalid
+// addresses are left empty, failing the calls to the contract of their chain
+func stickerContracts(config params.StickersConfig) map[uint64]StickerContract {
+	contracts := make(map[uint64]StickerContract, len(config.Contracts))
+	for chainID, contract := range config.Contracts {
+		var address common.Address
+		if common.IsHexAddress(contract.Address) {
+			address = common.HexToAddress(contract.Address)
+		} else {
+			log.Warn("invalid sticker contract address", "chainID", chainID, "address", contract.Address)
+		}
+		contracts[chainID] = StickerContract{Type: StickerContractType(contract.Type), Address: address}
+	}
+	return contracts
+}
+
+// erc1155Contract is the subset of the ERC-1155 sticker packs contract used by
+// the API
+type erc1155Contract interface {
+	URI(opts *bind.CallOpts, id *big.Int) (string, error)
+	PackData(opts *bind.CallOpts, id *big.Int) (stickers.StickerPack1155Data, error)
+	PackCount(opts *bind.CallOpts) (*big.Int, error)
+	BalanceOf(opts *bind.CallOpts, account common.Address, id *big.Int) (*big.Int, error)
+}
+
+// packBalanceContract is implemented by sticker contracts tracking ownership
+// as a balance per pack
+type packBalanceContract interface {
+	BalanceOf(opts *bind.CallOpts, account common.Address, id *big.Int) (*big.Int, error)
+}
+
+// erc1155StickerType adapts an ERC-1155 sticker packs contract to the shape of
+// the StickerType contract
+type erc1155StickerType struct {
+	contract erc1155Contract
+}
+
+func (c *erc1155StickerType) GetPackData(opts *bind.CallOpts, packID *big.Int) (stickerPackData, error) {
+	data, err := c.contract.PackData(opts, packID)
+	if err != nil {
+		return stickerPackData{}, err
+	}
+
+	uri, err := c.contract.URI(opts, packID)
+	if err != nil {
+		return stickerPackData{}, err
+	}
+
+	// Unknown packs have no content
+	if uri == "" {
+		return stickerPackData{}, nil
+	}
+
+	contenthash, err := uriToContenthash(uri, packID)
+	if err != nil {
+		return stickerPackData{}, err
+	}
+
+	return stickerPackData{
+		Owner:       data.Owner,
+		Mintable:    data.Mintable,
+		Timestamp:   data.Timestamp,
+		Price:       data.Price,
+		Contenthash: contenthash,
+	}, nil
+}
+
+func (c *erc1155StickerType) PackCount(opts *bind.CallOpts) (*big.Int, error) {
+	return c.contract.PackCount(opts)
+}
+
+func (c *erc1155StickerType) BalanceOf(opts *bind.CallOpts, account common.Address, id *big.Int) (*big.Int, error) {
+	return c.contract.BalanceOf(opts, account, id)
+}
+
+// uriToContenthash converts an ERC-1155 token URI pointing to IPFS into an
+// EIP-1577 contenthash, as returned by the StickerType contract
+func uriToContenthash(uri string, id *big.Int) ([]byte, error) {
+	// ERC-1155 clients substitute {id} with the lowercase hex token ID padded to 64 characters
+	uri = strings.Replace(uri, "{id}", fmt.Sprintf("%064x", id), -1)
+
+	if !strings.HasPrefix(uri, "ipfs://") {
+		return nil, fmt.Errorf("unsupported sticker pack URI %q", uri)
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(uri, "ipfs://"), "ipfs/")
+
+	contentID, err := cid.Decode(strings.SplitN(path, "/", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// CIDv0 can't be represented in base32 as used by the gateway URLs
+	contentID = cid.NewCidV1(contentID.Type(), contentID.Hash())
+
+	return multicodec.AddCodec("ipfs-ns", contentID.Bytes())
+}
+
+// contractStickerType returns the sticker contract configured for the chain,
+// defaulting to the legacy StickerType contract
+func (api *API) contractStickerType(chainID uint64) (stickerTypeContract, error) {
+	contract, ok := api.StickerContracts[chainID]
+	if !ok || contract.Type == "" || contract.Type == StickerContractLegacy {
+		stickerType, err := api.contractMaker.NewStickerType(chainID)
+		if err != nil {
+			return nil, err
+		}
+		return stickerType, nil
+	}
+
+	if contract.Type != StickerContractERC1155 {
+		return nil, fmt.Errorf("unknown sticker contract type %q", contract.Type)
+	}
+
+	if contract.Address == (common.Address{}) {
+		return nil, errors.New("missing sticker contract address")
+	}
+
+	packs, err := api.contractMaker.NewStickerPacks1155(chainID, contract.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &erc1155StickerType{contract: packs}, nil
+}
+
+// getBalancePackIDs returns the IDs of the packs of which account holds a
+// positive balance
+func (api *API) getBalancePackIDs(stickerType stickerTypeContract, balances packBalanceContract, account types.Address) ([]*big.Int, error) {
+	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
+
+	err := api.waitRateLimit(callOpts.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	numPacks, err := stickerType.PackCount(callOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var packIDs []*big.Int
+	for i := uint64(0); i < numPacks.Uint64(); i++ {
+		packID := new(big.Int).SetUint64(i)
+
+		err = api.waitRateLimit(callOpts.Context)
+		if err != nil {
+			return nil, err
+		}
+
+		balance, err := balances.BalanceOf(callOpts, common.Address(account), packID)
+		if err != nil {
+			return nil, err
+		}
+
+		if balance.Sign() > 0 {
+			packIDs = append(packIDs, packID)
+		}
+	}
+
+	return packIDs, nil
+}
@@ -0,0 +1,164 @@
+package stickers
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/go-multicodec"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/status-im/status-go/contracts/stickers"
+	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/params"
+)
+
+type fakeERC1155 struct {
+	mu       sync.Mutex
+	uris     map[uint64]string
+	packs    map[uint64]stickers.StickerPack1155Data
+	balances map[common.Address]map[uint64]int64
+}
+
+func (f *fakeERC1155) URI(opts *bind.CallOpts, id *big.Int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.uris[id.Uint64()], nil
+}
+
+func (f *fakeERC1155) PackData(opts *bind.CallOpts, id *big.Int) (stickers.StickerPack1155Data, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.packs[id.Uint64()], nil
+}
+
+func (f *fakeERC1155) PackCount(opts *bind.CallOpts) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return big.NewInt(int64(len(f.packs))), nil
+}
+
+func (f *fakeERC1155) BalanceOf(opts *bind.CallOpts, account common.Address, id *big.Int) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return big.NewInt(f.balances[account][id.Uint64()]), nil
+}
+
+// setupERC1155 moves the packs published on the legacy fake contract to an
+// ERC-1155 contract, referencing their content by URI
+func setupERC1155(t *testing.T, s *testSetup) *fakeERC1155 {
+	contract := &fakeERC1155{
+		uris:     make(map[uint64]string),
+		packs:    make(map[uint64]stickers.StickerPack1155Data),
+		balances: make(map[common.Address]map[uint64]int64),
+	}
+
+	for id, data := range s.contract.packs {
+		raw, _, err := multicodec.RemoveCodec(data.Contenthash)
+		require.NoError(t, err)
+		contentID, err := cid.Cast(raw)
+		require.NoError(t, err)
+
+		contract.uris[id] = "ipfs://" + contentID.String()
+		contract.packs[id] = stickers.StickerPack1155Data{Owner: data.Owner, Price: data.Price}
+	}
+
+	s.api.stickerType = func(chainID uint64) (stickerTypeContract, error) {
+		return &erc1155StickerType{contract: contract}, nil
+	}
+
+	return contract
+}
+
+func TestERC1155GetPack(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 0, "first", 10, 2)
+	s.publishPack(t, 1, "second", 20, 3)
+	legacy, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+
+	setupERC1155(t, s)
+
+	pack, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, legacy, pack)
+
+	require.NoError(t, s.api.AddPending(testChainID, packID(0)))
+
+	_, err = s.api.GetPack(testChainID, packID(2))
+	require.Equal(t, ErrPackNotFound, err)
+}
+
+func TestERC1155Owned(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(0); id < 3; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+	}
+
+	contract := setupERC1155(t, s)
+	account := types.HexToAddress("0x02")
+	contract.balances[common.Address(account)] = map[uint64]int64{0: 1, 2: 3}
+
+	owned, err := s.api.Owned(testChainID, account, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, owned, 2)
+	require.Equal(t, uint64(0), owned[0].ID.Uint64())
+	require.Equal(t, uint64(2), owned[1].ID.Uint64())
+	require.Equal(t, statusPurchased, owned[1].Status)
+}
+
+func TestStickerContractsConfig(t *testing.T) {
+	address := "0x0000000000000000000000000000000000000abc"
+	config := &params.NodeConfig{StickersConfig: params.StickersConfig{Contracts: map[uint64]params.StickerContractConfig{
+		10: {Type: "erc1155", Address: address},
+		20: {Type: "erc1155", Address: "invalid"},
+	}}}
+
+	api := NewAPI(context.Background(), nil, nil, nil, nil, config)
+	require.Equal(t, map[uint64]StickerContract{
+		10: {Type: StickerContractERC1155, Address: common.HexToAddress(address)},
+		20: {Type: StickerContractERC1155},
+	}, api.StickerContracts)
+
+	_, err := api.contractStickerType(20)
+	require.Error(t, err)
+
+	// Chains without configuration use the legacy contracts
+	require.Empty(t, NewAPI(context.Background(), nil, nil, nil, nil, nil).StickerContracts)
+}
+
+func TestURIToContenthash(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	hash, err := hex.DecodeString(s.ipfs.add(t, []byte("pack")))
+	require.NoError(t, err)
+	raw, _, err := multicodec.RemoveCodec(hash)
+	require.NoError(t, err)
+	contentID, err := cid.Cast(raw)
+	require.NoError(t, err)
+	v0 := cid.NewCidV0(contentID.Hash())
+
+	for _, uri := range []string{
+		"ipfs://" + contentID.String(),
+		"ipfs://ipfs/" + contentID.String(),
+		"ipfs://" + v0.String(),
+		"ipfs://" + contentID.String() + "/{id}.json",
+	} {
+		contenthash, err := uriToContenthash(uri, big.NewInt(1))
+		require.NoError(t, err, uri)
+		require.Equal(t, hash, contenthash, uri)
+	}
+
+	_, err = uriToContenthash("https://example.com/1.json", big.NewInt(1))
+	require.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package stickers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/wealdtech/go-multicodec"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var ErrContentMismatch = errors.New("content doesn't match its hash")
+var ErrUnverifiableContent = errors.New("content can't be verified against its hash")
+
+// ValidateHash checks that hash references IPFS content with a well formed
+// CID. The hash is either a hex encoded contenthash, as found in sticker pack
+// metadata, or a CID string, see decodeHashCID
+func ValidateHash(hash string) error {
+	_, err := decodeHashCID(hash)
+	return err
+}
+
+// decodeHashCID extracts the CID of a sticker hash: a hex encoded EIP-1577
+// contenthash, a base58 CIDv0 (Qm...) or a multibase CIDv1 such as base32
+// (bafy...). It fails with ErrInvalidHash for other encodings
+func decodeHashCID(hash string) (cid.Cid, error) {
+	contenthash, err := hexutil.Decode("0x" + hash)
+	if err == nil {
+		var contentID cid.Cid
+		contentID, err = decodeContenthash(contenthash)
+		if err == nil {
+			return contentID, nil
+		}
+	}
+
+	// Hex strings can also be base16 CIDv1, the contenthash error is kept
+	// as it's the most likely encoding
+	contentID, cidErr := cid.Decode(hash)
+	if cidErr != nil {
+		return cid.Undef, wrapError(ErrInvalidHash, fmt.Errorf("%q: %w", hash, err))
+	}
+
+	return contentID, nil
+}
+
+// decodeContenthash extracts the CID of an EIP-1577 IPFS contenthash
+func decodeContenthash(contenthash []byte) (cid.Cid, error) {
+	data, codec, err := multicodec.RemoveCodec(contenthash)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	codecName, err := multicodec.Name(codec)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	if codecName != "ipfs-ns" {
+		return cid.Undef, errors.New("codecName is not ipfs-ns")
+	}
+
+	return cid.Cast(data)
+}
+
+// Types of the UnixFS nodes holding file content
+const (
+	unixfsRaw  = 0
+	unixfsFile = 2
+)
+
+// blockContent checks that block hashes to contentID and extracts the content
+// it holds. Raw blocks are the content itself, dag-pb blocks hold it in a
+// UnixFS node. Content split across several blocks can't be verified
+func blockContent(contentID cid.Cid, block []byte) ([]byte, error) {
+	actual, err := contentID.Prefix().Sum(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if !actual.Equals(contentID) {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrContentMismatch, contentID, actual)
+	}
+
+	switch contentID.Type() {
+	case cid.Raw:
+		return block, nil
+	case cid.DagProtobuf:
+		return unixfsFileData(block)
+	default:
+		return nil, fmt.Errorf("%w: unsupported codec %d", ErrUnverifiableContent, contentID.Type())
+	}
+}
+
+// unixfsFileData returns the content of a single block UnixFS file from its
+// dag-pb node
+func unixfsFileData(block []byte) ([]byte, error) {
+	var data []byte
+	err := decodeProtobuf(block, func(field uint64, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			data = bytes
+		case 2:
+			return fmt.Errorf("%w: content spans several blocks", ErrUnverifiableContent)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unixfsType := uint64(unixfsRaw)
+	var content []byte
+	err = decodeProtobuf(data, func(field uint64, varint uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			unixfsType = varint
+		case 2:
+			content = bytes
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if unixfsType != unixfsRaw && unixfsType != unixfsFile {
+		return nil, fmt.Errorf("%w: UnixFS node of type %d isn't a file", ErrUnverifiableContent, unixfsType)
+	}
+
+	return content, nil
+}
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errMalformedProtobuf = errors.New("malformed protobuf message")
+
+// decodeProtobuf calls field with the value of every field of the protobuf
+// message, either a varint or bytes. Fixed size values are skipped
+func decodeProtobuf(message []byte, field func(number uint64, varint uint64, bytes []byte) error) error {
+	for len(message) > 0 {
+		tag, n := binary.Uvarint(message)
+		if n <= 0 {
+			return errMalformedProtobuf
+		}
+		message = message[n:]
+
+		var varint uint64
+		var bytes []byte
+		switch tag & 7 {
+		case wireVarint:
+			varint, n = binary.Uvarint(message)
+			if n <= 0 {
+				return errMalformedProtobuf
+			}
+		case wireBytes:
+			var length uint64
+			length, n = binary.Uvarint(message)
+			if n <= 0 || length > uint64(len(message)-n) {
+				return errMalformedProtobuf
+			}
+			bytes = message[n : n+int(length)]
+			n += int(length)
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		default:
+			return errMalformedProtobuf
+		}
+		if n > len(message) {
+			return errMalformedProtobuf
+		}
+		message = message[n:]
+
+		err := field(tag>>3, varint, bytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package stickers
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+	"olympos.io/encoding/edn"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestValidateHash(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	valid := s.ipfs.add(t, []byte("sticker"))
+	require.NoError(t, ValidateHash(valid))
+
+	for _, hash := range []string{
+		"zz",
+		"e301",
+		// sha2-256 multihash truncated
+		valid[:len(valid)-2],
+		// swarm-ns instead of ipfs-ns
+		"e40101701220" + strings.Repeat("00", 32),
+	} {
+		require.Error(t, ValidateHash(hash), hash)
+
+		_, err := s.api.decodeStringHash(hash)
+		require.Error(t, err, hash)
+	}
+}
+
+func TestDecodeStringHashCIDs(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	mh, err := multihash.Sum([]byte("sticker"), multihash.SHA2_256, -1)
+	require.NoError(t, err)
+	v0 := cid.NewCidV0(mh)
+	v1 := cid.NewCidV1(cid.DagProtobuf, mh)
+	base32, err := v1.StringOfBase(multibase.Base32)
+	require.NoError(t, err)
+	expected := "https://" + base32 + ".ipfs.infura-ipfs.io/"
+
+	require.True(t, strings.HasPrefix(v0.String(), "Qm"))
+	require.True(t, strings.HasPrefix(base32, "bafy"))
+
+	for _, hash := range []string{v0.String(), base32, s.ipfs.add(t, []byte("sticker"))} {
+		require.NoError(t, ValidateHash(hash), hash)
+
+		url, err := s.api.decodeStringHash(hash)
+		require.NoError(t, err, hash)
+		require.Equal(t, expected, url, hash)
+	}
+
+	for _, hash := range []string{"not a hash", "Qm" + strings.Repeat("1", 44), "bafy"} {
+		_, err := s.api.decodeStringHash(hash)
+		require.True(t, errors.Is(err, ErrInvalidHash), hash)
+	}
+}
+
+func TestDecodeStringHashMediaServer(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	hash := s.ipfs.add(t, []byte("sticker"))
+	gatewayURL, err := s.api.decodeStringHash(hash)
+	require.NoError(t, err)
+
+	baseURL := "https://localhost:12345"
+	s.api.MediaServerURL = func() string { return baseURL }
+
+	url, err := s.api.decodeStringHash(hash)
+	require.NoError(t, err)
+	require.Equal(t, "https://localhost:12345/stickers?hash="+hash, url)
+
+	_, err = s.api.decodeStringHash("not a hash")
+	require.True(t, errors.Is(err, ErrInvalidHash))
+
+	// Stickers are still downloaded from the IPFS gateway by the media server
+	data, err := s.api.downloadSticker(context.Background(), hash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("sticker"), data)
+
+	// Falls back to the gateway while the media server isn't listening
+	baseURL = ""
+	url, err = s.api.decodeStringHash(hash)
+	require.NoError(t, err)
+	require.Equal(t, gatewayURL, url)
+}
+
+func TestVerifyContent(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.VerifyContent = true
+
+	meta := ednStickerPack{
+		Name:      "raw",
+		Preview:   s.ipfs.add(t, []byte("preview")),
+		Thumbnail: s.ipfs.add(t, []byte("thumbnail")),
+		Stickers:  []ednSticker{{Hash: s.ipfs.add(t, []byte("sticker"))}},
+	}
+	data, err := edn.Marshal(ednStickerPackInfo{Meta: meta})
+	require.NoError(t, err)
+
+	hash := s.ipfs.addWithCodec(t, data, cid.Raw)
+	contenthash, err := hex.DecodeString(hash)
+	require.NoError(t, err)
+
+	s.contract.setPack(1, stickerPackData{Owner: common.HexToAddress("0x01"), Price: big.NewInt(1), Contenthash: contenthash})
+
+	pack, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, "raw", pack.Name)
+
+	// A gateway serving other content for the CID is detected
+	contentID, err := decodeContenthash(contenthash)
+	require.NoError(t, err)
+	key, err := contentID.StringOfBase(multibase.Base32)
+	require.NoError(t, err)
+	meta.Name = "tampered"
+	tampered, err := edn.Marshal(ednStickerPackInfo{Meta: meta})
+	require.NoError(t, err)
+	s.ipfs.content[key] = tampered
+	// Metadata is cached by content hash, so the gateway isn't queried again
+	// unless the cache is emptied
+	s.api.metadata = newByteCache(defaultMetadataCacheBytes)
+
+	_, err = s.api.GetPack(testChainID, packID(1))
+	require.True(t, errors.Is(err, ErrContentMismatch))
+
+	s.api.VerifyContent = false
+	pack, err = s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, "tampered", pack.Name)
+}
+
+func TestVerifyUnixFSContent(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.VerifyContent = true
+
+	meta := ednStickerPack{
+		Name:      "dag-pb",
+		Preview:   s.ipfs.add(t, []byte("preview")),
+		Thumbnail: s.ipfs.add(t, []byte("thumbnail")),
+		Stickers:  []ednSticker{{Hash: s.ipfs.add(t, []byte("sticker"))}},
+	}
+	data, err := edn.Marshal(ednStickerPackInfo{Meta: meta})
+	require.NoError(t, err)
+
+	metaID, metaHash := s.ipfs.addUnixFS(t, data)
+	contenthash, err := hex.DecodeString(metaHash)
+	require.NoError(t, err)
+	s.contract.setPack(1, stickerPackData{Owner: common.HexToAddress("0x01"), Price: big.NewInt(1), Contenthash: contenthash})
+
+	stickerID, stickerHash := s.ipfs.addUnixFS(t, []byte("sticker"))
+	require.True(t, strings.HasPrefix(stickerID.String(), "Qm"))
+
+	pack, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, "dag-pb", pack.Name)
+
+	sticker, err := s.api.downloadSticker(context.Background(), stickerHash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("sticker"), sticker)
+	sticker, err = s.api.downloadCID(context.Background(), stickerID.String())
+	require.NoError(t, err)
+	require.Equal(t, []byte("sticker"), sticker)
+
+	// Gateways serving tampered blocks are detected
+	meta.Name = "tampered"
+	tampered, err := edn.Marshal(ednStickerPackInfo{Meta: meta})
+	require.NoError(t, err)
+	s.ipfs.mu.Lock()
+	s.ipfs.blocks[blockKey(t, metaID)] = unixfsBlock(tampered)
+	s.ipfs.blocks[blockKey(t, stickerID)] = unixfsBlock([]byte("tampered"))
+	s.ipfs.mu.Unlock()
+	s.api.metadata = newByteCache(defaultMetadataCacheBytes)
+
+	_, err = s.api.GetPack(testChainID, packID(1))
+	require.True(t, errors.Is(err, ErrContentMismatch), err)
+	_, err = s.api.downloadSticker(context.Background(), stickerHash)
+	require.True(t, errors.Is(err, ErrContentMismatch), err)
+
+	// Content spanning several blocks can't be verified
+	linked := protobufField(nil, 2, []byte{})
+	linked = append(linked, unixfsBlock([]byte("sticker"))...)
+	_, err = blockContent(cid.NewCidV0(sum(t, linked)), linked)
+	require.True(t, errors.Is(err, ErrUnverifiableContent), err)
+}
+
+func TestVerifyStickerContent(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.VerifyContent = true
+
+	hash := s.ipfs.addWithCodec(t, []byte("sticker"), cid.Raw)
+	contenthash, err := hex.DecodeString(hash)
+	require.NoError(t, err)
+	contentID, err := decodeContenthash(contenthash)
+	require.NoError(t, err)
+	key, err := contentID.StringOfBase(multibase.Base32)
+	require.NoError(t, err)
+
+	data, err := s.api.downloadSticker(context.Background(), hash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("sticker"), data)
+	data, err = s.api.downloadCID(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("sticker"), data)
+
+	// A gateway serving other content for the CID is detected
+	s.ipfs.mu.Lock()
+	s.ipfs.content[key] = []byte("tampered")
+	s.ipfs.mu.Unlock()
+
+	_, err = s.api.downloadSticker(context.Background(), hash)
+	require.True(t, errors.Is(err, ErrContentMismatch))
+	_, err = s.api.downloadCID(context.Background(), key)
+	require.True(t, errors.Is(err, ErrContentMismatch))
+
+	s.api.VerifyContent = false
+	data, err = s.api.downloadSticker(context.Background(), hash)
+	require.NoError(t, err)
+	require.Equal(t, []byte("tampered"), data)
+}
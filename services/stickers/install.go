@@ -1,11 +1,18 @@
 package stickers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/eventbus"
 	"github.com/status-im/status-go/multiaccounts/settings"
 	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
 )
 
 func (api *API) Install(chainID uint64, packID *bigint.BigInt) error {
@@ -20,12 +27,12 @@ func (api *API) Install(chainID uint64, packID *bigint.BigInt) error {
 
 	// TODO: this does not validate if the pack is purchased. Should it?
 
-	stickerType, err := api.contractMaker.NewStickerType(chainID)
+	stickerType, err := api.newStickerType(chainID)
 	if err != nil {
 		return err
 	}
 
-	stickerPack, err := api.fetchPackData(stickerType, packID.Int, false)
+	stickerPack, err := api.fetchPackData(chainID, stickerType, packID.Int, false)
 	if err != nil {
 		return err
 	}
@@ -40,6 +47,137 @@ func (api *API) Install(chainID uint64, packID *bigint.BigInt) error {
 	return nil
 }
 
+// ErrInstallIncomplete is returned by InstallPack when some of the pack
+// content couldn't be fetched, calling it again fetches the missing content
+var ErrInstallIncomplete = errors.New("sticker pack content partially fetched")
+
+// InstallProgress is the outcome of fetching the content of an installed pack
+type InstallProgress struct {
+	Fetched int      `json:"fetched"`
+	Total   int      `json:"total"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+// InstallPack installs the sticker pack and fetches its preview, thumbnail
+// and stickers content so that they are rendered without waiting for the
+// IPFS gateway. The progress is reported with signals as the content is
+// fetched. Content failing to be fetched is reported in the completion signal
+// and ErrInstallIncomplete is returned, while the pack stays installed, so
+// that calling InstallPack again only fetches the missing content
+func (api *API) InstallPack(ctx context.Context, chainID uint64, packID *bigint.BigInt) (InstallProgress, error) {
+	stickerPack, err := api.installPack(chainID, packID)
+	if err != nil {
+		return InstallProgress{}, err
+	}
+
+	hashes := []string{stickerPack.Preview, stickerPack.Thumbnail}
+	for _, sticker := range stickerPack.Stickers {
+		hashes = append(hashes, sticker.Hash)
+	}
+
+	var missing []string
+	seen := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		if _, ok := api.cachedContent(hash); !ok {
+			missing = append(missing, hash)
+		}
+	}
+
+	progress := InstallProgress{Total: len(seen), Fetched: len(seen) - len(missing)}
+	signal.SendStickerPackInstallProgress(chainID, packID.String(), progress.Fetched, progress.Total)
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		slots = make(chan struct{}, maxConcurrentRequests)
+	)
+	for _, hash := range missing {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(hash string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			data, err := api.downloadSticker(ctx, hash)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				log.Warn("failed to fetch sticker pack content", "packID", packID, "hash", hash, "err", err)
+				progress.Failed = append(progress.Failed, hash)
+				return
+			}
+
+			api.cacheContent(hash, data)
+			progress.Fetched++
+			signal.SendStickerPackInstallProgress(chainID, packID.String(), progress.Fetched, progress.Total)
+		}(hash)
+	}
+	wg.Wait()
+
+	sort.Strings(progress.Failed)
+	signal.SendStickerPackInstalled(chainID, packID.String(), progress.Failed)
+
+	if len(progress.Failed) > 0 {
+		return progress, fmt.Errorf("%w: %d of %d fetched", ErrInstallIncomplete, progress.Fetched, progress.Total)
+	}
+
+	return progress, nil
+}
+
+// installPack returns the installed pack, installing it first if it isn't
+func (api *API) installPack(chainID uint64, packID *bigint.BigInt) (*StickerPack, error) {
+	installedPacks, err := api.installedStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	if stickerPack, exists := installedPacks[uint(packID.Uint64())]; exists {
+		return &stickerPack, nil
+	}
+
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	stickerPack, err := api.fetchPackData(chainID, stickerType, packID.Int, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePack(stickerPack); err != nil {
+		return nil, err
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	installedPacks, err = api.installedStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	if installed, exists := installedPacks[uint(packID.Uint64())]; exists {
+		return &installed, nil
+	}
+
+	installedPacks[uint(packID.Uint64())] = *stickerPack
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksInstalled, installedPacks)
+	if err != nil {
+		return nil, err
+	}
+
+	return stickerPack, nil
+}
+
 func (api *API) installedStickerPacks() (StickerPackCollection, error) {
 	stickerPacks := make(StickerPackCollection)
 
@@ -69,18 +207,18 @@ func (api *API) Installed() (StickerPackCollection, error) {
 	for packID, stickerPack := range stickerPacks {
 		stickerPack.Status = statusInstalled
 
-		stickerPack.Preview, err = decodeStringHash(stickerPack.Preview)
+		stickerPack.Preview, err = api.decodeStringHash(stickerPack.Preview)
 		if err != nil {
 			return nil, err
 		}
 
-		stickerPack.Thumbnail, err = decodeStringHash(stickerPack.Thumbnail)
+		stickerPack.Thumbnail, err = api.decodeStringHash(stickerPack.Thumbnail)
 		if err != nil {
 			return nil, err
 		}
 
 		for i, sticker := range stickerPack.Stickers {
-			sticker.URL, err = decodeStringHash(sticker.Hash)
+			sticker.URL, err = api.decodeStringHash(sticker.Hash)
 			if err != nil {
 				return nil, err
 			}
@@ -93,46 +231,191 @@ func (api *API) Installed() (StickerPackCollection, error) {
 	return stickerPacks, nil
 }
 
-func (api *API) Uninstall(packID *bigint.BigInt) error {
+// UninstallResult tells which collections an uninstalled sticker pack was
+// removed from
+type UninstallResult struct {
+	Installed bool `json:"installed"`
+	Pending   bool `json:"pending"`
+	Recent    bool `json:"recent"`
+	Order     bool `json:"order"`
+}
+
+// Uninstall removes the sticker pack from the installed packs, the pending
+// packs of the chain and the packs order, as well as its stickers from the
+// recent stickers. The same pack ID pending on other chains is another pack
+// and is kept. Nothing is changed for a pack that isn't present
+func (api *API) Uninstall(chainID uint64, packID *bigint.BigInt) (UninstallResult, error) {
+	var result UninstallResult
+	key, err := pendingKey(chainID, packID)
+	if err != nil {
+		return result, err
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
 	installedPacks, err := api.installedStickerPacks()
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	if _, exists := installedPacks[uint(packID.Uint64())]; !exists {
-		return errors.New("sticker pack is not installed")
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return result, err
 	}
 
-	delete(installedPacks, uint(packID.Uint64()))
+	recentStickers, err := api.recentStickers()
+	if err != nil {
+		return result, err
+	}
 
-	err = api.accountsDB.SaveSettingField(settings.StickersPacksInstalled, installedPacks)
+	order, err := api.packOrder()
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	// Removing uninstalled pack from recent stickers
+	newRecentStickers := make([]Sticker, 0, len(recentStickers))
+	for _, sticker := range recentStickers {
+		if sticker.PackID.Cmp(packID.Int) != 0 {
+			newRecentStickers = append(newRecentStickers, sticker)
+		}
+	}
 
-	recentStickers, err := api.recentStickers()
+	var removed []StickerPack
+	if installed, exists := installedPacks[key]; exists {
+		removed = append(removed, installed)
+		delete(installedPacks, key)
+		err = api.accountsDB.SaveSettingField(settings.StickersPacksInstalled, installedPacks)
+		if err != nil {
+			return result, err
+		}
+		result.Installed = true
+	}
+
+	if pending, exists := pendingPacks[chainID][key]; exists {
+		removed = append(removed, pending)
+		pendingPacks.remove(chainID, key)
+		err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+		if err != nil {
+			return result, err
+		}
+		result.Pending = true
+
+		signal.SendStickerPackPendingRemoved(chainID, packID.String())
+		api.Events.Publish(eventbus.StickerPackRemoved, eventbus.StickerPackPayload{ChainID: chainID, PackID: packID.String()})
+	}
+
+	if len(removed) > 0 {
+		api.evictPackContent(removed...)
+	}
+
+	if len(newRecentStickers) != len(recentStickers) {
+		err = api.accountsDB.SaveSettingField(settings.StickersRecentStickers, newRecentStickers)
+		if err != nil {
+			return result, err
+		}
+		result.Recent = true
+	}
+
+	newOrder := removePackID(order, packID)
+	if len(newOrder) != len(order) {
+		err = api.accountsDB.SaveSettingField(settings.StickersPacksOrder, newOrder)
+		if err != nil {
+			return result, err
+		}
+		result.Order = true
+	}
+
+	return result, nil
+}
+
+func (api *API) packOrder() ([]*bigint.BigInt, error) {
+	order := make([]*bigint.BigInt, 0)
+
+	orderJSON, err := api.accountsDB.GetStickerPacksOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	if orderJSON == nil {
+		return order, nil
+	}
+
+	err = json.Unmarshal(*orderJSON, &order)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func removePackID(packIDs []*bigint.BigInt, packID *bigint.BigInt) []*bigint.BigInt {
+	result := make([]*bigint.BigInt, 0, len(packIDs))
+	for _, id := range packIDs {
+		if id.Cmp(packID.Int) != 0 {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// SetPackOrder sets the order in which InstalledOrdered returns the installed
+// packs, such as to pin favorites first. Every pack must be installed and
+// listed once
+func (api *API) SetPackOrder(packIDs []*bigint.BigInt) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	installedPacks, err := api.installedStickerPacks()
 	if err != nil {
 		return err
 	}
 
-	idx := -1
-	for i, r := range recentStickers {
-		if r.PackID.Cmp(packID.Int) == 0 {
-			idx = i
-			break
+	listed := make(map[uint]struct{}, len(packIDs))
+	for _, packID := range packIDs {
+		key := uint(packID.Uint64())
+		if _, exists := installedPacks[key]; !exists {
+			return fmt.Errorf("sticker pack %s is not installed", packID)
+		}
+		if _, exists := listed[key]; exists {
+			return fmt.Errorf("sticker pack %s is listed more than once", packID)
 		}
+		listed[key] = struct{}{}
+	}
+
+	return api.accountsDB.SaveSettingField(settings.StickersPacksOrder, packIDs)
+}
+
+// InstalledOrdered returns the installed packs in the order set with
+// SetPackOrder, followed by the packs missing from the order by pack ID
+func (api *API) InstalledOrdered() ([]StickerPack, error) {
+	installedPacks, err := api.Installed()
+	if err != nil {
+		return nil, err
 	}
 
-	if idx > -1 {
-		var newRecentStickers []Sticker
-		newRecentStickers = append(newRecentStickers, recentStickers[:idx]...)
-		if idx != len(recentStickers)-1 {
-			newRecentStickers = append(newRecentStickers, recentStickers[idx+1:]...)
+	order, err := api.packOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]StickerPack, 0, len(installedPacks))
+	for _, packID := range order {
+		key := uint(packID.Uint64())
+		if stickerPack, exists := installedPacks[key]; exists {
+			result = append(result, stickerPack)
+			delete(installedPacks, key)
 		}
-		return api.accountsDB.SaveSettingField(settings.StickersRecentStickers, newRecentStickers)
 	}
 
-	return nil
+	remaining := make([]uint, 0, len(installedPacks))
+	for key := range installedPacks {
+		remaining = append(remaining, key)
+	}
+	sort.Slice(remaining, func(i, j int) bool { return remaining[i] < remaining[j] })
+	for _, key := range remaining {
+		result = append(result, installedPacks[key])
+	}
+
+	return result, nil
 }
@@ -0,0 +1,253 @@
+package stickers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
+)
+
+func TestUninstall(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "first", 10, 2)
+	s.publishPack(t, 2, "second", 10, 1)
+
+	require.NoError(t, s.api.Install(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.NoError(t, s.api.Install(testChainID, packID(2)))
+
+	first, err := s.api.GetPack(testChainID, packID(1))
+	require.NoError(t, err)
+	second, err := s.api.GetPack(testChainID, packID(2))
+	require.NoError(t, err)
+	require.NoError(t, s.api.TrackRecentSticker(packID(1), first.Stickers[0].Hash))
+	require.NoError(t, s.api.TrackRecentSticker(packID(2), second.Stickers[0].Hash))
+	require.NoError(t, s.api.TrackRecentSticker(packID(1), first.Stickers[1].Hash))
+
+	// The same pack ID pending on another chain is another pack
+	const otherChainID = testChainID + 1
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	pending.add(otherChainID, StickerPack{ID: packID(1), ChainID: otherChainID})
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, pending))
+
+	_, err = s.api.Uninstall(0, packID(1))
+	require.True(t, errors.Is(err, ErrInvalidChainID))
+
+	result, err := s.api.Uninstall(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, UninstallResult{Installed: true, Pending: true, Recent: true}, result)
+
+	installed, err := s.api.installedStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	require.Contains(t, installed, uint(2))
+
+	pending, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Empty(t, pending[testChainID])
+	require.Contains(t, pending[otherChainID], uint(1))
+
+	recent, err := s.api.recentStickers()
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	require.Equal(t, second.Stickers[0].Hash, recent[0].Hash)
+
+	// Uninstalling a pack that isn't present changes nothing
+	result, err = s.api.Uninstall(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, UninstallResult{}, result)
+
+	result, err = s.api.Uninstall(testChainID, packID(3))
+	require.NoError(t, err)
+	require.Equal(t, UninstallResult{}, result)
+
+	installed, err = s.api.installedStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+}
+
+func TestRemovedPackContentEvicted(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	shared := s.ipfs.add(t, []byte("shared sticker"))
+	for id := uint64(1); id <= 3; id++ {
+		s.publishMeta(t, id, 10, ednStickerPack{
+			Name:      fmt.Sprintf("pack %d", id),
+			Preview:   s.ipfs.add(t, []byte(fmt.Sprintf("preview %d", id))),
+			Thumbnail: s.ipfs.add(t, []byte(fmt.Sprintf("thumbnail %d", id))),
+			Stickers:  []ednSticker{{Hash: shared}, {Hash: s.ipfs.add(t, []byte(fmt.Sprintf("sticker %d", id)))}},
+		})
+	}
+
+	require.NoError(t, s.api.Install(testChainID, packID(1)))
+	require.NoError(t, s.api.Install(testChainID, packID(2)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(3)))
+
+	installed, err := s.api.installedStickerPacks()
+	require.NoError(t, err)
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	packs := []StickerPack{installed[1], installed[2], pending[testChainID][3]}
+	for _, stickerPack := range packs {
+		for _, entry := range packContents(&stickerPack) {
+			s.api.cacheContent(entry.Hash, []byte(entry.Hash))
+		}
+	}
+
+	cached := func(stickerPack StickerPack) []bool {
+		var cached []bool
+		for _, entry := range packContents(&stickerPack) {
+			_, ok := s.api.cachedContent(entry.Hash)
+			cached = append(cached, ok)
+		}
+		return cached
+	}
+
+	// The sticker shared with the other packs stays cached
+	_, err = s.api.Uninstall(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, false, true, false}, cached(packs[0]))
+	require.Equal(t, []bool{true, true, true, true}, cached(packs[1]))
+
+	require.NoError(t, s.api.RemovePending(testChainID, packID(3)))
+	require.Equal(t, []bool{false, false, true, false}, cached(packs[2]))
+
+	_, err = s.api.Uninstall(testChainID, packID(2))
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, false, false, false}, cached(packs[1]))
+}
+
+func TestPackOrder(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(1); id <= 4; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+		require.NoError(t, s.api.Install(testChainID, packID(id)))
+	}
+
+	ids := func(stickerPacks []StickerPack) []uint64 {
+		var result []uint64
+		for _, stickerPack := range stickerPacks {
+			result = append(result, stickerPack.ID.Uint64())
+		}
+		return result
+	}
+
+	ordered, err := s.api.InstalledOrdered()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 3, 4}, ids(ordered))
+
+	require.Error(t, s.api.SetPackOrder([]*bigint.BigInt{packID(3), packID(5)}))
+	require.Error(t, s.api.SetPackOrder([]*bigint.BigInt{packID(3), packID(3)}))
+
+	// Packs missing from the order follow the ordered ones
+	require.NoError(t, s.api.SetPackOrder([]*bigint.BigInt{packID(3), packID(1)}))
+	ordered, err = s.api.InstalledOrdered()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3, 1, 2, 4}, ids(ordered))
+	require.NotEmpty(t, ordered[0].Preview)
+
+	result, err := s.api.Uninstall(testChainID, packID(3))
+	require.NoError(t, err)
+	require.True(t, result.Order)
+
+	order, err := s.api.packOrder()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, uint64s(order))
+
+	ordered, err = s.api.InstalledOrdered()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 4}, ids(ordered))
+}
+
+func TestInstallPack(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var progress []signal.StickerPackInstallProgressSignal
+	var installed []signal.StickerPackInstalledSignal
+	var mu sync.Mutex
+	signal.SetMobileSignalHandler(func(data []byte) {
+		var envelope struct {
+			Type  string          `json:"type"`
+			Event json.RawMessage `json:"event"`
+		}
+		require.NoError(t, json.Unmarshal(data, &envelope))
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch envelope.Type {
+		case signal.EventStickerPackInstallProgress:
+			var event signal.StickerPackInstallProgressSignal
+			require.NoError(t, json.Unmarshal(envelope.Event, &event))
+			progress = append(progress, event)
+		case signal.EventStickerPackInstalled:
+			var event signal.StickerPackInstalledSignal
+			require.NoError(t, json.Unmarshal(envelope.Event, &event))
+			installed = append(installed, event)
+		}
+	})
+	defer signal.SetMobileSignalHandler(nil)
+
+	// The last sticker isn't available on IPFS yet
+	missing := []byte("missing sticker")
+	unpublished := &fakeIPFS{content: make(map[string][]byte)}
+	meta := ednStickerPack{
+		Name:      "pack",
+		Preview:   s.ipfs.add(t, []byte("preview")),
+		Thumbnail: s.ipfs.add(t, []byte("thumbnail")),
+		Stickers: []ednSticker{
+			{Hash: s.ipfs.add(t, []byte("sticker"))},
+			{Hash: unpublished.add(t, missing)},
+		},
+	}
+	s.publishMeta(t, 1, 10, meta)
+
+	result, err := s.api.InstallPack(context.Background(), testChainID, packID(1))
+	require.True(t, errors.Is(err, ErrInstallIncomplete))
+	require.Equal(t, InstallProgress{Fetched: 3, Total: 4, Failed: []string{meta.Stickers[1].Hash}}, result)
+
+	installedPacks, err := s.api.Installed()
+	require.NoError(t, err)
+	require.Contains(t, installedPacks, uint(1))
+
+	data, err := s.api.stickerContent(context.Background(), meta.Preview)
+	require.NoError(t, err)
+	require.Equal(t, []byte("preview"), data)
+
+	// Retrying only fetches the missing content
+	s.ipfs.add(t, missing)
+	requests := s.ipfs.requests
+	result, err = s.api.InstallPack(context.Background(), testChainID, packID(1))
+	require.NoError(t, err)
+	require.Equal(t, InstallProgress{Fetched: 4, Total: 4}, result)
+	require.Equal(t, requests+1, s.ipfs.requests)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []signal.StickerPackInstallProgressSignal{
+		{PackID: "1", ChainID: testChainID, Fetched: 0, Total: 4},
+		{PackID: "1", ChainID: testChainID, Fetched: 1, Total: 4},
+		{PackID: "1", ChainID: testChainID, Fetched: 2, Total: 4},
+		{PackID: "1", ChainID: testChainID, Fetched: 3, Total: 4},
+		{PackID: "1", ChainID: testChainID, Fetched: 3, Total: 4},
+		{PackID: "1", ChainID: testChainID, Fetched: 4, Total: 4},
+	}, progress)
+	require.Equal(t, []signal.StickerPackInstalledSignal{
+		{PackID: "1", ChainID: testChainID, Failed: []string{meta.Stickers[1].Hash}},
+		{PackID: "1", ChainID: testChainID},
+	}, installed)
+}
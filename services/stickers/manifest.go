@@ -0,0 +1,66 @@
+package stickers
+
+import (
+	"fmt"
+
+	"github.com/status-im/status-go/services/wallet/bigint"
+)
+
+// Kinds of the content of a sticker pack
+const (
+	ContentPreview   = "preview"
+	ContentThumbnail = "thumbnail"
+	ContentSticker   = "sticker"
+)
+
+// ManifestEntry is a content of a sticker pack, referenced by its hash as
+// found in the pack metadata and by its CID
+type ManifestEntry struct {
+	Kind string `json:"kind"`
+	Hash string `json:"hash"`
+	CID  string `json:"cid"`
+}
+
+// PackManifest returns the preview, thumbnail and stickers of a pack, in that
+// order, so that they can be fetched and verified up front. Hashes are
+// decoded to CIDs, without resolving them to gateway URLs. It fails with
+// ErrInvalidHash if a hash can't be decoded
+func (api *API) PackManifest(chainID uint64, packID *bigint.BigInt) ([]ManifestEntry, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	stickerPack, err := api.fetchPackData(chainID, stickerType, packID.Int, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validatePack(stickerPack)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := packContents(stickerPack)
+	for i, entry := range manifest {
+		contentID, err := decodeHashCID(entry.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("%s of pack %s: %w", entry.Kind, packID, err)
+		}
+		manifest[i].CID = contentID.String()
+	}
+
+	return manifest, nil
+}
+
+// packContents lists the content of a pack like PackManifest, without CIDs
+func packContents(stickerPack *StickerPack) []ManifestEntry {
+	contents := []ManifestEntry{
+		{Kind: ContentPreview, Hash: stickerPack.Preview},
+		{Kind: ContentThumbnail, Hash: stickerPack.Thumbnail},
+	}
+	for _, sticker := range stickerPack.Stickers {
+		contents = append(contents, ManifestEntry{Kind: ContentSticker, Hash: sticker.Hash})
+	}
+	return contents
+}
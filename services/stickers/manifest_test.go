@@ -0,0 +1,55 @@
+package stickers
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackManifest(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	meta := ednStickerPack{
+		Name:      "pack",
+		Preview:   s.ipfs.add(t, []byte("preview")),
+		Thumbnail: s.ipfs.add(t, []byte("thumbnail")),
+		Stickers: []ednSticker{
+			{Hash: s.ipfs.add(t, []byte("first"))},
+			{Hash: s.ipfs.add(t, []byte("second"))},
+		},
+	}
+	s.publishMeta(t, 1, 10, meta)
+
+	manifest, err := s.api.PackManifest(testChainID, packID(1))
+	require.NoError(t, err)
+	require.Len(t, manifest, 4)
+
+	kinds := []string{ContentPreview, ContentThumbnail, ContentSticker, ContentSticker}
+	hashes := []string{meta.Preview, meta.Thumbnail, meta.Stickers[0].Hash, meta.Stickers[1].Hash}
+	for i, entry := range manifest {
+		require.Equal(t, kinds[i], entry.Kind)
+		require.Equal(t, hashes[i], entry.Hash)
+
+		contenthash, err := hex.DecodeString(entry.Hash)
+		require.NoError(t, err)
+		contentID, err := decodeContenthash(contenthash)
+		require.NoError(t, err)
+		require.Equal(t, contentID.String(), entry.CID)
+	}
+
+	// Nothing is added to the pending packs
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	meta.Stickers[1].Hash = "zz"
+	s.publishMeta(t, 2, 10, meta)
+	_, err = s.api.PackManifest(testChainID, packID(2))
+	require.True(t, errors.Is(err, ErrInvalidHash))
+
+	_, err = s.api.PackManifest(testChainID, packID(3))
+	require.True(t, errors.Is(err, ErrPackNotFound))
+}
@@ -0,0 +1,171 @@
+package stickers
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/eventbus"
+	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
+)
+
+var errPackNotCached = errors.New("sticker pack data isn't cached")
+
+type packKey struct {
+	chainID uint64
+	packID  uint64
+}
+
+// cachedPackData is the pack data returned by a contract, along with the time
+// at which it was read
+type cachedPackData struct {
+	data     stickerPackData
+	readTime time.Time
+}
+
+func (api *API) cachePackData(chainID uint64, packID *big.Int, packData stickerPackData) {
+	key := packKey{chainID: chainID, packID: packID.Uint64()}
+
+	api.packDataLock.Lock()
+	defer api.packDataLock.Unlock()
+
+	if _, exists := api.packData[key]; exists || len(api.packData) < maxCachedPackData {
+		api.packData[key] = cachedPackData{data: packData, readTime: time.Now()}
+	}
+}
+
+// cachedPack builds the pack from the contract data and the metadata cached
+// by previous fetches, without any network call. Hashes aren't translated
+func (api *API) cachedPack(chainID uint64, packID *big.Int) (*StickerPack, error) {
+	api.packDataLock.Lock()
+	entry, cached := api.packData[packKey{chainID: chainID, packID: packID.Uint64()}]
+	api.packDataLock.Unlock()
+	if !cached {
+		return nil, errPackNotCached
+	}
+	packData := entry.data
+
+	body, cached := api.cachedMetadata(packData.Contenthash)
+	if !cached {
+		return nil, errPackNotCached
+	}
+
+	stickerPack := &StickerPack{
+		ID:         &bigint.BigInt{Int: packID},
+		Owner:      packData.Owner,
+		Price:      &bigint.BigInt{Int: packData.Price},
+		Unverified: true,
+	}
+
+	err := api.populateStickerPackAttributes(stickerPack, body, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return stickerPack, nil
+}
+
+// fetchPendingPack fetches the pack data to be stored in the pending packs
+func (api *API) fetchPendingPack(chainID uint64, packID *big.Int) (*StickerPack, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.fetchPackData(chainID, stickerType, packID, false)
+}
+
+// ReconcilePending fetches the unverified pending packs, added while offline
+// with AddPendingCached, and replaces them with the fetched data. Packs that
+// no longer exist are removed, packs that still can't be fetched are kept
+// unverified. It returns the number of packs verified or removed
+func (api *API) ReconcilePending() (int, error) {
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return 0, err
+	}
+
+	verified := make(StickerPacksByChain)
+	missing := make(StickerPacksByChain)
+	for chainID, chainPacks := range pendingPacks {
+		for _, stickerPack := range chainPacks {
+			if !stickerPack.Unverified {
+				continue
+			}
+
+			fetched, err := api.fetchPendingPack(chainID, stickerPack.ID.Int)
+			if errors.Is(err, ErrPackNotFound) {
+				missing.add(chainID, stickerPack)
+				continue
+			}
+			if err == nil {
+				err = validatePack(fetched)
+			}
+			if err != nil {
+				log.Warn("failed to verify pending sticker pack", "chainID", chainID, "packID", stickerPack.ID, "error", err)
+				continue
+			}
+
+			fetched.AddedAt = stickerPack.AddedAt
+			fetched.ChainID = chainID
+			verified.add(chainID, *fetched)
+		}
+	}
+
+	if verified.count() == 0 && missing.count() == 0 {
+		return 0, nil
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	// Pending packs might have changed while the pack data was fetched
+	pendingPacks, err = api.pendingStickerPacks()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for chainID, chainPacks := range verified {
+		for packID, stickerPack := range chainPacks {
+			if current, exists := pendingPacks[chainID][packID]; exists && current.Unverified {
+				pendingPacks[chainID][packID] = stickerPack
+				count++
+			}
+		}
+	}
+
+	removed := make(StickerPacksByChain)
+	for chainID, chainPacks := range missing {
+		for packID, stickerPack := range chainPacks {
+			if current, exists := pendingPacks[chainID][packID]; exists && current.Unverified {
+				pendingPacks.remove(chainID, packID)
+				removed.add(chainID, stickerPack)
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	if err != nil {
+		return 0, err
+	}
+
+	api.evictPendingContent(removed)
+
+	for chainID, chainPacks := range removed {
+		for _, stickerPack := range chainPacks {
+			signal.SendStickerPackPendingRemoved(chainID, stickerPack.ID.String())
+			api.Events.Publish(eventbus.StickerPackRemoved, eventbus.StickerPackPayload{ChainID: chainID, PackID: stickerPack.ID.String()})
+		}
+	}
+
+	return count, nil
+}
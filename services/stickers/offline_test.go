@@ -0,0 +1,71 @@
+package stickers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddPendingCached(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "first", 10, 2)
+	s.publishPack(t, 2, "second", 10, 2)
+	s.publishPack(t, 3, "third", 10, 2)
+	for id := uint64(1); id <= 2; id++ {
+		_, err := s.api.GetPack(testChainID, packID(id))
+		require.NoError(t, err)
+	}
+
+	s.contract.mu.Lock()
+	s.contract.err = errors.New("connection refused")
+	s.contract.mu.Unlock()
+
+	// Without the cache, or for packs never fetched, the error is returned
+	require.Error(t, s.api.AddPending(testChainID, packID(1)))
+	require.Error(t, s.api.AddPendingCached(testChainID, packID(3)))
+
+	require.NoError(t, s.api.AddPendingCached(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPendingCached(testChainID, packID(2)))
+
+	pending, err := s.api.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending[testChainID], 2)
+	first := pending[testChainID][1]
+	require.True(t, first.Unverified)
+	require.Equal(t, "first", first.Name)
+	require.Len(t, first.Stickers, 2)
+	require.NotEmpty(t, first.Stickers[0].URL)
+
+	// Nothing is verified while the contract is unreachable
+	reconciled, err := s.api.ReconcilePending()
+	require.NoError(t, err)
+	require.Zero(t, reconciled)
+
+	s.contract.mu.Lock()
+	s.contract.err = nil
+	s.contract.mu.Unlock()
+	s.contract.removePack(2)
+	stored, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	removed := stored[testChainID][2]
+	s.cachePackContent(t, removed)
+
+	reconciled, err = s.api.ReconcilePending()
+	require.NoError(t, err)
+	require.Equal(t, 2, reconciled)
+
+	stored, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, stored[testChainID], 1)
+	require.False(t, stored[testChainID][1].Unverified)
+	require.Equal(t, first.AddedAt, stored[testChainID][1].AddedAt)
+	require.NotContains(t, stored[testChainID], uint(2))
+	require.False(t, s.packContentCached(removed), "content of the removed pack is evicted")
+
+	reconciled, err = s.api.ReconcilePending()
+	require.NoError(t, err)
+	require.Zero(t, reconciled)
+}
@@ -1,102 +1,644 @@
 package stickers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/eventbus"
 	"github.com/status-im/status-go/multiaccounts/settings"
 	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
 )
 
+// Chain under which the pending packs stored before they were keyed by chain
+// are moved, unless the pack recorded its chain
+const defaultPendingChainID = 1
+
+// StickerPacksByChain holds sticker packs by chain ID, then by pack ID, as
+// the same pack ID refers to different packs on different chains
+type StickerPacksByChain map[uint64]StickerPackCollection
+
+// count returns the number of packs on all chains
+func (p StickerPacksByChain) count() int {
+	count := 0
+	for _, stickerPacks := range p {
+		count += len(stickerPacks)
+	}
+	return count
+}
+
+func (p StickerPacksByChain) add(chainID uint64, stickerPack StickerPack) {
+	if p[chainID] == nil {
+		p[chainID] = make(StickerPackCollection)
+	}
+	p[chainID][uint(stickerPack.ID.Uint64())] = stickerPack
+}
+
+// remove removes the pack from the chain, and the chain once it has no packs
+// left. It tells whether the pack was present
+func (p StickerPacksByChain) remove(chainID uint64, packID uint) bool {
+	if _, exists := p[chainID][packID]; !exists {
+		return false
+	}
+
+	delete(p[chainID], packID)
+	if len(p[chainID]) == 0 {
+		delete(p, chainID)
+	}
+	return true
+}
+
+// find returns the pack with the given ID on the chain with the lowest ID
+// having one
+func (p StickerPacksByChain) find(packID uint) (StickerPack, bool) {
+	chainIDs := make([]uint64, 0, len(p))
+	for chainID := range p {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+
+	for _, chainID := range chainIDs {
+		if stickerPack, exists := p[chainID][packID]; exists {
+			return stickerPack, true
+		}
+	}
+	return StickerPack{}, false
+}
+
+var ErrInvalidChainID = errors.New("invalid chain ID")
+var ErrInvalidPackID = errors.New("invalid sticker pack ID")
+
+// pendingKey validates the chain and pack IDs of a pending pack and returns
+// the key of the pack in the pending packs of the chain. Pack IDs which are
+// missing, negative or don't fit the key would otherwise alias another pack
+func pendingKey(chainID uint64, packID *bigint.BigInt) (uint, error) {
+	if chainID == 0 {
+		return 0, fmt.Errorf("%w: 0", ErrInvalidChainID)
+	}
+	if packID == nil || packID.Int == nil {
+		return 0, fmt.Errorf("%w: missing", ErrInvalidPackID)
+	}
+	if packID.Sign() < 0 || packID.BitLen() > bits.UintSize {
+		return 0, fmt.Errorf("%w: %s is out of range", ErrInvalidPackID, packID)
+	}
+	return uint(packID.Uint64()), nil
+}
+
 func (api *API) AddPending(chainID uint64, packID *bigint.BigInt) error {
+	return api.addPending(chainID, packID, false)
+}
+
+// AddPendingCached adds the pack to the pending packs like AddPending. When
+// the pack data can't be fetched, the data cached from a previous fetch is
+// used instead and the pack is flagged as unverified until ReconcilePending
+// fetches it
+func (api *API) AddPendingCached(chainID uint64, packID *bigint.BigInt) error {
+	return api.addPending(chainID, packID, true)
+}
+
+func (api *API) addPending(chainID uint64, packID *bigint.BigInt, allowCached bool) error {
+	key, err := pendingKey(chainID, packID)
+	if err != nil {
+		return err
+	}
+
 	pendingPacks, err := api.pendingStickerPacks()
 	if err != nil {
 		return err
 	}
 
-	if _, exists := pendingPacks[uint(packID.Uint64())]; exists {
-		return errors.New("sticker pack is already pending")
+	if _, exists := pendingPacks[chainID][key]; exists {
+		return ErrAlreadyPending
+	}
+
+	if api.pendingCapReached(pendingPacks) {
+		return ErrTooManyPending
 	}
 
-	stickerType, err := api.contractMaker.NewStickerType(chainID)
+	stickerPack, err := api.fetchPendingPack(chainID, packID.Int)
+	if err != nil && allowCached && !errors.Is(err, ErrPackNotFound) {
+		cached, cacheErr := api.cachedPack(chainID, packID.Int)
+		if cacheErr != nil {
+			return err
+		}
+		log.Warn("adding unverified sticker pack from cache", "chainID", chainID, "packID", packID, "error", err)
+		stickerPack, err = cached, nil
+	}
 	if err != nil {
 		return err
 	}
 
-	stickerPack, err := api.fetchPackData(stickerType, packID.Int, false)
+	err = validatePack(stickerPack)
 	if err != nil {
 		return err
 	}
 
-	pendingPacks[uint(packID.Uint64())] = *stickerPack
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	// Pending packs might have changed while the pack data was fetched
+	pendingPacks, err = api.pendingStickerPacks()
+	if err != nil {
+		return err
+	}
 
-	return api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	if _, exists := pendingPacks[chainID][key]; exists {
+		return ErrAlreadyPending
+	}
+
+	if api.pendingCapReached(pendingPacks) {
+		return ErrTooManyPending
+	}
+
+	stickerPack.AddedAt = time.Now().Unix()
+	stickerPack.ChainID = chainID
+	pendingPacks.add(chainID, *stickerPack)
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	if err != nil {
+		return err
+	}
+
+	signal.SendStickerPackPendingAdded(chainID, packID.String())
+	api.Events.Publish(eventbus.StickerPackAdded, eventbus.StickerPackPayload{ChainID: chainID, PackID: packID.String()})
+
+	if api.PrefetchPending {
+		go api.prefetchContent(packID, []string{stickerPack.Preview, stickerPack.Thumbnail})
+	}
+
+	return nil
 }
 
-func (api *API) pendingStickerPacks() (StickerPackCollection, error) {
-	stickerPacks := make(StickerPackCollection)
+// pendingCapReached tells whether no more packs can be added to pendingPacks
+func (api *API) pendingCapReached(pendingPacks StickerPacksByChain) bool {
+	return api.MaxPendingPacks > 0 && pendingPacks.count() >= api.MaxPendingPacks
+}
 
+// BatchPlan describes the outcome of adding a batch of sticker packs to the
+// pending packs
+type BatchPlan struct {
+	// Add are the packs that would be added
+	Add []*bigint.BigInt `json:"add"`
+	// Skip are the packs already pending, or repeated in the batch
+	Skip []*bigint.BigInt `json:"skip"`
+	// Reject are the packs exceeding the pending packs cap, and the invalid
+	// pack IDs
+	Reject []*bigint.BigInt `json:"reject"`
+}
+
+// PlanBatchAdd computes which of the given packs would be added to the
+// pending packs of the chain, skipped or rejected, in order, from the current
+// pending packs and MaxPendingPacks. Pack data isn't fetched, so packs that
+// would fail to be fetched aren't detected
+func (api *API) PlanBatchAdd(chainID uint64, ids []*bigint.BigInt) (BatchPlan, error) {
+	if chainID == 0 {
+		return BatchPlan{}, fmt.Errorf("%w: 0", ErrInvalidChainID)
+	}
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return BatchPlan{}, err
+	}
+
+	plan := BatchPlan{
+		Add:    []*bigint.BigInt{},
+		Skip:   []*bigint.BigInt{},
+		Reject: []*bigint.BigInt{},
+	}
+	planned := make(map[uint]struct{})
+	count := pendingPacks.count()
+	for _, id := range ids {
+		key, err := pendingKey(chainID, id)
+		if err != nil {
+			plan.Reject = append(plan.Reject, id)
+			continue
+		}
+		_, pending := pendingPacks[chainID][key]
+		_, repeated := planned[key]
+		switch {
+		case pending || repeated:
+			plan.Skip = append(plan.Skip, id)
+		case api.MaxPendingPacks > 0 && count >= api.MaxPendingPacks:
+			plan.Reject = append(plan.Reject, id)
+		default:
+			plan.Add = append(plan.Add, id)
+			planned[key] = struct{}{}
+			count++
+		}
+	}
+
+	return plan, nil
+}
+
+// pendingStickerPacks returns the pending packs, converted to the current
+// format when still stored in a legacy one until migratePendingStickerPacks
+// rewrites them
+func (api *API) pendingStickerPacks() (StickerPacksByChain, error) {
 	pendingStickersJSON, err := api.accountsDB.GetPendingStickerPacks()
 	if err != nil {
 		return nil, err
 	}
 
 	if pendingStickersJSON == nil {
-		return stickerPacks, nil
+		return make(StickerPacksByChain), nil
 	}
 
-	err = json.Unmarshal(*pendingStickersJSON, &stickerPacks)
+	stickerPacks, _, err := decodePendingStickerPacks(*pendingStickersJSON)
 	if err != nil {
-		return nil, err
+		return api.corruptedPending(*pendingStickersJSON, err)
 	}
+	return stickerPacks, nil
+}
+
+// Formats in which the pending packs setting was stored
+const (
+	// pendingFormatList is a JSON array of packs
+	pendingFormatList = iota + 1
+	// pendingFormatByID is an object of packs keyed by pack ID
+	pendingFormatByID
+	// pendingFormatByChain is the current StickerPacksByChain format
+	pendingFormatByChain
+)
 
+// legacyStickerPack is a pending pack stored in a legacy format, where the
+// pack ID could also be named packID
+type legacyStickerPack struct {
+	StickerPack
+	PackID *bigint.BigInt `json:"packID,omitempty"`
+}
+
+// pendingFormat tells the format of the pending packs setting. Packs stored
+// by pack ID always have an ID, while the packs of a chain decode as packs
+// without ID, as their keys don't match any field
+func pendingFormat(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return pendingFormatList, nil
+	}
+
+	var entries map[string]legacyStickerPack
+	err := json.Unmarshal(data, &entries)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(entries) == 0 {
+		return pendingFormatByChain, nil
+	}
+
+	for _, entry := range entries {
+		if entry.ID == nil && entry.PackID == nil {
+			return pendingFormatByChain, nil
+		}
+	}
+	return pendingFormatByID, nil
+}
+
+// decodePendingStickerPacks decodes the pending packs setting, converting
+// the legacy formats to the current one. Legacy packs are moved under their
+// recorded chain, or defaultPendingChainID
+func decodePendingStickerPacks(data []byte) (StickerPacksByChain, int, error) {
+	format, err := pendingFormat(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var legacy []legacyStickerPack
+	switch format {
+	case pendingFormatList:
+		err = json.Unmarshal(data, &legacy)
+		if err != nil {
+			return nil, 0, err
+		}
+	case pendingFormatByID:
+		var entries map[string]legacyStickerPack
+		err = json.Unmarshal(data, &entries)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, entry := range entries {
+			legacy = append(legacy, entry)
+		}
+	default:
+		stickerPacks := make(StickerPacksByChain)
+		err = json.Unmarshal(data, &stickerPacks)
+		if err != nil {
+			return nil, 0, err
+		}
+		return stickerPacks, format, nil
+	}
+
+	stickerPacks, err := migrateLegacyPending(legacy)
+	if err != nil {
+		return nil, 0, err
+	}
+	return stickerPacks, format, nil
+}
+
+func migrateLegacyPending(legacy []legacyStickerPack) (StickerPacksByChain, error) {
+	stickerPacks := make(StickerPacksByChain)
+	for _, entry := range legacy {
+		stickerPack := entry.StickerPack
+		if stickerPack.ID == nil {
+			stickerPack.ID = entry.PackID
+		}
+		if stickerPack.ID == nil {
+			return nil, errors.New("legacy pending sticker pack without ID")
+		}
+		if stickerPack.ChainID == 0 {
+			stickerPack.ChainID = defaultPendingChainID
+		}
+		stickerPacks.add(stickerPack.ChainID, stickerPack)
+	}
 	return stickerPacks, nil
 }
 
-func (api *API) Pending() (StickerPackCollection, error) {
+// migratePendingStickerPacks rewrites the pending packs setting in the
+// current format when it's stored in a legacy one. It tells whether the
+// setting was rewritten, running it again is a no-op
+func (api *API) migratePendingStickerPacks() (bool, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pendingStickersJSON, err := api.accountsDB.GetPendingStickerPacks()
+	if err != nil {
+		return false, err
+	}
+
+	if pendingStickersJSON == nil {
+		return false, nil
+	}
+
+	stickerPacks, format, err := decodePendingStickerPacks(*pendingStickersJSON)
+	if err != nil {
+		return false, wrapError(ErrPendingCorrupted, err)
+	}
+
+	if format == pendingFormatByChain {
+		return false, nil
+	}
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, stickerPacks)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info("migrated the pending sticker packs", "from", format, "to", pendingFormatByChain, "packs", stickerPacks.count())
+	return true, nil
+}
+
+// Pending returns the pending sticker packs of every chain, with their hashes
+// decoded into URLs. Hashes that can't be decoded are left without URL and
+// their pack is flagged as broken, so that other packs are still returned
+func (api *API) Pending() (StickerPacksByChain, error) {
 	stickerPacks, err := api.pendingStickerPacks()
 	if err != nil {
 		return nil, err
 	}
 
-	for packID, stickerPack := range stickerPacks {
-		stickerPack.Status = statusPending
+	for chainID, chainPacks := range stickerPacks {
+		for packID, stickerPack := range chainPacks {
+			stickerPack.Status = statusPending
+			stickerPack.ChainID = chainID
 
-		stickerPack.Preview, err = decodeStringHash(stickerPack.Preview)
-		if err != nil {
-			return nil, err
-		}
+			hashes := []string{stickerPack.Preview, stickerPack.Thumbnail}
+			for _, sticker := range stickerPack.Stickers {
+				hashes = append(hashes, sticker.Hash)
+			}
 
-		stickerPack.Thumbnail, err = decodeStringHash(stickerPack.Thumbnail)
-		if err != nil {
-			return nil, err
+			urls, errs := api.decodeHashes(hashes)
+			for i, err := range errs {
+				if err != nil {
+					log.Warn("failed to decode pending sticker pack hash", "chainID", chainID, "packID", packID, "hash", hashes[i], "error", err)
+					stickerPack.Broken = true
+				}
+			}
+
+			stickerPack.Preview = urls[0]
+			stickerPack.Thumbnail = urls[1]
+			for i := range stickerPack.Stickers {
+				stickerPack.Stickers[i].URL = urls[i+2]
+			}
+
+			chainPacks[packID] = stickerPack
 		}
+	}
+
+	return stickerPacks, nil
+}
+
+// PendingIssue is a pending pack added while its data couldn't be fetched,
+// or whose metadata is incomplete
+type PendingIssue struct {
+	ChainID    uint64         `json:"chainID"`
+	PackID     *bigint.BigInt `json:"packID"`
+	Unverified bool           `json:"unverified,omitempty"`
+	// Invalid tells what's missing from the metadata, empty when complete
+	Invalid string `json:"invalid,omitempty"`
+}
+
+// PendingIssues returns the pending packs that are unverified or whose
+// metadata is incomplete, ordered by chain ID then pack ID, so that they can
+// be added again. The pending packs aren't changed
+func (api *API) PendingIssues() ([]PendingIssue, error) {
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []PendingIssue{}
+	for chainID, chainPacks := range pendingPacks {
+		for _, stickerPack := range chainPacks {
+			stickerPack := stickerPack
+			issue := PendingIssue{ChainID: chainID, PackID: stickerPack.ID, Unverified: stickerPack.Unverified}
+			if err := validatePack(&stickerPack); err != nil {
+				issue.Invalid = err.Error()
+			}
 
-		for i, sticker := range stickerPack.Stickers {
-			sticker.URL, err = decodeStringHash(sticker.Hash)
-			if err != nil {
-				return nil, err
+			if issue.Unverified || issue.Invalid != "" {
+				issues = append(issues, issue)
 			}
-			stickerPack.Stickers[i] = sticker
 		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].ChainID != issues[j].ChainID {
+			return issues[i].ChainID < issues[j].ChainID
+		}
+		return issues[i].PackID.Cmp(issues[j].PackID.Int) < 0
+	})
 
-		stickerPacks[packID] = stickerPack
+	return issues, nil
+}
+
+// decodeHashes decodes the hashes into URLs, up to DecodeConcurrency at a
+// time. A hash that fails to be decoded gets an empty URL and its error at
+// the same index, the others are still decoded
+func (api *API) decodeHashes(hashes []string) ([]string, []error) {
+	concurrency := api.DecodeConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
 	}
 
-	return stickerPacks, nil
+	urls := make([]string, len(hashes))
+	errs := make([]error, len(hashes))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i := range hashes {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// Every goroutine writes its own index only
+			urls[i], errs[i] = api.decodeStringHash(hashes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return urls, errs
+}
+
+// PendingSplit holds the pending sticker packs added on the active chain and
+// those of the other chains
+type PendingSplit struct {
+	Active   StickerPackCollection `json:"active"`
+	Inactive StickerPacksByChain   `json:"inactive"`
+}
+
+// PendingForActiveChain splits the pending sticker packs between those added
+// on the active chain and those of the other chains
+func (api *API) PendingForActiveChain(activeChainID uint64) (PendingSplit, error) {
+	stickerPacks, err := api.Pending()
+	if err != nil {
+		return PendingSplit{}, err
+	}
+
+	active := stickerPacks[activeChainID]
+	if active == nil {
+		active = make(StickerPackCollection)
+	}
+	delete(stickerPacks, activeChainID)
+
+	return PendingSplit{Active: active, Inactive: stickerPacks}, nil
 }
 
-func (api *API) RemovePending(packID *bigint.BigInt) error {
+func (api *API) RemovePending(chainID uint64, packID *bigint.BigInt) error {
+	key, err := pendingKey(chainID, packID)
+	if err != nil {
+		return err
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
 	pendingPacks, err := api.pendingStickerPacks()
 	if err != nil {
 		return err
 	}
 
-	if _, exists := pendingPacks[uint(packID.Uint64())]; !exists {
+	removed := pendingPacks[chainID][key]
+	if !pendingPacks.remove(chainID, key) {
 		return nil
 	}
 
-	delete(pendingPacks, uint(packID.Uint64()))
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	if err != nil {
+		return err
+	}
+
+	api.evictPackContent(removed)
+
+	signal.SendStickerPackPendingRemoved(chainID, packID.String())
+	api.Events.Publish(eventbus.StickerPackRemoved, eventbus.StickerPackPayload{ChainID: chainID, PackID: packID.String()})
+
+	return nil
+}
+
+// ClearPending removes all the pending sticker packs at once and returns the
+// number of packs removed
+func (api *API) ClearPending() (int, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return 0, err
+	}
+
+	count := pendingPacks.count()
+	if count == 0 {
+		return 0, nil
+	}
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, make(StickerPacksByChain))
+	if err != nil {
+		return 0, err
+	}
+
+	api.evictPendingContent(pendingPacks)
+
+	for chainID, chainPacks := range pendingPacks {
+		for _, stickerPack := range chainPacks {
+			signal.SendStickerPackPendingRemoved(chainID, stickerPack.ID.String())
+			api.Events.Publish(eventbus.StickerPackRemoved, eventbus.StickerPackPayload{ChainID: chainID, PackID: stickerPack.ID.String()})
+		}
+	}
+
+	return count, nil
+}
+
+// PrunePending removes the pending sticker packs added more than maxAge ago,
+// as well as those without a known addition time, and returns the number of
+// packs removed
+func (api *API) PrunePending(maxAge time.Duration) (int, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge).Unix()
+
+	pruned := make(StickerPacksByChain)
+	for chainID, chainPacks := range pendingPacks {
+		for packID, stickerPack := range chainPacks {
+			if stickerPack.AddedAt < cutoff {
+				pruned.add(chainID, stickerPack)
+				pendingPacks.remove(chainID, packID)
+			}
+		}
+	}
+
+	count := pruned.count()
+	if count == 0 {
+		return 0, nil
+	}
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	if err != nil {
+		return 0, err
+	}
+
+	api.evictPendingContent(pruned)
+
+	for chainID, chainPacks := range pruned {
+		for _, stickerPack := range chainPacks {
+			signal.SendStickerPackPendingRemoved(chainID, stickerPack.ID.String())
+			api.Events.Publish(eventbus.StickerPackRemoved, eventbus.StickerPackPayload{ChainID: chainID, PackID: stickerPack.ID.String()})
+		}
+	}
 
-	return api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	return count, nil
 }
@@ -0,0 +1,610 @@
+package stickers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/status-im/status-go/eventbus"
+	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
+)
+
+func TestPendingChangedSignals(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var events []signal.StickerPackPendingChangedSignal
+	signal.SetMobileSignalHandler(func(data []byte) {
+		var envelope struct {
+			Type  string                                 `json:"type"`
+			Event signal.StickerPackPendingChangedSignal `json:"event"`
+		}
+		require.NoError(t, json.Unmarshal(data, &envelope))
+		if envelope.Type == signal.EventStickerPackPendingChanged {
+			events = append(events, envelope.Event)
+		}
+	})
+	defer signal.SetMobileSignalHandler(nil)
+
+	s.publishPack(t, 1, "first", 10, 2)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.Error(t, s.api.AddPending(testChainID, packID(1)))
+	require.NoError(t, s.api.RemovePending(testChainID, packID(1)))
+	require.NoError(t, s.api.RemovePending(testChainID, packID(1)))
+
+	require.Equal(t, []signal.StickerPackPendingChangedSignal{
+		{PackID: "1", ChainID: testChainID, Action: signal.StickerPackPendingAdded},
+		{PackID: "1", ChainID: testChainID, Action: signal.StickerPackPendingRemoved},
+	}, events)
+}
+
+func TestClearPending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	removed, err := s.api.ClearPending()
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+
+	for id := uint64(1); id <= 3; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+
+	stored, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	s.cachePackContent(t, stored[testChainID][1])
+
+	removed, err = s.api.ClearPending()
+	require.NoError(t, err)
+	require.Equal(t, 3, removed)
+	require.Zero(t, s.api.content.Size())
+
+	pending, err := s.api.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+// cachePackContent caches content for every hash of the pack
+func (s *testSetup) cachePackContent(t *testing.T, stickerPack StickerPack) {
+	for _, entry := range packContents(&stickerPack) {
+		s.api.cacheContent(entry.Hash, []byte(entry.Hash))
+	}
+}
+
+// packContentCached tells whether content is cached for any hash of the pack
+func (s *testSetup) packContentCached(stickerPack StickerPack) bool {
+	for _, entry := range packContents(&stickerPack) {
+		if _, ok := s.api.cachedContent(entry.Hash); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAddPendingUnknownPack(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	err := s.api.AddPending(testChainID, packID(42))
+	require.True(t, errors.Is(err, ErrPackNotFound))
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestPendingInvalidIDs(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 0, "pack", 10, 1)
+	require.NoError(t, s.api.AddPending(testChainID, packID(0)))
+
+	// Would alias pack 0 when truncated to the key
+	oversized := &bigint.BigInt{Int: new(big.Int).Lsh(big.NewInt(1), 64)}
+	negative := &bigint.BigInt{Int: big.NewInt(-1)}
+
+	for _, id := range []*bigint.BigInt{nil, {}, oversized, negative} {
+		require.True(t, errors.Is(s.api.AddPending(testChainID, id), ErrInvalidPackID))
+		require.True(t, errors.Is(s.api.AddPendingCached(testChainID, id), ErrInvalidPackID))
+		require.True(t, errors.Is(s.api.RemovePending(testChainID, id), ErrInvalidPackID))
+	}
+
+	require.True(t, errors.Is(s.api.AddPending(0, packID(0)), ErrInvalidChainID))
+	require.True(t, errors.Is(s.api.RemovePending(0, packID(0)), ErrInvalidChainID))
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, pending[testChainID], 1)
+	require.Contains(t, pending[testChainID], uint(0))
+}
+
+func TestAddPendingPrefetch(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	stickerPack := pending[testChainID][1]
+	_, cached := s.api.cachedContent(stickerPack.Preview)
+	require.False(t, cached)
+
+	s.api.PrefetchPending = true
+	require.NoError(t, s.api.RemovePending(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.Eventually(t, func() bool {
+		_, preview := s.api.cachedContent(stickerPack.Preview)
+		_, thumbnail := s.api.cachedContent(stickerPack.Thumbnail)
+		return preview && thumbnail
+	}, 5*time.Second, 10*time.Millisecond)
+	_, cached = s.api.cachedContent(stickerPack.Stickers[0].Hash)
+	require.False(t, cached)
+
+	// Content that can't be prefetched doesn't fail the add
+	unpublished := &fakeIPFS{content: make(map[string][]byte)}
+	meta := ednStickerPack{
+		Name:      "unpublished",
+		Preview:   unpublished.add(t, []byte("unpublished preview")),
+		Thumbnail: s.ipfs.add(t, []byte("published thumbnail")),
+		Stickers:  []ednSticker{{Hash: s.ipfs.add(t, []byte("sticker"))}},
+	}
+	s.publishMeta(t, 2, 10, meta)
+	require.NoError(t, s.api.AddPending(testChainID, packID(2)))
+	require.Eventually(t, func() bool {
+		_, cached := s.api.cachedContent(meta.Thumbnail)
+		return cached
+	}, 5*time.Second, 10*time.Millisecond)
+	_, cached = s.api.cachedContent(meta.Preview)
+	require.False(t, cached)
+
+	pending, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Contains(t, pending[testChainID], uint(2))
+}
+
+func TestConcurrentAddPending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	const numPacks = 10
+	for id := uint64(1); id <= numPacks; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+	}
+
+	var wg sync.WaitGroup
+	for id := uint64(1); id <= numPacks; id++ {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+			require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+		}(id)
+	}
+	wg.Wait()
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, pending[testChainID], numPacks)
+}
+
+func TestPendingEvents(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.Events = eventbus.New()
+	added := s.api.Events.Subscribe(eventbus.StickerPackAdded)
+	removed := s.api.Events.Subscribe(eventbus.StickerPackRemoved)
+
+	s.publishPack(t, 1, "first", 10, 1)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+
+	select {
+	case event := <-added:
+		require.Equal(t, eventbus.StickerPackPayload{ChainID: testChainID, PackID: "1"}, event.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("pack added event was not delivered")
+	}
+	require.Empty(t, removed)
+
+	require.NoError(t, s.api.RemovePending(testChainID, packID(1)))
+	require.Equal(t, eventbus.StickerPackPayload{ChainID: testChainID, PackID: "1"}, (<-removed).Payload)
+}
+
+func TestPrunePending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(1); id <= 3; id++ {
+		s.publishPack(t, id, fmt.Sprintf("pack %d", id), 10, 1)
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	for _, stickerPack := range pending[testChainID] {
+		require.NotZero(t, stickerPack.AddedAt)
+		s.cachePackContent(t, stickerPack)
+	}
+
+	// Pack 2 is stale and pack 3 was stored before timestamps were tracked
+	stale := pending[testChainID][2]
+	stale.AddedAt = time.Now().Add(-2 * time.Hour).Unix()
+	pending[testChainID][2] = stale
+	legacy := pending[testChainID][3]
+	legacy.AddedAt = 0
+	pending[testChainID][3] = legacy
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, pending))
+
+	pruned, err := s.api.PrunePending(time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+
+	pending, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, pending[testChainID], 1)
+	require.Contains(t, pending[testChainID], uint(1))
+
+	// The content of the pruned packs is evicted
+	require.True(t, s.packContentCached(pending[testChainID][1]))
+	require.False(t, s.packContentCached(stale))
+	require.False(t, s.packContentCached(legacy))
+
+	pruned, err = s.api.PrunePending(time.Hour)
+	require.NoError(t, err)
+	require.Zero(t, pruned)
+}
+
+func TestDecodeHashes(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.DecodeConcurrency = 4
+
+	var hashes []string
+	for i := 0; i < 50; i++ {
+		hashes = append(hashes, s.ipfs.add(t, []byte(fmt.Sprintf("sticker %d", i))))
+	}
+	hashes[10] = "zz"
+
+	urls, errs := s.api.decodeHashes(hashes)
+	require.Len(t, urls, len(hashes))
+	require.Len(t, errs, len(hashes))
+
+	for i, hash := range hashes {
+		if i == 10 {
+			require.Error(t, errs[i])
+			require.Empty(t, urls[i])
+			continue
+		}
+		url, err := s.api.decodeStringHash(hash)
+		require.NoError(t, err)
+		require.NoError(t, errs[i])
+		require.Equal(t, url, urls[i])
+	}
+}
+
+func TestPendingWithBrokenHash(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "first", 10, 3)
+	s.publishPack(t, 2, "second", 10, 3)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(2)))
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	broken := pending[testChainID][2]
+	broken.Stickers[1].Hash = "zz"
+	pending[testChainID][2] = broken
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, pending))
+
+	decoded, err := s.api.Pending()
+	require.NoError(t, err)
+
+	first := decoded[testChainID][1]
+	require.False(t, first.Broken)
+	for _, sticker := range first.Stickers {
+		require.NotEmpty(t, sticker.URL)
+	}
+
+	second := decoded[testChainID][2]
+	require.True(t, second.Broken)
+	require.NotEmpty(t, second.Preview)
+	require.NotEmpty(t, second.Thumbnail)
+	require.NotEmpty(t, second.Stickers[0].URL)
+	require.Empty(t, second.Stickers[1].URL)
+	require.NotEmpty(t, second.Stickers[2].URL)
+}
+
+func TestPendingIssues(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	issues, err := s.api.PendingIssues()
+	require.NoError(t, err)
+	require.Empty(t, issues)
+
+	for id := uint64(1); id <= 3; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	incomplete := pending[testChainID][2]
+	incomplete.Thumbnail = ""
+	pending[testChainID][2] = incomplete
+	unverified := pending[testChainID][3]
+	unverified.Unverified = true
+	pending.remove(testChainID, 3)
+	pending.add(3, unverified)
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, pending))
+	stored, err := s.api.accountsDB.GetPendingStickerPacks()
+	require.NoError(t, err)
+
+	issues, err = s.api.PendingIssues()
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Equal(t, uint64(testChainID), issues[0].ChainID)
+	require.Equal(t, uint64(2), issues[0].PackID.Uint64())
+	require.False(t, issues[0].Unverified)
+	require.Contains(t, issues[0].Invalid, "no thumbnail")
+	require.Equal(t, uint64(3), issues[1].ChainID)
+	require.Equal(t, uint64(3), issues[1].PackID.Uint64())
+	require.True(t, issues[1].Unverified)
+	require.Empty(t, issues[1].Invalid)
+
+	unchanged, err := s.api.accountsDB.GetPendingStickerPacks()
+	require.NoError(t, err)
+	require.Equal(t, *stored, *unchanged)
+}
+
+func TestPendingForActiveChain(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	for id := uint64(1); id <= 4; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+	}
+	require.NoError(t, s.api.AddPending(1, packID(1)))
+	require.NoError(t, s.api.AddPending(1, packID(2)))
+	require.NoError(t, s.api.AddPending(3, packID(3)))
+	require.NoError(t, s.api.AddPending(5, packID(4)))
+
+	split, err := s.api.PendingForActiveChain(1)
+	require.NoError(t, err)
+	require.Len(t, split.Active, 2)
+	require.Contains(t, split.Active, uint(1))
+	require.Contains(t, split.Active, uint(2))
+	require.Equal(t, 2, split.Inactive.count())
+	require.Contains(t, split.Inactive[3], uint(3))
+	require.Contains(t, split.Inactive[5], uint(4))
+	require.Equal(t, statusPending, split.Active[1].Status)
+
+	split, err = s.api.PendingForActiveChain(3)
+	require.NoError(t, err)
+	require.Len(t, split.Active, 1)
+	require.Contains(t, split.Active, uint(3))
+	require.Equal(t, 3, split.Inactive.count())
+
+	split, err = s.api.PendingForActiveChain(7)
+	require.NoError(t, err)
+	require.Empty(t, split.Active)
+
+	// The method is exposed in the stickers RPC namespace
+	server := gethrpc.NewServer()
+	for _, api := range (&Service{api: s.api}).APIs() {
+		require.NoError(t, server.RegisterName(api.Namespace, api.Service))
+	}
+	client := gethrpc.DialInProc(server)
+	defer client.Close()
+
+	var result PendingSplit
+	require.NoError(t, client.Call(&result, "stickers_pendingForActiveChain", 1))
+	require.Len(t, result.Active, 2)
+	require.Contains(t, result.Active, uint(1))
+	require.Equal(t, 2, result.Inactive.count())
+}
+
+func TestPendingOnMultipleChains(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+	require.NoError(t, s.api.AddPending(1, packID(1)))
+	// The same pack ID on another chain doesn't replace the first one
+	require.NoError(t, s.api.AddPending(3, packID(1)))
+	require.Error(t, s.api.AddPending(3, packID(1)))
+
+	pending, err := s.api.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, uint64(1), pending[1][1].ChainID)
+	require.Equal(t, uint64(3), pending[3][1].ChainID)
+
+	require.NoError(t, s.api.RemovePending(3, packID(1)))
+	pending, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Contains(t, pending[1], uint(1))
+}
+
+func TestMigrateLegacyPending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	// Packs were keyed by pack ID only, some with their chain recorded
+	legacy := StickerPackCollection{
+		1: {ID: packID(1), Name: "first", ChainID: 3},
+		2: {ID: packID(2), Name: "second"},
+	}
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, legacy))
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Equal(t, 2, pending.count())
+	require.Equal(t, "first", pending[3][1].Name)
+	require.Equal(t, "second", pending[defaultPendingChainID][2].Name)
+	require.Equal(t, uint64(defaultPendingChainID), pending[defaultPendingChainID][2].ChainID)
+
+	// The migrated packs are saved in the new format with the next change
+	require.NoError(t, s.api.RemovePending(3, packID(1)))
+	pendingJSON, err := s.api.accountsDB.GetPendingStickerPacks()
+	require.NoError(t, err)
+	var stored StickerPacksByChain
+	require.NoError(t, json.Unmarshal(*pendingJSON, &stored))
+	require.Len(t, stored, 1)
+	require.Equal(t, "second", stored[defaultPendingChainID][2].Name)
+
+	pending, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Equal(t, stored, pending)
+
+	// An empty setting decodes the same in both formats
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, StickerPackCollection{}))
+	pending, err = s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestMigratePendingFormats(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	expected := StickerPacksByChain{
+		1: {
+			1: {ID: packID(1), Name: "first", Preview: "e301", ChainID: 1},
+			2: {ID: packID(2), Name: "second", ChainID: 1},
+		},
+		3: {
+			3: {ID: packID(3), Name: "third", ChainID: 3},
+		},
+	}
+
+	for name, legacy := range map[string]string{
+		"list": `[
+			{"id": 1, "name": "first", "preview": "e301"},
+			{"packID": 2, "name": "second"},
+			{"id": 3, "name": "third", "chainID": 3}
+		]`,
+		"by pack ID": `{
+			"1": {"id": 1, "name": "first", "preview": "e301"},
+			"2": {"packID": 2, "name": "second"},
+			"3": {"id": 3, "name": "third", "chainID": 3}
+		}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, json.RawMessage(legacy)))
+
+			// Legacy packs are readable before the migration
+			pending, err := s.api.pendingStickerPacks()
+			require.NoError(t, err)
+			require.Equal(t, expected, pending)
+
+			migrated, err := s.api.migratePendingStickerPacks()
+			require.NoError(t, err)
+			require.True(t, migrated)
+
+			pendingJSON, err := s.api.accountsDB.GetPendingStickerPacks()
+			require.NoError(t, err)
+			format, err := pendingFormat(*pendingJSON)
+			require.NoError(t, err)
+			require.Equal(t, pendingFormatByChain, format)
+
+			pending, err = s.api.pendingStickerPacks()
+			require.NoError(t, err)
+			require.Equal(t, expected, pending)
+
+			migrated, err = s.api.migratePendingStickerPacks()
+			require.NoError(t, err)
+			require.False(t, migrated)
+
+			unchanged, err := s.api.accountsDB.GetPendingStickerPacks()
+			require.NoError(t, err)
+			require.JSONEq(t, string(*pendingJSON), string(*unchanged))
+		})
+	}
+
+	// Packs without any ID can't be migrated
+	require.NoError(t, s.api.accountsDB.SaveSettingField(settings.StickersPacksPending, json.RawMessage(`[{"name": "anonymous"}]`)))
+	_, err := s.api.migratePendingStickerPacks()
+	require.Error(t, err)
+}
+
+func TestPendingCap(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.MaxPendingPacks = 2
+	for id := uint64(1); id <= 3; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+	}
+
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.NoError(t, s.api.AddPending(testChainID, packID(2)))
+	require.True(t, errors.Is(s.api.AddPending(testChainID, packID(3)), ErrTooManyPending))
+}
+
+func TestPlanBatchAdd(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.MaxPendingPacks = 3
+	s.publishPack(t, 1, "pack", 10, 1)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	calls := s.contract.calls
+
+	plan, err := s.api.PlanBatchAdd(testChainID, nil)
+	require.NoError(t, err)
+	require.Empty(t, plan.Add)
+
+	// Two slots are left: 2 and 3 fill them, 1 is pending, the second 2 is
+	// repeated and 4 exceeds the cap
+	plan, err = s.api.PlanBatchAdd(testChainID, []*bigint.BigInt{packID(1), packID(2), packID(3), packID(2), packID(4)})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3}, uint64s(plan.Add))
+	require.Equal(t, []uint64{1, 2}, uint64s(plan.Skip))
+	require.Equal(t, []uint64{4}, uint64s(plan.Reject))
+
+	// Planning doesn't fetch packs nor change the pending packs
+	require.Equal(t, calls, s.contract.calls)
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Equal(t, 1, pending.count())
+
+	// Packs pending on another chain don't count as pending on this one
+	plan, err = s.api.PlanBatchAdd(3, []*bigint.BigInt{packID(1)})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, uint64s(plan.Add))
+
+	s.api.MaxPendingPacks = 0
+	plan, err = s.api.PlanBatchAdd(testChainID, []*bigint.BigInt{packID(2), packID(3), packID(4)})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3, 4}, uint64s(plan.Add))
+	require.Empty(t, plan.Reject)
+
+	// Invalid pack IDs, like a JSON null, are rejected
+	var ids []*bigint.BigInt
+	require.NoError(t, json.Unmarshal([]byte(`[null, "2"]`), &ids))
+	oversized := &bigint.BigInt{Int: new(big.Int).Lsh(big.NewInt(1), 64)}
+	plan, err = s.api.PlanBatchAdd(testChainID, append(ids, oversized))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2}, uint64s(plan.Add))
+	require.Equal(t, []*bigint.BigInt{nil, oversized}, plan.Reject)
+
+	_, err = s.api.PlanBatchAdd(0, []*bigint.BigInt{packID(2)})
+	require.True(t, errors.Is(err, ErrInvalidChainID))
+}
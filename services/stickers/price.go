@@ -0,0 +1,154 @@
+package stickers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/status-im/status-go/services/wallet/bigint"
+)
+
+// Sticker packs are sold for SNT, whose amounts have 18 decimals
+const priceTokenSymbol = "SNT"
+const priceTokenDecimals = 18
+
+// Prices can be changed by the pack owners, so they are only reused briefly
+const defaultPriceCacheTTL = time.Minute
+
+var ErrNoExchangeRate = errors.New("no exchange rate available")
+
+// ExchangeRateSource provides the value of one token in a fiat currency
+type ExchangeRateSource interface {
+	Rate(symbol string, currency string) (float64, error)
+}
+
+// FiatPrice is the price of a sticker pack in token base units, along with
+// its estimated value in a fiat currency
+type FiatPrice struct {
+	Token    *bigint.BigInt `json:"token"`
+	Symbol   string         `json:"symbol"`
+	Fiat     float64        `json:"fiat"`
+	Currency string         `json:"currency"`
+}
+
+// PackPriceInFiat reads the price of the pack on chain, unless it was read
+// less than PriceCacheTTL ago, and converts it to currency with the
+// ExchangeRates source. ErrNoExchangeRate is returned when no rate is
+// available
+func (api *API) PackPriceInFiat(chainID uint64, packID *bigint.BigInt, currency string) (FiatPrice, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return FiatPrice{}, err
+	}
+
+	packData, err := api.currentPackData(api.ctx, chainID, stickerType, packID.Int)
+	if err != nil {
+		return FiatPrice{}, err
+	}
+
+	if !packExists(packData) {
+		return FiatPrice{}, ErrPackNotFound
+	}
+
+	rate, err := api.exchangeRate(currency)
+	if err != nil {
+		return FiatPrice{}, err
+	}
+
+	return FiatPrice{
+		Token:    &bigint.BigInt{Int: packData.Price},
+		Symbol:   priceTokenSymbol,
+		Fiat:     toFiat(packData.Price, rate),
+		Currency: currency,
+	}, nil
+}
+
+// currentPackData returns the cached pack data when it was read less than
+// PriceCacheTTL ago, otherwise it reads it from the contract and caches it
+func (api *API) currentPackData(ctx context.Context, chainID uint64, stickerType stickerTypeContract, packID *big.Int) (stickerPackData, error) {
+	api.packDataLock.Lock()
+	entry, cached := api.packData[packKey{chainID: chainID, packID: packID.Uint64()}]
+	api.packDataLock.Unlock()
+	if cached && time.Since(entry.readTime) < api.PriceCacheTTL {
+		return entry.data, nil
+	}
+
+	packData, err := api.getPackDataContext(ctx, stickerType, packID)
+	if err != nil {
+		return packData, err
+	}
+
+	if packExists(packData) {
+		api.cachePackData(chainID, packID, packData)
+	}
+
+	return packData, nil
+}
+
+// RefreshPrices invalidates the cached prices of the packs of a chain and
+// reads them again from the contract. It stops when ctx is done, the packs
+// not read yet being read again on their next use
+func (api *API) RefreshPrices(ctx context.Context, chainID uint64) error {
+	var packIDs []uint64
+	api.packDataLock.Lock()
+	for key, entry := range api.packData {
+		if key.chainID == chainID {
+			entry.readTime = time.Time{}
+			api.packData[key] = entry
+			packIDs = append(packIDs, key.packID)
+		}
+	}
+	api.packDataLock.Unlock()
+
+	if len(packIDs) == 0 {
+		return nil
+	}
+	sort.Slice(packIDs, func(i, j int) bool { return packIDs[i] < packIDs[j] })
+
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return err
+	}
+
+	for _, packID := range packIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		_, err := api.currentPackData(ctx, chainID, stickerType, new(big.Int).SetUint64(packID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (api *API) exchangeRate(currency string) (float64, error) {
+	if api.ExchangeRates == nil {
+		return 0, fmt.Errorf("%w: no exchange rate source", ErrNoExchangeRate)
+	}
+
+	rate, err := api.ExchangeRates.Rate(priceTokenSymbol, currency)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s/%s: %v", ErrNoExchangeRate, priceTokenSymbol, currency, err)
+	}
+
+	// A zero rate is what sources return for unknown pairs
+	if rate <= 0 {
+		return 0, fmt.Errorf("%w: %s/%s", ErrNoExchangeRate, priceTokenSymbol, currency)
+	}
+
+	return rate, nil
+}
+
+// toFiat converts an amount in token base units with the given rate
+func toFiat(amount *big.Int, rate float64) float64 {
+	units := new(big.Float).SetInt(amount)
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(priceTokenDecimals), nil))
+	value, _ := new(big.Float).Mul(new(big.Float).Quo(units, scale), big.NewFloat(rate)).Float64()
+	return value
+}
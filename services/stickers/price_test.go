@@ -0,0 +1,94 @@
+package stickers
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRates map[string]float64
+
+func (f fakeRates) Rate(symbol string, currency string) (float64, error) {
+	rate, ok := f[symbol+"/"+currency]
+	if !ok {
+		return 0, errors.New("unknown pair")
+	}
+	return rate, nil
+}
+
+func TestPackPriceInFiat(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	// 2.5 SNT
+	s.publishPack(t, 1, "pack", 2500000000000000000, 1)
+
+	_, err := s.api.PackPriceInFiat(testChainID, packID(1), "USD")
+	require.True(t, errors.Is(err, ErrNoExchangeRate))
+
+	s.api.ExchangeRates = fakeRates{"SNT/USD": 0.04, "SNT/EUR": 0}
+
+	fiat, err := s.api.PackPriceInFiat(testChainID, packID(1), "USD")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(2500000000000000000), fiat.Token.Int)
+	require.Equal(t, "SNT", fiat.Symbol)
+	require.Equal(t, "USD", fiat.Currency)
+	require.InDelta(t, 0.1, fiat.Fiat, 1e-9)
+
+	_, err = s.api.PackPriceInFiat(testChainID, packID(1), "EUR")
+	require.True(t, errors.Is(err, ErrNoExchangeRate))
+
+	_, err = s.api.PackPriceInFiat(testChainID, packID(1), "CHF")
+	require.True(t, errors.Is(err, ErrNoExchangeRate))
+
+	_, err = s.api.PackPriceInFiat(testChainID, packID(2), "USD")
+	require.True(t, errors.Is(err, ErrPackNotFound))
+}
+
+func TestPackPriceCache(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.ExchangeRates = fakeRates{"SNT/USD": 1}
+	s.publishPack(t, 0, "other", 10, 1)
+	s.publishPack(t, 1, "pack", 10, 1)
+
+	fiat, err := s.api.PackPriceInFiat(testChainID, packID(1), "USD")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10), fiat.Token.Int)
+	require.Equal(t, 1, s.contract.calls)
+
+	// The cached price is returned within the TTL, even though it changed
+	s.publishPack(t, 1, "pack", 20, 1)
+	fiat, err = s.api.PackPriceInFiat(testChainID, packID(1), "USD")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(10), fiat.Token.Int)
+	require.Equal(t, 1, s.contract.calls)
+
+	packs, err := s.api.MarketPage(context.Background(), testChainID, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	require.Equal(t, big.NewInt(10), packs[0].Price.Int)
+	require.Equal(t, 1, s.contract.calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, s.api.RefreshPrices(ctx, testChainID))
+	require.Equal(t, 1, s.contract.calls)
+
+	require.NoError(t, s.api.RefreshPrices(context.Background(), testChainID))
+	require.Equal(t, 2, s.contract.calls)
+	fiat, err = s.api.PackPriceInFiat(testChainID, packID(1), "USD")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(20), fiat.Token.Int)
+	require.Equal(t, 2, s.contract.calls)
+
+	// Disabling the cache reads the price on every call
+	s.api.PriceCacheTTL = 0
+	_, err = s.api.PackPriceInFiat(testChainID, packID(1), "USD")
+	require.NoError(t, err)
+	require.Equal(t, 3, s.contract.calls)
+}
@@ -0,0 +1,151 @@
+package stickers
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/eventbus"
+	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
+)
+
+// ownedPacks returns which of the given packs are owned by account. Contracts
+// tracking a balance per pack are queried for these packs only, otherwise
+// all the packs purchased by the account are listed
+func (api *API) ownedPacks(ctx context.Context, chainID uint64, account types.Address, packIDs []uint) (map[uint]bool, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[uint]bool)
+
+	balances, ok := stickerType.(packBalanceContract)
+	if !ok {
+		purchased, err := api.getPurchasedPackIDs(chainID, account)
+		if err != nil {
+			return nil, err
+		}
+		for _, packID := range purchased {
+			owned[uint(packID.Uint64())] = true
+		}
+		return owned, ctx.Err()
+	}
+
+	callOpts := &bind.CallOpts{Context: ctx, Pending: false}
+	for _, packID := range packIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err = api.waitRateLimit(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var balance *big.Int
+		err = api.retry(ctx, func() error {
+			var err error
+			balance, err = balances.BalanceOf(callOpts, common.Address(account), new(big.Int).SetUint64(uint64(packID)))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if balance.Sign() > 0 {
+			owned[packID] = true
+		}
+	}
+
+	return owned, nil
+}
+
+// ReconcilePurchased installs the pending packs of the chain whose purchase
+// by account is confirmed on-chain and removes them from the pending packs.
+// Packs not owned yet stay pending. It returns the IDs of the installed packs
+func (api *API) ReconcilePurchased(ctx context.Context, chainID uint64, account types.Address) ([]*bigint.BigInt, error) {
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pendingPacks[chainID]) == 0 {
+		return nil, nil
+	}
+
+	packIDs := make([]uint, 0, len(pendingPacks[chainID]))
+	for packID := range pendingPacks[chainID] {
+		packIDs = append(packIDs, packID)
+	}
+
+	owned, err := api.ownedPacks(ctx, chainID, account, packIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(owned) == 0 {
+		return nil, nil
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	// Pending packs might have changed while the contract was queried
+	pendingPacks, err = api.pendingStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	installedPacks, err := api.installedStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []*bigint.BigInt
+	for packID := range owned {
+		stickerPack, exists := pendingPacks[chainID][packID]
+		if !exists {
+			continue
+		}
+
+		pendingPacks.remove(chainID, packID)
+		if _, exists := installedPacks[packID]; !exists {
+			stickerPack.AddedAt = 0
+			stickerPack.ChainID = 0
+			stickerPack.Status = 0
+			installedPacks[packID] = stickerPack
+		}
+		installed = append(installed, stickerPack.ID)
+	}
+
+	if len(installed) == 0 {
+		return nil, nil
+	}
+	sort.Slice(installed, func(i, j int) bool { return installed[i].Cmp(installed[j].Int) < 0 })
+
+	// Installed packs are saved first, so that a pack is never lost if saving
+	// the pending packs fails
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksInstalled, installedPacks)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, pendingPacks)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, packID := range installed {
+		signal.SendStickerPackPendingRemoved(chainID, packID.String())
+		signal.SendStickerPackInstalled(chainID, packID.String(), nil)
+		api.Events.Publish(eventbus.StickerPackRemoved, eventbus.StickerPackPayload{ChainID: chainID, PackID: packID.String()})
+	}
+
+	return installed, nil
+}
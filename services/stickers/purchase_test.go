@@ -0,0 +1,88 @@
+package stickers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/status-im/status-go/eth-node/types"
+	"github.com/status-im/status-go/services/wallet/bigint"
+	"github.com/status-im/status-go/signal"
+)
+
+func TestReconcilePurchased(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var installedSignals []string
+	var mu sync.Mutex
+	signal.SetMobileSignalHandler(func(data []byte) {
+		var envelope struct {
+			Type  string          `json:"type"`
+			Event json.RawMessage `json:"event"`
+		}
+		require.NoError(t, json.Unmarshal(data, &envelope))
+		if envelope.Type != signal.EventStickerPackInstalled {
+			return
+		}
+
+		var event signal.StickerPackInstalledSignal
+		require.NoError(t, json.Unmarshal(envelope.Event, &event))
+		mu.Lock()
+		defer mu.Unlock()
+		installedSignals = append(installedSignals, event.PackID)
+	})
+	defer signal.SetMobileSignalHandler(nil)
+
+	for id := uint64(0); id < 3; id++ {
+		s.publishPack(t, id, "pack", 10, 1)
+	}
+	contract := setupERC1155(t, s)
+	for id := uint64(0); id < 3; id++ {
+		require.NoError(t, s.api.AddPending(testChainID, packID(id)))
+	}
+
+	account := types.HexToAddress("0x02")
+
+	installed, err := s.api.ReconcilePurchased(context.Background(), testChainID, account)
+	require.NoError(t, err)
+	require.Empty(t, installed)
+
+	// The purchases of packs 0 and 2 are confirmed
+	contract.balances[common.Address(account)] = map[uint64]int64{0: 1, 2: 1}
+
+	installed, err = s.api.ReconcilePurchased(context.Background(), testChainID, account)
+	require.NoError(t, err)
+	require.Equal(t, []*bigint.BigInt{packID(0), packID(2)}, installed)
+
+	pending, err := s.api.pendingStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, pending[testChainID], 1)
+	require.Contains(t, pending[testChainID], uint(1))
+
+	installedPacks, err := s.api.installedStickerPacks()
+	require.NoError(t, err)
+	require.Len(t, installedPacks, 2)
+	require.Contains(t, installedPacks, uint(0))
+	require.Contains(t, installedPacks, uint(2))
+	require.Zero(t, installedPacks[0].AddedAt)
+	require.Zero(t, installedPacks[0].ChainID)
+
+	mu.Lock()
+	require.ElementsMatch(t, []string{"0", "2"}, installedSignals)
+	mu.Unlock()
+
+	// Nothing changes once reconciled
+	installed, err = s.api.ReconcilePurchased(context.Background(), testChainID, account)
+	require.NoError(t, err)
+	require.Empty(t, installed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.api.ReconcilePurchased(ctx, testChainID, account)
+	require.Equal(t, context.Canceled, err)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 
 	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/services/wallet/bigint"
 )
 
 const maxNumberRecentStickers = 24
@@ -29,6 +30,9 @@ func (api *API) recentStickers() ([]Sticker, error) {
 }
 
 func (api *API) ClearRecent() error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
 	var recentStickersList []Sticker
 	return api.accountsDB.SaveSettingField(settings.StickersRecentStickers, recentStickersList)
 }
@@ -40,7 +44,7 @@ func (api *API) Recent() ([]Sticker, error) {
 	}
 
 	for i, sticker := range recentStickersList {
-		sticker.URL, err = decodeStringHash(sticker.Hash)
+		sticker.URL, err = api.decodeStringHash(sticker.Hash)
 		if err != nil {
 			return nil, err
 		}
@@ -51,6 +55,15 @@ func (api *API) Recent() ([]Sticker, error) {
 }
 
 func (api *API) AddRecent(sticker Sticker) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return api.addRecent(sticker)
+}
+
+// addRecent moves the sticker to the front of the recent stickers, the caller
+// must hold api.mu
+func (api *API) addRecent(sticker Sticker) error {
 	recentStickersList, err := api.recentStickers()
 	if err != nil {
 		return err
@@ -77,3 +90,33 @@ func (api *API) AddRecent(sticker Sticker) error {
 
 	return api.accountsDB.SaveSettingField(settings.StickersRecentStickers, recentStickersList)
 }
+
+// TrackRecentSticker marks a sticker as the most recently used one and counts
+// its use, see StickerUsage
+func (api *API) TrackRecentSticker(packID *bigint.BigInt, stickerHash string) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	sticker := Sticker{PackID: packID, Hash: stickerHash}
+	err := api.addRecent(sticker)
+	if err != nil {
+		return err
+	}
+
+	return api.countStickerUsage(sticker)
+}
+
+// RecentStickers returns up to limit recently used stickers, most recent
+// first. A non positive limit returns all of them
+func (api *API) RecentStickers(limit int) ([]Sticker, error) {
+	recentStickersList, err := api.Recent()
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(recentStickersList) > limit {
+		recentStickersList = recentStickersList[:limit]
+	}
+
+	return recentStickersList, nil
+}
@@ -0,0 +1,57 @@
+package stickers
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentStickers(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var hashes []string
+	for i := 0; i < maxNumberRecentStickers+2; i++ {
+		hashes = append(hashes, s.ipfs.add(t, []byte(fmt.Sprintf("sticker %d", i))))
+	}
+
+	for _, hash := range hashes {
+		require.NoError(t, s.api.TrackRecentSticker(packID(1), hash))
+	}
+	// Using a sticker again moves it to the front instead of duplicating it
+	require.NoError(t, s.api.TrackRecentSticker(packID(1), hashes[5]))
+
+	recent, err := s.api.RecentStickers(0)
+	require.NoError(t, err)
+	require.Len(t, recent, maxNumberRecentStickers)
+	require.Equal(t, hashes[5], recent[0].Hash)
+	require.Equal(t, hashes[len(hashes)-1], recent[1].Hash)
+	require.NotEmpty(t, recent[0].URL)
+
+	recent, err = s.api.RecentStickers(3)
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+	require.Equal(t, hashes[5], recent[0].Hash)
+}
+
+func TestAddRecentConcurrently(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.NoError(t, s.api.AddRecent(Sticker{PackID: packID(1), Hash: fmt.Sprintf("sticker %d", i)}))
+		}(i)
+	}
+	wg.Wait()
+
+	// No sticker is lost to a concurrent read-modify-write
+	recent, err := s.api.recentStickers()
+	require.NoError(t, err)
+	require.Len(t, recent, 10)
+}
@@ -0,0 +1,84 @@
+package stickers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/multiaccounts/settings"
+)
+
+// ErrPendingCorrupted is returned when the pending packs setting can't be
+// decoded, see RepairPending
+var ErrPendingCorrupted = errors.New("pending sticker packs setting is corrupted")
+
+// corruptedPending handles a pending packs setting that can't be decoded.
+// Unless RecoverCorruptedPending is set, it fails with ErrPendingCorrupted.
+// Otherwise the setting is backed up and no packs are returned, the setting
+// being replaced by the next change of the pending packs
+func (api *API) corruptedPending(data []byte, decodeErr error) (StickerPacksByChain, error) {
+	if !api.RecoverCorruptedPending {
+		return nil, wrapError(ErrPendingCorrupted, decodeErr)
+	}
+
+	log.Error("pending sticker packs setting is corrupted, ignoring it", "error", decodeErr)
+
+	err := api.backupPending(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return make(StickerPacksByChain), nil
+}
+
+// backupPending keeps the raw pending packs setting, which isn't necessarily
+// valid JSON, as a JSON string in the pending packs backup setting
+func (api *API) backupPending(data []byte) error {
+	backupJSON, err := api.accountsDB.GetPendingStickerPacksBackup()
+	if err != nil {
+		return err
+	}
+
+	// The setting is read often, it's only backed up once
+	if backupJSON != nil {
+		var backup string
+		if json.Unmarshal(*backupJSON, &backup) == nil && backup == string(data) {
+			return nil
+		}
+	}
+
+	return api.accountsDB.SaveSettingField(settings.StickersPacksPendingBackup, string(data))
+}
+
+// RepairPending resets the pending packs setting to no pending packs when it
+// can't be decoded, after backing it up, even if RecoverCorruptedPending isn't
+// set. The packs have to be added again. It tells whether the setting was
+// repaired
+func (api *API) RepairPending() (bool, error) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pendingStickersJSON, err := api.accountsDB.GetPendingStickerPacks()
+	if err != nil || pendingStickersJSON == nil {
+		return false, err
+	}
+
+	_, _, err = decodePendingStickerPacks(*pendingStickersJSON)
+	if err == nil {
+		return false, nil
+	}
+
+	log.Warn("repairing corrupted pending sticker packs setting", "error", err)
+
+	err = api.backupPending(*pendingStickersJSON)
+	if err != nil {
+		return false, err
+	}
+
+	err = api.accountsDB.SaveSettingField(settings.StickersPacksPending, make(StickerPacksByChain))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
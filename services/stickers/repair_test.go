@@ -0,0 +1,71 @@
+package stickers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorruptedPending(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+
+	repaired, err := s.api.RepairPending()
+	require.NoError(t, err)
+	require.False(t, repaired)
+
+	corrupted := `{"1": {"1": {"id": 1, "name": "trunc`
+	_, err = s.api.accountsDB.DB().Exec(`UPDATE settings SET stickers_packs_pending = ? WHERE synthetic_id = 'id'`, []byte(corrupted))
+	require.NoError(t, err)
+
+	// Corruption is reported by default
+	_, err = s.api.Pending()
+	require.True(t, errors.Is(err, ErrPendingCorrupted))
+	require.True(t, errors.Is(s.api.AddPending(testChainID, packID(1)), ErrPendingCorrupted))
+
+	backup := func() string {
+		backupJSON, err := s.api.accountsDB.GetPendingStickerPacksBackup()
+		require.NoError(t, err)
+		if backupJSON == nil {
+			return ""
+		}
+		var value string
+		require.NoError(t, json.Unmarshal(*backupJSON, &value))
+		return value
+	}
+	require.Empty(t, backup())
+
+	// Recovering ignores the corrupted setting after backing it up
+	s.api.RecoverCorruptedPending = true
+	pending, err := s.api.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	require.Equal(t, corrupted, backup())
+
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	pending, err = s.api.Pending()
+	require.NoError(t, err)
+	require.Contains(t, pending[testChainID], uint(1))
+
+	// The setting is reset explicitly with RepairPending
+	s.api.RecoverCorruptedPending = false
+	_, err = s.api.accountsDB.DB().Exec(`UPDATE settings SET stickers_packs_pending = ? WHERE synthetic_id = 'id'`, []byte(`[{"name": "anonymous"}]`))
+	require.NoError(t, err)
+
+	repaired, err = s.api.RepairPending()
+	require.NoError(t, err)
+	require.True(t, repaired)
+	require.Equal(t, `[{"name": "anonymous"}]`, backup())
+
+	pending, err = s.api.Pending()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	repaired, err = s.api.RepairPending()
+	require.NoError(t, err)
+	require.False(t, repaired)
+}
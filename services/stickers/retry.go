@@ -0,0 +1,120 @@
+package stickers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v3"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// RetryPolicy controls how contract calls failing with transient errors are
+// retried. Attempts are spaced with an exponential backoff with jitter
+type RetryPolicy struct {
+	MaxRetries      uint64
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxRetries:      3,
+	InitialInterval: 200 * time.Millisecond,
+	MaxInterval:     2 * time.Second,
+}
+
+// retry runs op until it succeeds, fails with a non transient error, the
+// retry policy is exhausted or ctx is done, in which case ctx.Err() is
+// returned
+func (api *API) retry(ctx context.Context, op func() error) error {
+	if api.RetryPolicy.MaxRetries == 0 {
+		return op()
+	}
+
+	b := &backoff.ExponentialBackOff{
+		InitialInterval:     api.RetryPolicy.InitialInterval,
+		RandomizationFactor: 0.5,
+		Multiplier:          2,
+		MaxInterval:         api.RetryPolicy.MaxInterval,
+		Clock:               backoff.SystemClock,
+	}
+
+	return backoff.Retry(func() error {
+		err := op()
+		if err != nil && ctx.Err() != nil {
+			return backoff.Permanent(ctx.Err())
+		}
+		if err != nil && !isTransientError(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, backoff.WithContext(backoff.WithMaxRetries(b, api.RetryPolicy.MaxRetries), ctx))
+}
+
+func isTransientError(err error) bool {
+	// Deadlines are set by the callers, retrying can't meet them
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var httpErr ethRpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// newStickerType returns the sticker contract of the chain, failing with
+// ErrContractUnavailable when it can't be reached
+func (api *API) newStickerType(chainID uint64) (stickerTypeContract, error) {
+	var stickerType stickerTypeContract
+	err := api.retry(api.ctx, func() error {
+		var err error
+		stickerType, err = api.stickerType(chainID)
+		return err
+	})
+	if err != nil {
+		return nil, wrapError(ErrContractUnavailable, err)
+	}
+	return stickerType, nil
+}
+
+func (api *API) getPackData(stickerType stickerTypeContract, packID *big.Int) (stickerPackData, error) {
+	return api.getPackDataContext(api.ctx, stickerType, packID)
+}
+
+func (api *API) getPackDataContext(ctx context.Context, stickerType stickerTypeContract, packID *big.Int) (stickerPackData, error) {
+	callOpts := &bind.CallOpts{Context: ctx, Pending: false}
+
+	var packData stickerPackData
+	err := api.retry(ctx, func() error {
+		err := api.waitRateLimit(ctx)
+		if err != nil {
+			return err
+		}
+
+		packData, err = stickerType.GetPackData(callOpts, packID)
+		return err
+	})
+	if err != nil && isTransientError(err) {
+		return packData, wrapError(ErrContractUnavailable, err)
+	}
+	return packData, err
+}
@@ -0,0 +1,84 @@
+package stickers
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+var errConnRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+func TestRetryTransientContractErrors(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+	s.api.RetryPolicy = RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	s.contract.failures = 2
+	s.contract.failureErr = errConnRefused
+
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+	require.Equal(t, 3, s.contract.calls)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+	s.contract.failures = 1
+	s.contract.failureErr = errConnRefused
+
+	err := s.api.AddPending(testChainID, packID(1))
+	require.True(t, errors.Is(err, syscall.ECONNREFUSED))
+	require.Equal(t, 1, s.contract.calls)
+}
+
+func TestRetrySkipsLogicalErrors(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.api.RetryPolicy = RetryPolicy{MaxRetries: 2, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	s.contract.failures = 1
+	s.contract.failureErr = errors.New("execution reverted")
+
+	require.Error(t, s.api.AddPending(testChainID, packID(1)))
+	require.Equal(t, 1, s.contract.calls)
+
+	require.True(t, errors.Is(s.api.AddPending(testChainID, packID(1)), ErrPackNotFound))
+	require.Equal(t, 2, s.contract.calls)
+}
+
+func TestRetryHonorsCallerContext(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	s.publishPack(t, 1, "pack", 10, 1)
+	s.api.RetryPolicy = RetryPolicy{MaxRetries: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	s.contract.failures = 4
+	s.contract.failureErr = errConnRefused
+
+	require.False(t, isTransientError(context.DeadlineExceeded))
+
+	// An expired caller context isn't retried nor reported as an
+	// unavailable contract
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	_, err := s.api.getPackDataContext(ctx, s.contract, big.NewInt(1))
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Equal(t, 1, s.contract.calls)
+
+	// Nor is the rate limiter waited for
+	s.api.RateLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	require.True(t, s.api.RateLimiter.Allow())
+	_, err = s.api.getPackDataContext(ctx, s.contract, big.NewInt(1))
+	require.Error(t, err)
+	require.Equal(t, 1, s.contract.calls)
+}
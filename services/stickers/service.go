@@ -2,14 +2,18 @@ package stickers
 
 import (
 	"context"
+	"time"
 
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	ethRpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/status-im/status-go/account"
 	"github.com/status-im/status-go/multiaccounts/accounts"
 	"github.com/status-im/status-go/params"
 	"github.com/status-im/status-go/rpc"
+	"github.com/status-im/status-go/server"
 	"github.com/status-im/status-go/services/rpcfilters"
+	"github.com/status-im/status-go/services/wallet/bigint"
 )
 
 // NewService initializes service instance.
@@ -22,6 +26,7 @@ func NewService(acc *accounts.Database, rpcClient *rpc.Client, accountsManager *
 		accountsManager: accountsManager,
 		rpcFiltersSrvc:  rpcFiltersSrvc,
 		config:          config,
+		api:             NewAPI(ctx, acc, rpcClient, accountsManager, rpcFiltersSrvc, config),
 
 		ctx:    ctx,
 		cancel: cancel,
@@ -35,6 +40,7 @@ type Service struct {
 	accountsManager *account.GethManager
 	rpcFiltersSrvc  *rpcfilters.Service
 	config          *params.NodeConfig
+	api             *API
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -42,6 +48,9 @@ type Service struct {
 
 // Start a service.
 func (s *Service) Start() error {
+	if _, err := s.api.migratePendingStickerPacks(); err != nil {
+		log.Warn("failed to migrate the pending sticker packs", "err", err)
+	}
 	return nil
 }
 
@@ -57,11 +66,79 @@ func (s *Service) APIs() []ethRpc.API {
 		{
 			Namespace: "stickers",
 			Version:   "0.1.0",
-			Service:   NewAPI(s.ctx, s.accountsDB, s.rpcClient, s.accountsManager, s.rpcFiltersSrvc, s.config),
+			Service:   s.api,
 		},
 	}
 }
 
+// FetchSticker returns the content of a sticker prefetched when installing
+// its pack, or downloads it through the IPFS gateway configured on the API,
+// to be served locally by the media server
+func (s *Service) FetchSticker(ctx context.Context, hash string) ([]byte, error) {
+	return s.api.stickerContent(ctx, hash)
+}
+
+// FetchIPFS downloads IPFS content by CID through the IPFS gateway configured
+// on the API, to be served locally by the media server
+func (s *Service) FetchIPFS(ctx context.Context, cid string) ([]byte, error) {
+	return s.api.downloadCID(ctx, cid)
+}
+
+// StreamPackStickers downloads the stickers of an installed or pending pack
+// concurrently and emits each of them as soon as it's downloaded. The channel
+// is closed once every sticker was emitted or ctx is done. It isn't exposed
+// over RPC, which can't return channels
+func (s *Service) StreamPackStickers(ctx context.Context, packID *bigint.BigInt) <-chan StickerResult {
+	return s.api.streamPackStickers(ctx, packID)
+}
+
+// WatchMarket checks the sticker market of a chain every interval and sends a
+// signal listing the packs published since the last check. It blocks until
+// ctx is done, so it isn't exposed over RPC
+func (s *Service) WatchMarket(ctx context.Context, chainID uint64, interval time.Duration) error {
+	return s.api.watchMarket(ctx, chainID, interval)
+}
+
+// SetContentCacheBytes changes the total size of the sticker content kept in
+// memory, evicting the least recently used content if needed
+func (s *Service) SetContentCacheBytes(maxBytes int64) {
+	s.api.content.SetMaxBytes(maxBytes)
+}
+
+// SetMetadataCacheBytes changes the total size of the pack metadata kept in
+// memory, evicting the least recently used metadata if needed
+func (s *Service) SetMetadataCacheBytes(maxBytes int64) {
+	s.api.metadata.SetMaxBytes(maxBytes)
+}
+
+// CacheStats returns the lookup counters of the pack metadata and sticker
+// content caches, to be reported by the media server
+func (s *Service) CacheStats() map[string]server.CacheStats {
+	return map[string]server.CacheStats{
+		"sticker-metadata": s.api.metadata.Stats(),
+		"sticker-content":  s.api.content.Stats(),
+	}
+}
+
+// TrimCaches drops the least recently used sticker content and pack metadata
+// kept in memory until the caches hold at most lowWater of their budget, e.g.
+// on memory warnings from the OS
+func (s *Service) TrimCaches(lowWater float64) {
+	s.api.trimCaches(lowWater)
+}
+
+// SetMediaServerURL makes the sticker URLs point at the /stickers route of
+// the local media server whose base URL is returned by baseURL
+func (s *Service) SetMediaServerURL(baseURL func() string) {
+	s.api.MediaServerURL = baseURL
+}
+
+// SetExchangeRates sets the source converting pack prices to fiat currencies,
+// used by PackPriceInFiat
+func (s *Service) SetExchangeRates(rates ExchangeRateSource) {
+	s.api.ExchangeRates = rates
+}
+
 // Protocols returns list of p2p protocols.
 func (s *Service) Protocols() []p2p.Protocol {
 	return nil
@@ -0,0 +1,212 @@
+package stickers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/services/wallet/bigint"
+)
+
+// Maximum size of a downloaded sticker
+const maxStickerSize = 2 * 1024 * 1024
+
+// Default limits of the content fetched from the IPFS gateway, see
+// FetchTimeout and MaxContentBytes
+const defaultFetchTimeout = 10 * time.Second
+const defaultMaxContentBytes = 25 * 1024 * 1024
+
+var ErrFetchTimeout = errors.New("IPFS gateway fetch timed out")
+var ErrContentTooLarge = errors.New("IPFS content too large")
+
+// StickerResult is the outcome of downloading a single sticker
+type StickerResult struct {
+	Hash string
+	Data []byte
+	Err  error
+}
+
+// streamPackStickers downloads the stickers of an installed or pending pack
+// concurrently and emits each of them as soon as it's downloaded. The channel
+// is closed once every sticker was emitted or ctx is done
+func (api *API) streamPackStickers(ctx context.Context, packID *bigint.BigInt) <-chan StickerResult {
+	results := make(chan StickerResult)
+
+	go func() {
+		defer close(results)
+
+		stickerPack, err := api.localPack(packID)
+		if err != nil {
+			select {
+			case results <- StickerResult{Err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, maxConcurrentRequests)
+		for _, sticker := range stickerPack.Stickers {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return
+			}
+
+			wg.Add(1)
+			go func(hash string) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				data, err := api.downloadSticker(ctx, hash)
+				select {
+				case results <- StickerResult{Hash: hash, Data: data, Err: err}:
+				case <-ctx.Done():
+				}
+			}(sticker.Hash)
+		}
+		wg.Wait()
+	}()
+
+	return results
+}
+
+// localPack returns the installed or pending pack with the given ID
+func (api *API) localPack(packID *bigint.BigInt) (*StickerPack, error) {
+	installedPacks, err := api.installedStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	if stickerPack, exists := installedPacks[uint(packID.Uint64())]; exists {
+		return &stickerPack, nil
+	}
+
+	pendingPacks, err := api.pendingStickerPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	if stickerPack, exists := pendingPacks.find(uint(packID.Uint64())); exists {
+		return &stickerPack, nil
+	}
+
+	return nil, ErrPackNotFound
+}
+
+// downloadSticker fetches the content of a sticker from the IPFS gateway,
+// verified against its hash when VerifyContent is set
+func (api *API) downloadSticker(ctx context.Context, hash string) ([]byte, error) {
+	contentID, err := decodeHashCID(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.downloadContent(ctx, contentID, "sticker "+hash, maxStickerSize)
+}
+
+// downloadCID fetches IPFS content by CID from the IPFS gateway, verified
+// against the CID when VerifyContent is set
+func (api *API) downloadCID(ctx context.Context, rawCID string) ([]byte, error) {
+	contentID, err := cid.Decode(rawCID)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.downloadContent(ctx, contentID, "content "+rawCID, 0)
+}
+
+// downloadContent fetches the content of contentID from the IPFS gateway.
+// When VerifyContent is set, the raw block is fetched instead, so that it can
+// be hashed, and the content is extracted from it
+func (api *API) downloadContent(ctx context.Context, contentID cid.Cid, name string, maxSize int64) ([]byte, error) {
+	contentURL, err := api.cidToURL(contentID)
+	if err != nil {
+		return nil, wrapError(ErrInvalidHash, err)
+	}
+
+	if !api.VerifyContent {
+		return api.download(ctx, contentURL, name, maxSize)
+	}
+
+	block, err := api.download(ctx, contentURL+"?format=raw", name, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockContent(contentID, block)
+}
+
+// download fetches contentURL within FetchTimeout, failing when its content
+// exceeds maxSize or MaxContentBytes. A non positive maxSize leaves only
+// MaxContentBytes
+func (api *API) download(ctx context.Context, contentURL string, name string, maxSize int64) ([]byte, error) {
+	if api.MaxContentBytes > 0 && (maxSize <= 0 || api.MaxContentBytes < maxSize) {
+		maxSize = api.MaxContentBytes
+	}
+
+	if api.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, api.FetchTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, contentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := api.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fetchError(name, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Error("failed to close the content request body", "err", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", name, resp.Status)
+	}
+
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes, more than %d", ErrContentTooLarge, name, resp.ContentLength, maxSize)
+	}
+
+	var body io.Reader = resp.Body
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fetchError(name, err)
+	}
+
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrContentTooLarge, name, maxSize)
+	}
+
+	return data, nil
+}
+
+// fetchError tells the fetches that timed out apart from other failures
+func fetchError(name string, err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return wrapError(ErrFetchTimeout, fmt.Errorf("%s: %w", name, err))
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package stickers
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/multiformats/go-multibase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamPackStickers(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	const numStickers = 10
+	s.publishPack(t, 1, "pack", 10, numStickers)
+	require.NoError(t, s.api.Install(testChainID, packID(1)))
+
+	received := make(map[string][]byte)
+	for result := range s.api.streamPackStickers(context.Background(), packID(1)) {
+		require.NoError(t, result.Err)
+		_, duplicate := received[result.Hash]
+		require.False(t, duplicate, "sticker %s emitted twice", result.Hash)
+		received[result.Hash] = result.Data
+	}
+
+	require.Len(t, received, numStickers)
+	for i := 0; i < numStickers; i++ {
+		data := []byte(fmt.Sprintf("sticker pack %d", i))
+		require.Equal(t, data, received[s.ipfs.add(t, data)])
+	}
+}
+
+func TestStreamPackStickersErrors(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var results []StickerResult
+	for result := range s.api.streamPackStickers(context.Background(), packID(1)) {
+		results = append(results, result)
+	}
+	require.Len(t, results, 1)
+	require.True(t, errors.Is(results[0].Err, ErrPackNotFound))
+
+	s.publishPack(t, 1, "pack", 10, 5)
+	require.NoError(t, s.api.AddPending(testChainID, packID(1)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for result := range s.api.streamPackStickers(ctx, packID(1)) {
+		require.Error(t, result.Err)
+	}
+}
+
+func TestDownloadStickerSizeLimit(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	hash := s.ipfs.add(t, make([]byte, maxStickerSize+1))
+	_, err := s.api.downloadSticker(context.Background(), hash)
+	require.True(t, errors.Is(err, ErrContentTooLarge))
+
+	hash = s.ipfs.add(t, make([]byte, maxStickerSize))
+	data, err := s.api.downloadSticker(context.Background(), hash)
+	require.NoError(t, err)
+	require.Len(t, data, maxStickerSize)
+}
+
+// hangingGateway never responds, until the request is canceled
+type hangingGateway struct{}
+
+func (hangingGateway) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestFetchLimits(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	small := s.ipfs.add(t, make([]byte, 1024))
+	large := s.ipfs.add(t, make([]byte, 1025))
+
+	s.api.MaxContentBytes = 1024
+	_, err := s.api.downloadSticker(context.Background(), small)
+	require.NoError(t, err)
+	_, err = s.api.downloadSticker(context.Background(), large)
+	require.True(t, errors.Is(err, ErrContentTooLarge))
+
+	// Pack metadata is bounded too
+	s.publishPack(t, 1, "pack", 10, 1)
+	s.api.MaxContentBytes = 16
+	_, err = s.api.GetPack(testChainID, packID(1))
+	require.True(t, errors.Is(err, ErrContentTooLarge))
+
+	// Stickers stay bounded by the sticker limit
+	s.api.MaxContentBytes = 0
+	_, err = s.api.downloadSticker(context.Background(), s.ipfs.add(t, make([]byte, maxStickerSize+1)))
+	require.True(t, errors.Is(err, ErrContentTooLarge))
+
+	s.api.client = &http.Client{Transport: hangingGateway{}}
+	s.api.FetchTimeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err = s.api.downloadSticker(context.Background(), small)
+	require.True(t, errors.Is(err, ErrFetchTimeout))
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+	// Canceled fetches aren't timeouts
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.api.downloadSticker(ctx, small)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrFetchTimeout))
+}
+
+func TestDownloadCID(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	hash, err := hex.DecodeString(s.ipfs.add(t, []byte("content")))
+	require.NoError(t, err)
+	contentID, err := decodeContenthash(hash)
+	require.NoError(t, err)
+
+	// Any CID encoding is fetched from the gateway in base32
+	base58, err := contentID.StringOfBase(multibase.Base58BTC)
+	require.NoError(t, err)
+	data, err := s.api.downloadCID(context.Background(), base58)
+	require.NoError(t, err)
+	require.Equal(t, []byte("content"), data)
+
+	_, err = s.api.downloadCID(context.Background(), "notacid")
+	require.Error(t, err)
+}
@@ -34,14 +34,12 @@ func (api *API) Buy(ctx context.Context, chainID uint64, txArgs transactions.Sen
 		return "", err
 	}
 
-	stickerType, err := api.contractMaker.NewStickerType(chainID)
+	stickerType, err := api.newStickerType(chainID)
 	if err != nil {
 		return "", err
 	}
 
-	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
-
-	packInfo, err := stickerType.GetPackData(callOpts, packID.Int)
+	packInfo, err := api.getPackData(stickerType, packID.Int)
 	if err != nil {
 		return "", err
 	}
@@ -85,14 +83,12 @@ func (api *API) Buy(ctx context.Context, chainID uint64, txArgs transactions.Sen
 }
 
 func (api *API) BuyEstimate(ctx context.Context, chainID uint64, from types.Address, packID *bigint.BigInt) (uint64, error) {
-	callOpts := &bind.CallOpts{Context: api.ctx, Pending: false}
-
-	stickerType, err := api.contractMaker.NewStickerType(chainID)
+	stickerType, err := api.newStickerType(chainID)
 	if err != nil {
 		return 0, err
 	}
 
-	packInfo, err := stickerType.GetPackData(callOpts, packID.Int)
+	packInfo, err := api.getPackData(stickerType, packID.Int)
 	if err != nil {
 		return 0, err
 	}
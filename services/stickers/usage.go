@@ -0,0 +1,75 @@
+package stickers
+
+import (
+	"encoding/json"
+
+	"github.com/status-im/status-go/multiaccounts/settings"
+)
+
+// StickerUsage counts how many times stickers were used, by pack ID and by
+// sticker hash
+type StickerUsage struct {
+	Packs    map[string]uint64 `json:"packs"`
+	Stickers map[string]uint64 `json:"stickers"`
+}
+
+func (api *API) stickerUsage() (StickerUsage, error) {
+	usage := StickerUsage{
+		Packs:    make(map[string]uint64),
+		Stickers: make(map[string]uint64),
+	}
+
+	usageJSON, err := api.accountsDB.GetStickersUsage()
+	if err != nil {
+		return usage, err
+	}
+
+	if usageJSON == nil {
+		return usage, nil
+	}
+
+	err = json.Unmarshal(*usageJSON, &usage)
+	if err != nil {
+		return usage, err
+	}
+
+	if usage.Packs == nil {
+		usage.Packs = make(map[string]uint64)
+	}
+	if usage.Stickers == nil {
+		usage.Stickers = make(map[string]uint64)
+	}
+
+	return usage, nil
+}
+
+// countStickerUsage increments the usage of the sticker and of its pack, the
+// caller must hold api.mu
+func (api *API) countStickerUsage(sticker Sticker) error {
+	usage, err := api.stickerUsage()
+	if err != nil {
+		return err
+	}
+
+	usage.Packs[sticker.PackID.String()]++
+	usage.Stickers[sticker.Hash]++
+
+	return api.accountsDB.SaveSettingField(settings.StickersUsage, usage)
+}
+
+// StickerUsage returns how many times stickers were used since the usage was
+// last reset
+func (api *API) StickerUsage() (StickerUsage, error) {
+	return api.stickerUsage()
+}
+
+// ResetStickerUsage clears the usage counts of every sticker
+func (api *API) ResetStickerUsage() error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return api.accountsDB.SaveSettingField(settings.StickersUsage, StickerUsage{
+		Packs:    make(map[string]uint64),
+		Stickers: make(map[string]uint64),
+	})
+}
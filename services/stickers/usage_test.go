@@ -0,0 +1,48 @@
+package stickers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStickerUsage(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	usage, err := s.api.StickerUsage()
+	require.NoError(t, err)
+	require.Empty(t, usage.Packs)
+	require.Empty(t, usage.Stickers)
+
+	require.NoError(t, s.api.TrackRecentSticker(packID(1), "e301"))
+	require.NoError(t, s.api.TrackRecentSticker(packID(1), "e302"))
+	require.NoError(t, s.api.TrackRecentSticker(packID(1), "e301"))
+	require.NoError(t, s.api.TrackRecentSticker(packID(2), "e303"))
+
+	expected := StickerUsage{
+		Packs:    map[string]uint64{"1": 3, "2": 1},
+		Stickers: map[string]uint64{"e301": 2, "e302": 1, "e303": 1},
+	}
+	usage, err = s.api.StickerUsage()
+	require.NoError(t, err)
+	require.Equal(t, expected, usage)
+
+	// The counts are persisted in the settings
+	restarted := NewAPI(context.Background(), s.api.accountsDB, nil, nil, nil, nil)
+	usage, err = restarted.StickerUsage()
+	require.NoError(t, err)
+	require.Equal(t, expected, usage)
+
+	require.NoError(t, s.api.ResetStickerUsage())
+	usage, err = s.api.StickerUsage()
+	require.NoError(t, err)
+	require.Empty(t, usage.Packs)
+	require.Empty(t, usage.Stickers)
+
+	// Resetting the usage keeps the recent stickers
+	recent, err := s.api.recentStickers()
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+}
@@ -0,0 +1,182 @@
+package stickers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v3"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-go/multiaccounts/settings"
+	"github.com/status-im/status-go/signal"
+)
+
+// Maximum delay between two checks of the market after failed checks
+const maxMarketWatchBackoff = 30 * time.Minute
+
+var ErrInvalidWatchInterval = errors.New("invalid market watch interval")
+
+// marketSeen returns the number of packs of the market last seen by chain ID
+func (api *API) marketSeen() (map[uint64]uint64, error) {
+	seen := make(map[uint64]uint64)
+
+	seenJSON, err := api.accountsDB.GetStickersMarketSeen()
+	if err != nil {
+		return nil, err
+	}
+
+	if seenJSON == nil {
+		return seen, nil
+	}
+
+	err = json.Unmarshal(*seenJSON, &seen)
+	if err != nil {
+		return nil, err
+	}
+
+	return seen, nil
+}
+
+func (api *API) setMarketSeen(chainID uint64, count uint64) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	seen, err := api.marketSeen()
+	if err != nil {
+		return err
+	}
+
+	seen[chainID] = count
+	return api.accountsDB.SaveSettingField(settings.StickersMarketSeen, seen)
+}
+
+// checkMarket compares the number of packs of the market with the last seen
+// one and returns the packs published since, sending a signal when there are
+// some. The first check of a chain only records the number of packs. New
+// packs whose metadata can't be retrieved are only listed by ID in the signal.
+// The contract calls give up once ctx is done
+func (api *API) checkMarket(ctx context.Context, chainID uint64) ([]StickerPack, error) {
+	stickerType, err := api.newStickerType(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.waitRateLimit(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	numPacks, err := stickerType.PackCount(&bind.CallOpts{Context: ctx, Pending: false})
+	if err != nil {
+		return nil, err
+	}
+	count := numPacks.Uint64()
+
+	seen, err := api.marketSeen()
+	if err != nil {
+		return nil, err
+	}
+
+	lastCount, ok := seen[chainID]
+	if ok && count == lastCount {
+		return nil, nil
+	}
+
+	var newPacks []StickerPack
+	if ok && count > lastCount {
+		packIDs := make([]string, 0, count-lastCount)
+		packs := make([]*StickerPack, count-lastCount)
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, maxConcurrentRequests)
+		for i := lastCount; i < count; i++ {
+			packID := new(big.Int).SetUint64(i)
+			packIDs = append(packIDs, packID.String())
+
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(i uint64, packID *big.Int) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				packData, err := api.getPackDataContext(ctx, stickerType, packID)
+				if err != nil {
+					log.Warn("Could not retrieve new stickerpack data", "packID", packID, "error", err)
+					return
+				}
+
+				stickerPack, err := api.packFromData(chainID, packID, packData, true)
+				if err != nil {
+					log.Warn("Could not retrieve new stickerpack data", "packID", packID, "error", err)
+					return
+				}
+				packs[i-lastCount] = stickerPack
+			}(i, packID)
+		}
+		wg.Wait()
+
+		newPacks = []StickerPack{}
+		for _, stickerPack := range packs {
+			if stickerPack != nil {
+				stickerPack.Status = statusAvailable
+				newPacks = append(newPacks, *stickerPack)
+			}
+		}
+
+		signal.SendStickerMarketNewPacks(chainID, packIDs, newPacks)
+	}
+
+	err = api.setMarketSeen(chainID, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPacks, nil
+}
+
+// watchMarket checks the sticker market of a chain every interval and sends a
+// signal listing the packs published since the last check, including the
+// checks of previous runs. Failed checks are retried with an exponential
+// backoff. It blocks until ctx is done
+func (api *API) watchMarket(ctx context.Context, chainID uint64, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidWatchInterval
+	}
+
+	maxInterval := maxMarketWatchBackoff
+	if interval > maxInterval {
+		maxInterval = interval
+	}
+
+	b := &backoff.ExponentialBackOff{
+		InitialInterval:     interval,
+		RandomizationFactor: 0.5,
+		Multiplier:          2,
+		MaxInterval:         maxInterval,
+		Clock:               backoff.SystemClock,
+	}
+	b.Reset()
+
+	for {
+		delay := interval
+		_, err := api.checkMarket(ctx, chainID)
+		if err != nil {
+			delay = b.NextBackOff()
+			log.Warn("Could not check the sticker market", "chainID", chainID, "retryIn", delay, "error", err)
+		} else {
+			b.Reset()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package stickers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/status-im/status-go/signal"
+)
+
+func TestCheckMarket(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	var events []signal.StickerMarketNewPacksSignal
+	var mu sync.Mutex
+	signal.SetMobileSignalHandler(func(data []byte) {
+		var envelope struct {
+			Type  string          `json:"type"`
+			Event json.RawMessage `json:"event"`
+		}
+		require.NoError(t, json.Unmarshal(data, &envelope))
+		if envelope.Type != signal.EventStickerMarketNewPacks {
+			return
+		}
+
+		var event signal.StickerMarketNewPacksSignal
+		require.NoError(t, json.Unmarshal(envelope.Event, &event))
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	defer signal.SetMobileSignalHandler(nil)
+
+	s.publishPack(t, 0, "first", 10, 1)
+
+	// The first check only records the packs
+	packs, err := s.api.checkMarket(context.Background(), testChainID)
+	require.NoError(t, err)
+	require.Empty(t, packs)
+
+	s.publishPack(t, 1, "second", 10, 1)
+	s.publishPack(t, 2, "third", 10, 1)
+
+	packs, err = s.api.checkMarket(context.Background(), testChainID)
+	require.NoError(t, err)
+	require.Len(t, packs, 2)
+	require.Equal(t, "second", packs[0].Name)
+	require.Equal(t, "third", packs[1].Name)
+
+	mu.Lock()
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(testChainID), events[0].ChainID)
+	require.Equal(t, []string{"1", "2"}, events[0].PackIDs)
+	mu.Unlock()
+
+	packs, err = s.api.checkMarket(context.Background(), testChainID)
+	require.NoError(t, err)
+	require.Empty(t, packs)
+
+	// The last seen count survives restarts
+	api := NewAPI(context.Background(), s.api.accountsDB, nil, nil, nil, nil)
+	api.stickerType = s.api.stickerType
+	api.client = s.api.client
+	api.RateLimiter = nil
+	packs, err = api.checkMarket(context.Background(), testChainID)
+	require.NoError(t, err)
+	require.Empty(t, packs)
+
+	mu.Lock()
+	require.Len(t, events, 1)
+	mu.Unlock()
+
+	// The check gives up with the watch context, not the API one
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.api.RateLimiter = rate.NewLimiter(1, 1)
+	_, err = s.api.checkMarket(ctx, testChainID)
+	require.True(t, errors.Is(err, context.Canceled), err)
+}
+
+func TestWatchMarket(t *testing.T) {
+	s, stop := setupTestAPI(t)
+	defer stop()
+
+	require.Equal(t, ErrInvalidWatchInterval, s.api.watchMarket(context.Background(), testChainID, 0))
+
+	s.contract.err = errors.New("rpc unavailable")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, s.api.watchMarket(ctx, testChainID, 10*time.Millisecond))
+
+	seen, err := s.api.marketSeen()
+	require.NoError(t, err)
+	require.Empty(t, seen)
+
+	s.contract.err = nil
+	s.publishPack(t, 0, "first", 10, 1)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.Equal(t, context.DeadlineExceeded, s.api.watchMarket(ctx, testChainID, 10*time.Millisecond))
+
+	seen, err = s.api.marketSeen()
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]uint64{testChainID: 1}, seen)
+}
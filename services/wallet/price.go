@@ -36,3 +36,17 @@ func fetchCryptoComparePrices(symbols []string, currency string) (map[string]flo
 	}
 	return result, nil
 }
+
+// CryptoCompareRates provides token exchange rates from CryptoCompare, the
+// source of FetchPrices
+type CryptoCompareRates struct{}
+
+// Rate returns the value of one symbol token in currency, zero when the pair
+// is unknown
+func (CryptoCompareRates) Rate(symbol string, currency string) (float64, error) {
+	prices, err := fetchCryptoComparePrices([]string{symbol}, currency)
+	if err != nil {
+		return 0, err
+	}
+	return prices[symbol], nil
+}
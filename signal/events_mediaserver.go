@@ -0,0 +1,20 @@
+package signal
+
+const (
+	// EventMediaServerPortChanged is triggered when the media server listens
+	// on another port than before, which makes the media URLs handed out so
+	// far stale
+	EventMediaServerPortChanged = "mediaserver.portChanged"
+)
+
+type MediaServerPortChangedSignal struct {
+	Port    int    `json:"port"`
+	BaseURL string `json:"baseURL"`
+}
+
+func SendMediaServerPortChanged(port int, baseURL string) {
+	send(EventMediaServerPortChanged, MediaServerPortChangedSignal{
+		Port:    port,
+		BaseURL: baseURL,
+	})
+}
@@ -0,0 +1,91 @@
+package signal
+
+const (
+	// EventStickerPackPendingChanged is triggered when a sticker pack is
+	// added to or removed from the pending sticker packs
+	EventStickerPackPendingChanged = "stickers.pendingChanged"
+
+	// EventStickerPackInstallProgress is triggered every time a content of a
+	// sticker pack being installed is fetched
+	EventStickerPackInstallProgress = "stickers.installProgress"
+
+	// EventStickerPackInstalled is triggered once the content of a sticker
+	// pack being installed was fetched
+	EventStickerPackInstalled = "stickers.installed"
+
+	// EventStickerMarketNewPacks is triggered when new sticker packs are
+	// published on the sticker market
+	EventStickerMarketNewPacks = "stickers.marketNewPacks"
+)
+
+const (
+	StickerPackPendingAdded   = "added"
+	StickerPackPendingRemoved = "removed"
+)
+
+type StickerPackPendingChangedSignal struct {
+	PackID  string `json:"packID"`
+	ChainID uint64 `json:"chainID,omitempty"`
+	Action  string `json:"action"`
+}
+
+func SendStickerPackPendingAdded(chainID uint64, packID string) {
+	send(EventStickerPackPendingChanged, StickerPackPendingChangedSignal{
+		PackID:  packID,
+		ChainID: chainID,
+		Action:  StickerPackPendingAdded,
+	})
+}
+
+func SendStickerPackPendingRemoved(chainID uint64, packID string) {
+	send(EventStickerPackPendingChanged, StickerPackPendingChangedSignal{
+		PackID:  packID,
+		ChainID: chainID,
+		Action:  StickerPackPendingRemoved,
+	})
+}
+
+type StickerPackInstallProgressSignal struct {
+	PackID  string `json:"packID"`
+	ChainID uint64 `json:"chainID"`
+	Fetched int    `json:"fetched"`
+	Total   int    `json:"total"`
+}
+
+type StickerPackInstalledSignal struct {
+	PackID  string   `json:"packID"`
+	ChainID uint64   `json:"chainID"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+func SendStickerPackInstallProgress(chainID uint64, packID string, fetched, total int) {
+	send(EventStickerPackInstallProgress, StickerPackInstallProgressSignal{
+		PackID:  packID,
+		ChainID: chainID,
+		Fetched: fetched,
+		Total:   total,
+	})
+}
+
+func SendStickerPackInstalled(chainID uint64, packID string, failed []string) {
+	send(EventStickerPackInstalled, StickerPackInstalledSignal{
+		PackID:  packID,
+		ChainID: chainID,
+		Failed:  failed,
+	})
+}
+
+type StickerMarketNewPacksSignal struct {
+	ChainID uint64   `json:"chainID"`
+	PackIDs []string `json:"packIDs"`
+	// Packs is the metadata of the new packs which could be retrieved
+	Packs interface{} `json:"packs"`
+}
+
+func SendStickerMarketNewPacks(chainID uint64, packIDs []string, packs interface{}) {
+	send(EventStickerMarketNewPacks, StickerMarketNewPacksSignal{
+		ChainID: chainID,
+		PackIDs: packIDs,
+		Packs:   packs,
+	})
+}